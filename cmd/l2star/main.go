@@ -6,6 +6,8 @@ import (
 
 	"github.com/gnpaone/l2star/internal/ui"
 
+	l2net "github.com/gnpaone/l2star/internal/net"
+
 	tea "github.com/charmbracelet/bubbletea"
 )
 
@@ -19,7 +21,14 @@ func main() {
 	}
 
 	p := tea.NewProgram(ui.InitialModel(), tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
+	_, err := p.Run()
+	if err := l2net.RemoveVLANInterfaces(); err != nil {
+		fmt.Println(err)
+	}
+	if err := l2net.RestoreIPForward(); err != nil {
+		fmt.Println(err)
+	}
+	if err != nil {
 		fmt.Printf("Alas, there's been an error: %v", err)
 		os.Exit(1)
 	}
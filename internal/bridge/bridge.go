@@ -0,0 +1,234 @@
+package bridge
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+)
+
+// Side identifies one of the two bridged interfaces
+type Side int
+
+const (
+	SideA Side = iota
+	SideB
+)
+
+// Port is one end of the bridge. *pcap.Handle satisfies it.
+type Port interface {
+	ReadPacketData() ([]byte, gopacket.CaptureInfo, error)
+	WritePacketData(data []byte) error
+}
+
+// Action tells the bridge what to do with a frame matched by a rule
+type Action int
+
+const (
+	ActionForward Action = iota
+	ActionDrop
+	ActionRewrite
+)
+
+// Direction restricts a rule to frames travelling one way across the bridge
+type Direction int
+
+const (
+	DirectionAToB Direction = 1 << iota
+	DirectionBToA
+	DirectionBoth = DirectionAToB | DirectionBToA
+)
+
+// Rule matches frames crossing the bridge. Rules are evaluated in order and the first match wins.
+type Rule struct {
+	Name      string
+	Direction Direction
+	Match     func(packet gopacket.Packet) bool
+	Action    Action
+	Rewrite   func(frame []byte) []byte
+}
+
+// Stats counts what the bridge did with the frames it received
+type Stats struct {
+	Forwarded uint64
+	Dropped   uint64
+	Rewritten uint64
+	BPDUsSent uint64
+}
+
+// Config configuration for a bridge
+type Config struct {
+	Rules []Rule
+	// BPDUGenerator builds the BPDU sent out of each side every BPDUInterval. Nil disables BPDU injection.
+	BPDUGenerator func(side Side) ([]byte, error)
+	BPDUInterval  time.Duration
+	// Capture receives every forwarded frame in pcap format when set.
+	Capture  io.Writer
+	StopChan chan struct{}
+}
+
+// Bridge forwards frames between two ports in userspace
+type Bridge struct {
+	cfg   Config
+	stats Stats
+
+	captureMu sync.Mutex
+	capture   *pcapgo.Writer
+}
+
+// New creates a bridge from cfg
+func New(cfg Config) *Bridge {
+	if cfg.BPDUInterval == 0 {
+		cfg.BPDUInterval = 2 * time.Second
+	}
+	return &Bridge{cfg: cfg}
+}
+
+// Stats returns a snapshot of the bridge counters
+func (b *Bridge) Stats() Stats {
+	return Stats{
+		Forwarded: atomic.LoadUint64(&b.stats.Forwarded),
+		Dropped:   atomic.LoadUint64(&b.stats.Dropped),
+		Rewritten: atomic.LoadUint64(&b.stats.Rewritten),
+		BPDUsSent: atomic.LoadUint64(&b.stats.BPDUsSent),
+	}
+}
+
+// Run forwards frames between a and b until the stop channel is closed
+func (b *Bridge) Run(a, bPort Port) error {
+	if b.cfg.Capture != nil {
+		b.capture = pcapgo.NewWriter(b.cfg.Capture)
+		if err := b.capture.WriteFileHeader(65536, layers.LinkTypeEthernet); err != nil {
+			return err
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		b.forward(a, bPort, DirectionAToB)
+	}()
+	go func() {
+		defer wg.Done()
+		b.forward(bPort, a, DirectionBToA)
+	}()
+
+	if b.cfg.BPDUGenerator != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.sendBPDUs(a, bPort)
+		}()
+	}
+
+	<-b.cfg.StopChan
+	wg.Wait()
+	return nil
+}
+
+func (b *Bridge) forward(from, to Port, dir Direction) {
+	for {
+		select {
+		case <-b.cfg.StopChan:
+			return
+		default:
+		}
+
+		data, ci, err := from.ReadPacketData()
+		if err == io.EOF {
+			return
+		}
+		if err != nil || len(data) == 0 {
+			continue
+		}
+
+		frame, ok := b.apply(data, dir)
+		if !ok {
+			atomic.AddUint64(&b.stats.Dropped, 1)
+			continue
+		}
+
+		if err := to.WritePacketData(frame); err != nil {
+			atomic.AddUint64(&b.stats.Dropped, 1)
+			continue
+		}
+		atomic.AddUint64(&b.stats.Forwarded, 1)
+		b.record(ci, frame)
+	}
+}
+
+// apply runs the rules against a frame and returns the frame to forward, or false if it must be dropped
+func (b *Bridge) apply(data []byte, dir Direction) ([]byte, bool) {
+	if len(b.cfg.Rules) == 0 {
+		return data, true
+	}
+
+	packet := gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.NoCopy)
+	for _, rule := range b.cfg.Rules {
+		if rule.Direction != 0 && rule.Direction&dir == 0 {
+			continue
+		}
+		if rule.Match != nil && !rule.Match(packet) {
+			continue
+		}
+
+		switch rule.Action {
+		case ActionDrop:
+			return nil, false
+		case ActionRewrite:
+			if rule.Rewrite != nil {
+				data = rule.Rewrite(data)
+				atomic.AddUint64(&b.stats.Rewritten, 1)
+			}
+		}
+		return data, true
+	}
+	return data, true
+}
+
+func (b *Bridge) record(ci gopacket.CaptureInfo, frame []byte) {
+	if b.capture == nil {
+		return
+	}
+	ci.CaptureLength = len(frame)
+	ci.Length = len(frame)
+	if ci.Timestamp.IsZero() {
+		ci.Timestamp = time.Now()
+	}
+
+	b.captureMu.Lock()
+	defer b.captureMu.Unlock()
+	b.capture.WritePacket(ci, frame)
+}
+
+func (b *Bridge) sendBPDUs(a, bPort Port) {
+	send := func() {
+		for side, port := range []Port{a, bPort} {
+			packet, err := b.cfg.BPDUGenerator(Side(side))
+			if err != nil {
+				continue
+			}
+			if err := port.WritePacketData(packet); err == nil {
+				atomic.AddUint64(&b.stats.BPDUsSent, 1)
+			}
+		}
+	}
+
+	ticker := time.NewTicker(b.cfg.BPDUInterval)
+	defer ticker.Stop()
+
+	send()
+	for {
+		select {
+		case <-b.cfg.StopChan:
+			return
+		case <-ticker.C:
+			send()
+		}
+	}
+}
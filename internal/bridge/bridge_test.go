@@ -0,0 +1,140 @@
+package bridge
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gnpaone/l2star/internal/porttest"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+)
+
+func ethFrame(t *testing.T, src, dst string) []byte {
+	srcMAC, _ := net.ParseMAC(src)
+	dstMAC, _ := net.ParseMAC(dst)
+	eth := layers.Ethernet{SrcMAC: srcMAC, DstMAC: dstMAC, EthernetType: layers.EthernetTypeIPv4}
+	buf := gopacket.NewSerializeBuffer()
+	err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{}, &eth, gopacket.Payload(make([]byte, 46)))
+	if err != nil {
+		t.Fatalf("Failed to build frame: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func receive(t *testing.T, ch chan []byte) []byte {
+	select {
+	case data := <-ch:
+		return data
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for frame")
+	}
+	return nil
+}
+
+func TestBridgeForwardsBothWays(t *testing.T) {
+	a, b := porttest.New(), porttest.New()
+	stop := make(chan struct{})
+	var capture bytes.Buffer
+	br := New(Config{Capture: &capture, StopChan: stop})
+
+	done := make(chan error)
+	go func() { done <- br.Run(a, b) }()
+
+	frameAB := ethFrame(t, "00:00:00:00:00:0a", "00:00:00:00:00:0b")
+	frameBA := ethFrame(t, "00:00:00:00:00:0b", "00:00:00:00:00:0a")
+	a.In <- frameAB
+	b.In <- frameBA
+
+	if got := receive(t, b.Out); !bytes.Equal(got, frameAB) {
+		t.Errorf("A->B frame mismatch")
+	}
+	if got := receive(t, a.Out); !bytes.Equal(got, frameBA) {
+		t.Errorf("B->A frame mismatch")
+	}
+
+	close(stop)
+	<-done
+
+	if s := br.Stats(); s.Forwarded != 2 {
+		t.Errorf("Expected 2 forwarded frames, got %d", s.Forwarded)
+	}
+
+	r, err := pcapgo.NewReader(&capture)
+	if err != nil {
+		t.Fatalf("Capture is not a valid pcap: %v", err)
+	}
+	count := 0
+	for {
+		if _, _, err := r.ReadPacketData(); err != nil {
+			break
+		}
+		count++
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 captured frames, got %d", count)
+	}
+}
+
+func TestBridgeRules(t *testing.T) {
+	a, b := porttest.New(), porttest.New()
+	stop := make(chan struct{})
+	from, _ := net.ParseMAC("00:00:00:00:00:0a")
+	to, _ := net.ParseMAC("02:00:00:00:00:99")
+	br := New(Config{
+		Rules:    []Rule{DropBPDUs(), RewriteMAC(DirectionAToB, from, to)},
+		StopChan: stop,
+	})
+
+	done := make(chan error)
+	go func() { done <- br.Run(a, b) }()
+
+	a.In <- ethFrame(t, "00:00:00:00:00:0c", "01:80:c2:00:00:00")
+	a.In <- ethFrame(t, "00:00:00:00:00:0a", "00:00:00:00:00:0b")
+
+	got := receive(t, b.Out)
+	if !bytes.Equal(got[6:12], to) {
+		t.Errorf("Expected source MAC rewritten to %v, got %v", to, net.HardwareAddr(got[6:12]))
+	}
+	select {
+	case <-b.Out:
+		t.Error("BPDU should have been dropped")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(stop)
+	<-done
+
+	s := br.Stats()
+	if s.Dropped != 1 || s.Rewritten != 1 {
+		t.Errorf("Expected 1 dropped and 1 rewritten, got %+v", s)
+	}
+}
+
+func TestBridgeSendsBPDUsOnBothSides(t *testing.T) {
+	a, b := porttest.New(), porttest.New()
+	stop := make(chan struct{})
+	br := New(Config{
+		BPDUGenerator: func(side Side) ([]byte, error) {
+			return []byte{byte(side)}, nil
+		},
+		BPDUInterval: time.Hour,
+		StopChan:     stop,
+	})
+
+	done := make(chan error)
+	go func() { done <- br.Run(a, b) }()
+
+	if got := receive(t, a.Out); got[0] != byte(SideA) {
+		t.Errorf("Expected side A BPDU on port A, got %v", got)
+	}
+	if got := receive(t, b.Out); got[0] != byte(SideB) {
+		t.Errorf("Expected side B BPDU on port B, got %v", got)
+	}
+
+	close(stop)
+	<-done
+}
@@ -0,0 +1,68 @@
+package bridge
+
+import (
+	"bytes"
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+var stpMulticast = net.HardwareAddr{0x01, 0x80, 0xc2, 0x00, 0x00, 0x00}
+
+// DropBPDUs keeps the switches on either side from seeing each other's BPDUs,
+// so that our injected root claim is the only one they hear.
+func DropBPDUs() Rule {
+	return DropDestination(stpMulticast)
+}
+
+// DropDestination drops every frame sent to dst
+func DropDestination(dst net.HardwareAddr) Rule {
+	return Rule{
+		Name:      "drop dst " + dst.String(),
+		Direction: DirectionBoth,
+		Match: func(packet gopacket.Packet) bool {
+			eth, ok := packet.LinkLayer().(*layers.Ethernet)
+			return ok && bytes.Equal(eth.DstMAC, dst)
+		},
+		Action: ActionDrop,
+	}
+}
+
+// DropEtherType drops every frame carrying the given EtherType
+func DropEtherType(t layers.EthernetType) Rule {
+	return Rule{
+		Name:      "drop type " + t.String(),
+		Direction: DirectionBoth,
+		Match: func(packet gopacket.Packet) bool {
+			eth, ok := packet.LinkLayer().(*layers.Ethernet)
+			return ok && eth.EthernetType == t
+		},
+		Action: ActionDrop,
+	}
+}
+
+// RewriteMAC replaces every occurrence of from with to in the Ethernet source and destination
+func RewriteMAC(dir Direction, from, to net.HardwareAddr) Rule {
+	return Rule{
+		Name:      "rewrite " + from.String() + " -> " + to.String(),
+		Direction: dir,
+		Match: func(packet gopacket.Packet) bool {
+			eth, ok := packet.LinkLayer().(*layers.Ethernet)
+			return ok && (bytes.Equal(eth.SrcMAC, from) || bytes.Equal(eth.DstMAC, from))
+		},
+		Action: ActionRewrite,
+		Rewrite: func(frame []byte) []byte {
+			if len(frame) < 12 {
+				return frame
+			}
+			if bytes.Equal(frame[0:6], from) {
+				copy(frame[0:6], to)
+			}
+			if bytes.Equal(frame[6:12], from) {
+				copy(frame[6:12], to)
+			}
+			return frame
+		},
+	}
+}
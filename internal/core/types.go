@@ -2,6 +2,8 @@ package core
 
 import (
 	"time"
+
+	"github.com/google/gopacket"
 )
 
 // Interface represents a network interface available for attacks
@@ -21,5 +23,22 @@ type AttackConfig struct {
 	Generator    PacketGenerator
 	StaticPacket []byte
 	Frequency    time.Duration
+	Burst        int // packets sent per tick, 0 means 1
+	// Batch, when set, returns every packet of a tick instead of Generator/StaticPacket
+	Batch        func() ([][]byte, error)
+	// Restore, when set, returns packets sent RestoreRounds times after StopChan closes
+	Restore      func() ([][]byte, error)
+	RestoreRounds int
 	StopChan     chan struct{}
 }
+
+// PacketHandler is called for every packet read by a capture
+type PacketHandler func(packet gopacket.Packet)
+
+// CaptureConfig configuration for a passive capture
+type CaptureConfig struct {
+	InterfaceName string
+	Filter        string
+	Handler       PacketHandler
+	StopChan      chan struct{}
+}
@@ -0,0 +1,255 @@
+package forward
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+)
+
+// Mode selects who forwards the intercepted traffic
+type Mode int
+
+const (
+	ModeOff Mode = iota
+	// ModeKernel turns on ip_forward and lets the kernel route; we only count.
+	ModeKernel
+	// ModeUserspace turns off ip_forward and relays intercepted frames to the real next hop ourselves.
+	ModeUserspace
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeKernel:
+		return "kernel"
+	case ModeUserspace:
+		return "userspace"
+	}
+	return "off"
+}
+
+// Port is where intercepted frames are read from and written back to
+type Port interface {
+	ReadPacketData() ([]byte, gopacket.CaptureInfo, error)
+	WritePacketData(data []byte) error
+}
+
+// Config configuration for a forwarder
+type Config struct {
+	OurMAC net.HardwareAddr
+	// OurIPs are our own addresses; traffic to them is not intercepted.
+	OurIPs []net.IP
+	// Subnet is the local network. Other destinations are sent to Gateway.
+	Subnet  *net.IPNet
+	Gateway net.IP
+	// Resolve returns the real MAC of a local address
+	Resolve func(ip net.IP) (net.HardwareAddr, bool)
+	// Capture receives every intercepted frame in pcap format when set.
+	Capture  io.Writer
+	StopChan chan struct{}
+}
+
+// Stats counters of a forwarder
+type Stats struct {
+	Intercepted uint64
+	Forwarded   uint64
+	// Unresolved counts frames dropped because the next hop's MAC is unknown
+	Unresolved uint64
+}
+
+// HostStats is the intercepted traffic of one source host
+type HostStats struct {
+	IP       net.IP
+	Packets  uint64
+	Bytes    uint64
+	Flows    int
+	LastSeen time.Time
+}
+
+type host struct {
+	HostStats
+	flows map[string]struct{}
+}
+
+// Forwarder accounts for and relays the traffic a MitM attack pulls to us
+type Forwarder struct {
+	cfg   Config
+	stats Stats
+
+	mu    sync.Mutex
+	hosts map[string]*host
+
+	captureMu sync.Mutex
+	capture   *pcapgo.Writer
+}
+
+// New creates a forwarder from cfg
+func New(cfg Config) (*Forwarder, error) {
+	f := &Forwarder{cfg: cfg, hosts: make(map[string]*host)}
+	if cfg.Capture != nil {
+		f.capture = pcapgo.NewWriter(cfg.Capture)
+		if err := f.capture.WriteFileHeader(65536, layers.LinkTypeEthernet); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// Stats returns a snapshot of the forwarder counters
+func (f *Forwarder) Stats() Stats {
+	return Stats{
+		Intercepted: atomic.LoadUint64(&f.stats.Intercepted),
+		Forwarded:   atomic.LoadUint64(&f.stats.Forwarded),
+		Unresolved:  atomic.LoadUint64(&f.stats.Unresolved),
+	}
+}
+
+// Hosts returns the per host counters, busiest first
+func (f *Forwarder) Hosts() []HostStats {
+	f.mu.Lock()
+	out := make([]HostStats, 0, len(f.hosts))
+	for _, h := range f.hosts {
+		out = append(out, h.HostStats)
+	}
+	f.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Bytes != out[j].Bytes {
+			return out[i].Bytes > out[j].Bytes
+		}
+		return bytes.Compare(out[i].IP, out[j].IP) < 0
+	})
+	return out
+}
+
+// Count records packet if it was sent to our MAC for someone else's IP. It reports whether it was.
+func (f *Forwarder) Count(packet gopacket.Packet) bool {
+	eth, ok := packet.LinkLayer().(*layers.Ethernet)
+	if !ok || !bytes.Equal(eth.DstMAC, f.cfg.OurMAC) || bytes.Equal(eth.SrcMAC, f.cfg.OurMAC) {
+		return false
+	}
+	ip4, ok := packet.NetworkLayer().(*layers.IPv4)
+	if !ok || f.ours(ip4.DstIP) {
+		return false
+	}
+
+	now := packet.Metadata().Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+	atomic.AddUint64(&f.stats.Intercepted, 1)
+
+	f.mu.Lock()
+	h, ok := f.hosts[ip4.SrcIP.String()]
+	if !ok {
+		h = &host{HostStats: HostStats{IP: append(net.IP(nil), ip4.SrcIP...)}, flows: make(map[string]struct{})}
+		f.hosts[ip4.SrcIP.String()] = h
+	}
+	h.Packets++
+	h.Bytes += uint64(len(packet.Data()))
+	h.LastSeen = now
+	h.flows[flowKey(ip4, packet.TransportLayer())] = struct{}{}
+	h.Flows = len(h.flows)
+	f.mu.Unlock()
+
+	f.record(packet.Metadata().CaptureInfo, packet.Data())
+	return true
+}
+
+// Rewrite returns an intercepted frame readdressed from us to the real next hop
+func (f *Forwarder) Rewrite(packet gopacket.Packet) ([]byte, bool) {
+	ip4, ok := packet.NetworkLayer().(*layers.IPv4)
+	if !ok {
+		return nil, false
+	}
+
+	next := ip4.DstIP
+	if f.cfg.Subnet != nil && !f.cfg.Subnet.Contains(next) && f.cfg.Gateway != nil {
+		next = f.cfg.Gateway
+	}
+	mac, ok := f.cfg.Resolve(next)
+	if !ok {
+		atomic.AddUint64(&f.stats.Unresolved, 1)
+		return nil, false
+	}
+
+	frame := append([]byte(nil), packet.Data()...)
+	copy(frame[0:6], mac)
+	copy(frame[6:12], f.cfg.OurMAC)
+	return frame, true
+}
+
+// Run relays intercepted frames read from port back out of it until the stop channel is closed
+func (f *Forwarder) Run(port Port) error {
+	for {
+		select {
+		case <-f.cfg.StopChan:
+			return nil
+		default:
+		}
+
+		data, ci, err := port.ReadPacketData()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil || len(data) == 0 {
+			continue
+		}
+
+		packet := gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.NoCopy)
+		packet.Metadata().CaptureInfo = ci
+		if !f.Count(packet) {
+			continue
+		}
+		frame, ok := f.Rewrite(packet)
+		if !ok {
+			continue
+		}
+		if err := port.WritePacketData(frame); err != nil {
+			continue
+		}
+		atomic.AddUint64(&f.stats.Forwarded, 1)
+	}
+}
+
+func (f *Forwarder) ours(ip net.IP) bool {
+	for _, o := range f.cfg.OurIPs {
+		if o.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Forwarder) record(ci gopacket.CaptureInfo, frame []byte) {
+	if f.capture == nil {
+		return
+	}
+	ci.CaptureLength = len(frame)
+	ci.Length = len(frame)
+	if ci.Timestamp.IsZero() {
+		ci.Timestamp = time.Now()
+	}
+
+	f.captureMu.Lock()
+	defer f.captureMu.Unlock()
+	f.capture.WritePacket(ci, frame)
+}
+
+// flowKey identifies a flow by addresses, protocol and ports
+func flowKey(ip4 *layers.IPv4, transport gopacket.TransportLayer) string {
+	key := fmt.Sprintf("%s>%s/%d", ip4.SrcIP, ip4.DstIP, ip4.Protocol)
+	if transport != nil {
+		flow := transport.TransportFlow()
+		key += fmt.Sprintf(":%s>%s", flow.Src(), flow.Dst())
+	}
+	return key
+}
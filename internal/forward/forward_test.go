@@ -0,0 +1,118 @@
+package forward
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gnpaone/l2star/internal/porttest"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+)
+
+var (
+	ourMAC, _     = net.ParseMAC("00:11:22:33:44:55")
+	victimMAC, _  = net.ParseMAC("aa:aa:aa:aa:aa:01")
+	gatewayMAC, _ = net.ParseMAC("aa:aa:aa:aa:aa:fe")
+)
+
+func udpFrame(t *testing.T, dstMAC net.HardwareAddr, src, dst string, sport layers.UDPPort) []byte {
+	t.Helper()
+	eth := layers.Ethernet{SrcMAC: victimMAC, DstMAC: dstMAC, EthernetType: layers.EthernetTypeIPv4}
+	ip := layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: net.ParseIP(src), DstIP: net.ParseIP(dst)}
+	udp := layers.UDP{SrcPort: sport, DstPort: 53}
+	udp.SetNetworkLayerForChecksum(&ip)
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, &eth, &ip, &udp, gopacket.Payload("query")); err != nil {
+		t.Fatalf("Failed to build frame: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestForwarderRelaysToGateway(t *testing.T) {
+	_, subnet, _ := net.ParseCIDR("10.0.0.0/24")
+	var capture bytes.Buffer
+	stop := make(chan struct{})
+
+	f, err := New(Config{
+		OurMAC:  ourMAC,
+		OurIPs:  []net.IP{net.ParseIP("10.0.0.99")},
+		Subnet:  subnet,
+		Gateway: net.ParseIP("10.0.0.1"),
+		Resolve: func(ip net.IP) (net.HardwareAddr, bool) {
+			if ip.Equal(net.ParseIP("10.0.0.1")) {
+				return gatewayMAC, true
+			}
+			return nil, false
+		},
+		Capture:  &capture,
+		StopChan: stop,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	port := porttest.New()
+	done := make(chan struct{})
+	go func() {
+		f.Run(port)
+		close(done)
+	}()
+
+	// Intercepted twice on two flows, one frame for us, one not sent to our MAC, one to an unresolved host
+	port.In <- udpFrame(t, ourMAC, "10.0.0.10", "8.8.8.8", 5000)
+	port.In <- udpFrame(t, ourMAC, "10.0.0.10", "8.8.8.8", 5001)
+	port.In <- udpFrame(t, ourMAC, "10.0.0.10", "10.0.0.99", 5002)
+	port.In <- udpFrame(t, gatewayMAC, "10.0.0.10", "8.8.8.8", 5003)
+	port.In <- udpFrame(t, ourMAC, "10.0.0.10", "10.0.0.20", 5004)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case out := <-port.Out:
+			eth := gopacket.NewPacket(out, layers.LayerTypeEthernet, gopacket.Default).LinkLayer().(*layers.Ethernet)
+			if eth.DstMAC.String() != gatewayMAC.String() || eth.SrcMAC.String() != ourMAC.String() {
+				t.Errorf("Frame not readdressed to the gateway: %s -> %s", eth.SrcMAC, eth.DstMAC)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for forwarded frame")
+		}
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(stop)
+	<-done
+
+	select {
+	case <-port.Out:
+		t.Error("Forwarded a frame that was not intercepted")
+	default:
+	}
+
+	stats := f.Stats()
+	if stats.Intercepted != 3 || stats.Forwarded != 2 || stats.Unresolved != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	hosts := f.Hosts()
+	if len(hosts) != 1 || hosts[0].IP.String() != "10.0.0.10" || hosts[0].Packets != 3 || hosts[0].Flows != 3 {
+		t.Errorf("Unexpected host stats: %+v", hosts)
+	}
+
+	r, err := pcapgo.NewReader(&capture)
+	if err != nil {
+		t.Fatalf("Invalid pcap: %v", err)
+	}
+	n := 0
+	for {
+		if _, _, err := r.ReadPacketData(); err != nil {
+			break
+		}
+		n++
+	}
+	if n != 3 {
+		t.Errorf("Captured %d frames, want 3", n)
+	}
+}
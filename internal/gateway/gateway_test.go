@@ -0,0 +1,129 @@
+package gateway
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gnpaone/l2star/internal/proto/arp"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+var (
+	ourMAC, _      = net.ParseMAC("00:11:22:33:44:55")
+	virtualMAC, _  = net.ParseMAC("00:00:0c:07:ac:0a")
+	victimMAC, _   = net.ParseMAC("aa:aa:aa:aa:aa:01")
+	upstreamMAC, _ = net.ParseMAC("aa:aa:aa:aa:aa:fe")
+)
+
+func newTestGateway(t *testing.T) *Gateway {
+	t.Helper()
+	g, err := New(Config{
+		VIP:        net.ParseIP("10.0.0.1"),
+		VirtualMAC: virtualMAC,
+		OurMAC:     ourMAC,
+		Upstream:   net.ParseIP("10.0.0.2"),
+		Resolve: func(ip net.IP) (net.HardwareAddr, bool) {
+			return upstreamMAC, ip.Equal(net.ParseIP("10.0.0.2"))
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func decode(data []byte) gopacket.Packet {
+	return gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)
+}
+
+func TestGatewayAnswersARPForVIP(t *testing.T) {
+	g := newTestGateway(t)
+
+	req, _ := arp.CraftARPRequest(victimMAC, net.ParseIP("10.0.0.10"), net.ParseIP("10.0.0.1"))
+	replies := g.Handle(decode(req))
+	if len(replies) != 1 {
+		t.Fatalf("Expected an ARP reply, got %d", len(replies))
+	}
+	a := decode(replies[0]).Layer(layers.LayerTypeARP).(*layers.ARP)
+	if net.HardwareAddr(a.SourceHwAddress).String() != virtualMAC.String() || !net.IP(a.SourceProtAddress).Equal(net.ParseIP("10.0.0.1")) {
+		t.Errorf("Expected the VIP at the virtual MAC, got %v at %v", net.IP(a.SourceProtAddress), net.HardwareAddr(a.SourceHwAddress))
+	}
+
+	other, _ := arp.CraftARPRequest(victimMAC, net.ParseIP("10.0.0.10"), net.ParseIP("10.0.0.3"))
+	if replies := g.Handle(decode(other)); len(replies) != 0 {
+		t.Error("Answered for an address other than the VIP")
+	}
+
+	now := time.Now()
+	if due := g.Due(now); len(due) != 1 {
+		t.Errorf("Expected a gratuitous ARP at start, got %d", len(due))
+	}
+	if due := g.Due(now.Add(time.Second)); len(due) != 0 {
+		t.Error("Announced again without an interval")
+	}
+}
+
+func TestGatewayRelaysVirtualMACTraffic(t *testing.T) {
+	g := newTestGateway(t)
+
+	eth := layers.Ethernet{SrcMAC: victimMAC, DstMAC: virtualMAC, EthernetType: layers.EthernetTypeIPv4}
+	ip := layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: net.ParseIP("10.0.0.10"), DstIP: net.ParseIP("8.8.8.8")}
+	udp := layers.UDP{SrcPort: 5000, DstPort: 53}
+	udp.SetNetworkLayerForChecksum(&ip)
+	buf := gopacket.NewSerializeBuffer()
+	gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}, &eth, &ip, &udp, gopacket.Payload("query"))
+
+	out := g.Handle(decode(buf.Bytes()))
+	if len(out) != 1 {
+		t.Fatalf("Expected the frame relayed, got %d frames", len(out))
+	}
+	relayed := decode(out[0]).LinkLayer().(*layers.Ethernet)
+	if relayed.DstMAC.String() != upstreamMAC.String() || relayed.SrcMAC.String() != ourMAC.String() {
+		t.Errorf("Relayed %v -> %v", relayed.SrcMAC, relayed.DstMAC)
+	}
+
+	victims := g.Victims()
+	if len(victims) != 1 || victims[0].MAC.String() != victimMAC.String() || !victims[0].IP.Equal(net.ParseIP("10.0.0.10")) || victims[0].Packets != 1 {
+		t.Errorf("Unexpected victims: %+v", victims)
+	}
+
+	// Our own relayed frames seen on the capture are not relayed again
+	if again := g.Handle(decode(out[0])); len(again) != 0 {
+		t.Error("Relayed our own frame")
+	}
+	if s := g.Stats(); s.Relayed != 1 || s.Unresolved != 0 {
+		t.Errorf("Unexpected stats: %+v", s)
+	}
+}
+
+func TestGatewayRelaysEverythingUpstream(t *testing.T) {
+	g := newTestGateway(t)
+
+	// Even a destination on our subnet goes through the real router, as the victim asked
+	eth := layers.Ethernet{SrcMAC: victimMAC, DstMAC: virtualMAC, EthernetType: layers.EthernetTypeIPv4}
+	ip := layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolICMPv4, SrcIP: net.ParseIP("10.0.0.10"), DstIP: net.ParseIP("10.0.0.20")}
+	buf := gopacket.NewSerializeBuffer()
+	gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true}, &eth, &ip, gopacket.Payload("ping"))
+	frame := buf.Bytes()
+
+	out := g.Handle(decode(frame))
+	if len(out) != 1 || decode(out[0]).LinkLayer().(*layers.Ethernet).DstMAC.String() != upstreamMAC.String() {
+		t.Fatalf("Expected the frame relayed to the upstream router, got %d frames", len(out))
+	}
+
+	cfg := g.Config()
+	cfg.Upstream = net.ParseIP("10.0.0.3")
+	unresolved, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := unresolved.Handle(decode(frame)); len(out) != 0 {
+		t.Error("Relayed a frame to an unresolved upstream router")
+	}
+	if s := unresolved.Stats(); s.Relayed != 0 || s.Unresolved != 1 {
+		t.Errorf("Unexpected stats: %+v", s)
+	}
+}
@@ -0,0 +1,41 @@
+package net
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gnpaone/l2star/internal/bridge"
+
+	"github.com/google/gopacket/pcap"
+)
+
+// StartBridge forwards frames between ifaceA and ifaceB through br until its stop channel is closed
+func StartBridge(ifaceA, ifaceB string, br *bridge.Bridge) error {
+	a, err := openPort(ifaceA)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	b, err := openPort(ifaceB)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	return br.Run(a, b)
+}
+
+// openPort opens an interface for bridging. Only inbound frames are read so
+// that frames we write out of a port are not picked up and forwarded back.
+func openPort(name string) (*pcap.Handle, error) {
+	handle, err := pcap.OpenLive(name, 65536, true, 100*time.Millisecond)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open device %s: %v", name, err)
+	}
+	if err := handle.SetDirection(pcap.DirectionIn); err != nil {
+		handle.Close()
+		return nil, fmt.Errorf("failed to set direction on %s: %v", name, err)
+	}
+	return handle, nil
+}
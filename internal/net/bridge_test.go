@@ -0,0 +1,69 @@
+package net
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/gnpaone/l2star/internal/bridge"
+)
+
+// TestBridgeVethNamespaces bridges two veth pairs whose far ends live in
+// separate network namespaces and checks that the namespaces can ping each other.
+func TestBridgeVethNamespaces(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+	if _, err := exec.LookPath("ip"); err != nil {
+		t.Skip("requires iproute2")
+	}
+	if _, err := exec.LookPath("ping"); err != nil {
+		t.Skip("requires ping")
+	}
+
+	run := func(args ...string) {
+		t.Helper()
+		if out, err := exec.Command("ip", args...).CombinedOutput(); err != nil {
+			t.Fatalf("ip %v: %v: %s", args, err, out)
+		}
+	}
+
+	run("netns", "add", "l2star-a")
+	defer exec.Command("ip", "netns", "del", "l2star-a").Run()
+	run("netns", "add", "l2star-b")
+	defer exec.Command("ip", "netns", "del", "l2star-b").Run()
+
+	run("link", "add", "l2s-a0", "type", "veth", "peer", "name", "l2s-a1")
+	defer exec.Command("ip", "link", "del", "l2s-a1").Run()
+	run("link", "add", "l2s-b0", "type", "veth", "peer", "name", "l2s-b1")
+	defer exec.Command("ip", "link", "del", "l2s-b1").Run()
+
+	run("link", "set", "l2s-a0", "netns", "l2star-a")
+	run("link", "set", "l2s-b0", "netns", "l2star-b")
+	run("-n", "l2star-a", "addr", "add", "10.99.0.1/24", "dev", "l2s-a0")
+	run("-n", "l2star-b", "addr", "add", "10.99.0.2/24", "dev", "l2s-b0")
+	run("-n", "l2star-a", "link", "set", "l2s-a0", "up")
+	run("-n", "l2star-b", "link", "set", "l2s-b0", "up")
+	run("link", "set", "l2s-a1", "up")
+	run("link", "set", "l2s-b1", "up")
+
+	stop := make(chan struct{})
+	br := bridge.New(bridge.Config{StopChan: stop})
+	done := make(chan error)
+	go func() { done <- StartBridge("l2s-a1", "l2s-b1", br) }()
+	time.Sleep(500 * time.Millisecond)
+
+	out, err := exec.Command("ip", "netns", "exec", "l2star-a", "ping", "-c", "2", "-W", "2", "10.99.0.2").CombinedOutput()
+	close(stop)
+	if bridgeErr := <-done; bridgeErr != nil {
+		t.Fatalf("Bridge failed: %v", bridgeErr)
+	}
+	if err != nil {
+		t.Fatalf("Ping across bridge failed: %v: %s", err, out)
+	}
+
+	if s := br.Stats(); s.Forwarded == 0 {
+		t.Errorf("Expected forwarded frames, got %+v", s)
+	}
+}
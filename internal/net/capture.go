@@ -0,0 +1,49 @@
+package net
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gnpaone/l2star/internal/core"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/pcap"
+)
+
+// StartCapture reads packets from the specified interface and hands them to cfg.Handler
+// until cfg.StopChan is closed. Only packets received by the interface are reported.
+func StartCapture(cfg core.CaptureConfig) error {
+	handle, err := pcap.OpenLive(cfg.InterfaceName, 65536, true, 250*time.Millisecond)
+	if err != nil {
+		return fmt.Errorf("failed to open device: %v", err)
+	}
+	defer handle.Close()
+
+	if cfg.Filter != "" {
+		if err := handle.SetBPFFilter(cfg.Filter); err != nil {
+			return fmt.Errorf("failed to set filter %q: %v", cfg.Filter, err)
+		}
+	}
+	handle.SetDirection(pcap.DirectionIn)
+
+	for {
+		select {
+		case <-cfg.StopChan:
+			return nil
+		default:
+		}
+
+		data, ci, err := handle.ReadPacketData()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			continue
+		}
+
+		packet := gopacket.NewPacket(data, handle.LinkType(), gopacket.Default)
+		packet.Metadata().CaptureInfo = ci
+		cfg.Handler(packet)
+	}
+}
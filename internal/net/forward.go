@@ -0,0 +1,129 @@
+package net
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gnpaone/l2star/internal/forward"
+)
+
+// sysctlRoot is where the sysctls are read and written, replaced in tests
+var sysctlRoot = "/proc/sys"
+
+type sysctl struct{ path, value string }
+
+// forwardSysctls are set while kernel forwarding is on. Redirects are disabled so the
+// kernel does not tell victims about the real gateway: Linux sends them when either
+// conf/all or conf/<iface> allows it, and the per-interface value defaults to on.
+func forwardSysctls(iface string) []sysctl {
+	return []sysctl{
+		{"net/ipv4/ip_forward", "1"},
+		{"net/ipv4/conf/all/send_redirects", "0"},
+		{"net/ipv4/conf/default/send_redirects", "0"},
+		{"net/ipv4/conf/" + iface + "/send_redirects", "0"},
+	}
+}
+
+// userspaceSysctls are set while we relay in userspace, so the kernel does not forward the frames a second time
+var userspaceSysctls = []sysctl{
+	{"net/ipv4/ip_forward", "0"},
+}
+
+// savedSysctls holds the values found before EnableIPForward or DisableIPForward changed them
+var savedSysctls = struct {
+	sync.Mutex
+	values map[string]string
+}{values: make(map[string]string)}
+
+// EnableIPForward turns on kernel IPv4 forwarding and the ICMP redirects of iface off.
+// RestoreIPForward puts the previous settings back.
+func EnableIPForward(iface string) error {
+	return setSysctls(forwardSysctls(iface))
+}
+
+// DisableIPForward turns off kernel IPv4 forwarding while frames are relayed in userspace.
+// RestoreIPForward puts the previous setting back.
+func DisableIPForward() error {
+	return setSysctls(userspaceSysctls)
+}
+
+// setSysctls writes sysctls, saving the values they had first for RestoreIPForward
+func setSysctls(sysctls []sysctl) error {
+	savedSysctls.Lock()
+	defer savedSysctls.Unlock()
+
+	for _, s := range sysctls {
+		path := filepath.Join(sysctlRoot, s.path)
+		old, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", path, err)
+		}
+		if _, saved := savedSysctls.values[path]; !saved {
+			savedSysctls.values[path] = strings.TrimSpace(string(old))
+		}
+		if err := os.WriteFile(path, []byte(s.value), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// RestoreIPForward restores the settings changed by EnableIPForward or DisableIPForward. It does nothing if they were not changed.
+func RestoreIPForward() error {
+	savedSysctls.Lock()
+	defer savedSysctls.Unlock()
+
+	var firstErr error
+	for path, value := range savedSysctls.values {
+		if err := os.WriteFile(path, []byte(value), 0644); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to restore %s: %v", path, err)
+		}
+		delete(savedSysctls.values, path)
+	}
+	return firstErr
+}
+
+// StartForwarder relays the frames intercepted on iface through fw until its stop channel is closed
+func StartForwarder(iface string, fw *forward.Forwarder) error {
+	port, err := openPort(iface)
+	if err != nil {
+		return err
+	}
+	defer port.Close()
+
+	return fw.Run(port)
+}
+
+// DefaultGateway returns the IPv4 default gateway routed through iface, or nil
+func DefaultGateway(iface string) net.IP {
+	f, err := os.Open("/proc/net/route")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Iface Destination Gateway Flags ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[0] != iface || fields[1] != "00000000" {
+			continue
+		}
+		raw, err := hex.DecodeString(fields[2])
+		if err != nil || len(raw) != 4 {
+			continue
+		}
+		// The kernel prints the address in host (little endian) order
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, binary.LittleEndian.Uint32(raw))
+		return ip
+	}
+	return nil
+}
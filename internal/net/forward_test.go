@@ -0,0 +1,59 @@
+package net
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnableIPForwardDisablesInterfaceRedirects(t *testing.T) {
+	root := t.TempDir()
+	defer func(old string) { sysctlRoot = old }(sysctlRoot)
+	sysctlRoot = root
+
+	initial := map[string]string{
+		"net/ipv4/ip_forward":                  "0",
+		"net/ipv4/conf/all/send_redirects":     "1",
+		"net/ipv4/conf/default/send_redirects": "1",
+		"net/ipv4/conf/eth0/send_redirects":    "1",
+	}
+	for path, value := range initial {
+		full := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(value+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(path string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(root, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(string(data))
+	}
+
+	if err := EnableIPForward("eth0"); err != nil {
+		t.Fatalf("EnableIPForward: %v", err)
+	}
+	if read("net/ipv4/ip_forward") != "1" {
+		t.Error("ip_forward not turned on")
+	}
+	for _, path := range []string{"net/ipv4/conf/all/send_redirects", "net/ipv4/conf/default/send_redirects", "net/ipv4/conf/eth0/send_redirects"} {
+		if read(path) != "0" {
+			t.Errorf("%s not turned off", path)
+		}
+	}
+
+	if err := RestoreIPForward(); err != nil {
+		t.Fatalf("RestoreIPForward: %v", err)
+	}
+	for path, value := range initial {
+		if got := read(path); got != value {
+			t.Errorf("%s restored to %q, want %q", path, got, value)
+		}
+	}
+}
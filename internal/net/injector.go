@@ -23,9 +23,14 @@ func ListInterfaces() ([]core.Interface, error) {
 			ips = append(ips, addr.IP.String())
 		}
 
+		desc := dev.Description
+		if vlan := vlanDescription(dev.Name); vlan != "" {
+			desc = vlan
+		}
+
 		interfaces = append(interfaces, core.Interface{
 			Name:        dev.Name,
-			Description: dev.Description,
+			Description: desc,
 			IPs:         ips,
 		})
 	}
@@ -40,35 +45,90 @@ func StartAttack(cfg core.AttackConfig) error {
 	}
 	defer handle.Close()
 
+	runAttack(handle, cfg)
+	return nil
+}
+
+// packetWriter sends raw frames, as a pcap handle does
+type packetWriter interface {
+	WritePacketData(data []byte) error
+}
+
+// runAttack sends the attack's packets on w every tick until StopChan is closed, then its
+// restore packets. It only returns once the restore packets are sent.
+func runAttack(w packetWriter, cfg core.AttackConfig) {
 	if cfg.Frequency == 0 {
 		cfg.Frequency = 1 * time.Second
 	}
 
+	burst := cfg.Burst
+	if burst <= 0 {
+		burst = 1
+	}
+
 	ticker := time.NewTicker(cfg.Frequency)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-cfg.StopChan:
-			return nil
+			if cfg.Restore != nil {
+				restore(w, cfg)
+			}
+			return
 		case <-ticker.C:
-			var packet []byte
-			var err error
-
-			if cfg.Generator != nil {
-				packet, err = cfg.Generator()
+			if cfg.Batch != nil {
+				packets, err := cfg.Batch()
 				if err != nil {
 					continue
 				}
-			} else {
-				packet = cfg.StaticPacket
+				for _, packet := range packets {
+					w.WritePacketData(packet)
+				}
+				continue
 			}
+			for i := 0; i < burst; i++ {
+				var packet []byte
+				var err error
 
-			if len(packet) > 0 {
-				if err := handle.WritePacketData(packet); err != nil {
-					// Ignore error to keep UI clean? TODO: Show the error somewhere
+				if cfg.Generator != nil {
+					packet, err = cfg.Generator()
+					if err != nil {
+						continue
+					}
+				} else {
+					packet = cfg.StaticPacket
+				}
+
+				if len(packet) > 0 {
+					if err := w.WritePacketData(packet); err != nil {
+						// Ignore error to keep UI clean? TODO: Show the error somewhere
+					}
 				}
 			}
 		}
 	}
 }
+
+// restoreInterval is the pause between the rounds of restore packets
+const restoreInterval = 500 * time.Millisecond
+
+// restore sends the attack's restore packets a few times so the victims pick them up
+func restore(w packetWriter, cfg core.AttackConfig) {
+	rounds := cfg.RestoreRounds
+	if rounds <= 0 {
+		rounds = 1
+	}
+	for i := 0; i < rounds; i++ {
+		if i > 0 {
+			time.Sleep(restoreInterval)
+		}
+		packets, err := cfg.Restore()
+		if err != nil {
+			return
+		}
+		for _, packet := range packets {
+			w.WritePacketData(packet)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package net
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gnpaone/l2star/internal/core"
+)
+
+// recorder keeps the frames written to it
+type recorder struct {
+	mu     sync.Mutex
+	frames []string
+}
+
+func (r *recorder) WritePacketData(data []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.frames = append(r.frames, string(data))
+	return nil
+}
+
+func TestAttackSendsRestoreBeforeReturning(t *testing.T) {
+	w := &recorder{}
+	stop := make(chan struct{})
+	cfg := core.AttackConfig{
+		Batch:         func() ([][]byte, error) { return [][]byte{[]byte("attack")}, nil },
+		Restore:       func() ([][]byte, error) { return [][]byte{[]byte("restore")}, nil },
+		RestoreRounds: 2,
+		Frequency:     10 * time.Millisecond,
+		StopChan:      stop,
+	}
+
+	done := make(chan struct{})
+	go func() {
+		runAttack(w, cfg)
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(stop)
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Attack did not return after stop")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	restores := 0
+	for _, f := range w.frames {
+		if f == "restore" {
+			restores++
+		}
+	}
+	if restores != 2 {
+		t.Errorf("Expected 2 restore rounds before returning, got %d in %v", restores, w.frames)
+	}
+	if w.frames[len(w.frames)-1] != "restore" {
+		t.Errorf("Attack packets sent after the restore: %v", w.frames)
+	}
+}
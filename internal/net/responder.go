@@ -0,0 +1,74 @@
+package net
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gnpaone/l2star/internal/core"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/pcap"
+)
+
+// responderPoll is how often delayed responder replies are checked
+const responderPoll = 20 * time.Millisecond
+
+// Responder answers captured packets, possibly with replies that only become due later
+type Responder interface {
+	Handle(packet gopacket.Packet) [][]byte
+	Due(now time.Time) [][]byte
+	StopChan() chan struct{}
+}
+
+// StartResponder feeds the traffic matching filter on iface to r and sends its
+// replies as soon as they are due, until r's stop channel is closed
+func StartResponder(iface, filter string, r Responder) error {
+	handle, err := pcap.OpenLive(iface, 1600, true, pcap.BlockForever)
+	if err != nil {
+		return fmt.Errorf("failed to open device: %v", err)
+	}
+	defer handle.Close()
+
+	// The ticker and the capture both send on the handle
+	var mu sync.Mutex
+	send := func(packets [][]byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, packet := range packets {
+			handle.WritePacketData(packet)
+		}
+	}
+
+	stop := r.StopChan()
+	// The handle is only closed once the ticker is done, also when the capture fails
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	defer func() {
+		close(done)
+		wg.Wait()
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(responderPoll)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-done:
+				return
+			case now := <-ticker.C:
+				send(r.Due(now))
+			}
+		}
+	}()
+
+	return StartCapture(core.CaptureConfig{
+		InterfaceName: iface,
+		Filter:        filter,
+		Handler:       func(packet gopacket.Packet) { send(r.Handle(packet)) },
+		StopChan:      stop,
+	})
+}
@@ -0,0 +1,78 @@
+package net
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/vishvananda/netlink"
+)
+
+// subinterfaces are the 802.1Q subinterfaces we created, so they can be removed on exit
+var subinterfaces = struct {
+	sync.Mutex
+	names map[string]uint16
+}{names: make(map[string]uint16)}
+
+// CreateVLANInterface creates and brings up the 802.1Q subinterface <parent>.<vid>.
+// It is removed by RemoveVLANInterfaces.
+func CreateVLANInterface(parent string, vid uint16) (string, error) {
+	name := fmt.Sprintf("%s.%d", parent, vid)
+	if len(name) > 15 {
+		// IFNAMSIZ
+		name = fmt.Sprintf("vlan%d", vid)
+	}
+
+	subinterfaces.Lock()
+	defer subinterfaces.Unlock()
+	if _, ok := subinterfaces.names[name]; ok {
+		return name, nil
+	}
+
+	link, err := netlink.LinkByName(parent)
+	if err != nil {
+		return "", fmt.Errorf("failed to find %s: %v", parent, err)
+	}
+
+	vlan := &netlink.Vlan{
+		LinkAttrs: netlink.LinkAttrs{Name: name, ParentIndex: link.Attrs().Index},
+		VlanId:    int(vid),
+	}
+	if err := netlink.LinkAdd(vlan); err != nil {
+		return "", fmt.Errorf("failed to create %s: %v", name, err)
+	}
+	subinterfaces.names[name] = vid
+
+	if err := netlink.LinkSetUp(vlan); err != nil {
+		return name, fmt.Errorf("failed to bring up %s: %v", name, err)
+	}
+	return name, nil
+}
+
+// RemoveVLANInterfaces deletes every subinterface created by CreateVLANInterface
+func RemoveVLANInterfaces() error {
+	subinterfaces.Lock()
+	defer subinterfaces.Unlock()
+
+	var firstErr error
+	for name := range subinterfaces.names {
+		link, err := netlink.LinkByName(name)
+		if err == nil {
+			err = netlink.LinkDel(link)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to remove %s: %v", name, err)
+		}
+		delete(subinterfaces.names, name)
+	}
+	return firstErr
+}
+
+// vlanDescription describes a subinterface we created, or returns "" for other interfaces
+func vlanDescription(name string) string {
+	subinterfaces.Lock()
+	defer subinterfaces.Unlock()
+	if vid, ok := subinterfaces.names[name]; ok {
+		return fmt.Sprintf("802.1Q VLAN %d (created by l2star)", vid)
+	}
+	return ""
+}
@@ -0,0 +1,48 @@
+package net
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// TestVLANInterfaces creates subinterfaces on a veth link and checks they are removed again
+func TestVLANInterfaces(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+	if _, err := exec.LookPath("ip"); err != nil {
+		t.Skip("requires iproute2")
+	}
+
+	if out, err := exec.Command("ip", "link", "add", "l2s-v0", "type", "veth", "peer", "name", "l2s-v1").CombinedOutput(); err != nil {
+		t.Skipf("cannot create veth pair: %v: %s", err, out)
+	}
+	defer exec.Command("ip", "link", "del", "l2s-v0").Run()
+
+	if out, err := exec.Command("ip", "link", "add", "link", "l2s-v0", "name", "l2s-vprobe", "type", "vlan", "id", "99").CombinedOutput(); err != nil {
+		t.Skipf("kernel without 802.1Q support: %v: %s", err, out)
+	}
+	exec.Command("ip", "link", "del", "l2s-vprobe").Run()
+
+	for _, vid := range []uint16{10, 20} {
+		name, err := CreateVLANInterface("l2s-v0", vid)
+		if err != nil {
+			t.Fatalf("CreateVLANInterface(%d): %v", vid, err)
+		}
+		out, err := exec.Command("ip", "-d", "link", "show", name).CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s was not created: %s", name, out)
+		}
+		if vlanDescription(name) == "" {
+			t.Errorf("%s is not described as ours", name)
+		}
+	}
+
+	if err := RemoveVLANInterfaces(); err != nil {
+		t.Fatalf("RemoveVLANInterfaces: %v", err)
+	}
+	if err := exec.Command("ip", "link", "show", "l2s-v0.10").Run(); err == nil {
+		t.Error("l2s-v0.10 still exists")
+	}
+}
@@ -0,0 +1,39 @@
+// Package porttest provides an in-memory packet port for the bridge and forwarder tests.
+package porttest
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/gopacket"
+)
+
+// ErrTimeout is returned by ReadPacketData when no frame arrives in time, like a pcap read timeout
+var ErrTimeout = errors.New("timeout")
+
+// Port is an in-memory port: frames pushed to In are read from it, frames written to it land in Out
+type Port struct {
+	In  chan []byte
+	Out chan []byte
+}
+
+// New creates a port whose channels hold 16 frames
+func New() *Port {
+	return &Port{In: make(chan []byte, 16), Out: make(chan []byte, 16)}
+}
+
+// ReadPacketData returns the next frame pushed to In, or ErrTimeout after 10ms
+func (p *Port) ReadPacketData() ([]byte, gopacket.CaptureInfo, error) {
+	select {
+	case data := <-p.In:
+		return data, gopacket.CaptureInfo{Timestamp: time.Now(), Length: len(data), CaptureLength: len(data)}, nil
+	case <-time.After(10 * time.Millisecond):
+		return nil, gopacket.CaptureInfo{}, ErrTimeout
+	}
+}
+
+// WritePacketData copies data to Out
+func (p *Port) WritePacketData(data []byte) error {
+	p.Out <- append([]byte(nil), data...)
+	return nil
+}
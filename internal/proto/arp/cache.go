@@ -0,0 +1,86 @@
+package arp
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Entry is an IP to MAC binding learned from the wire
+type Entry struct {
+	IP        net.IP
+	MAC       net.HardwareAddr
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// Cache learns IP to MAC bindings from the ARP traffic it is fed. It is safe for concurrent use.
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]*Entry
+	// ignore is our own MAC, whose spoofed bindings must not be learned
+	ignore net.HardwareAddr
+}
+
+// NewCache creates an empty cache. Bindings announced by ignore, our own MAC, are not learned.
+func NewCache(ignore net.HardwareAddr) *Cache {
+	return &Cache{entries: make(map[string]*Entry), ignore: ignore}
+}
+
+// Update records the sender binding of the ARP packet, if any. It reports whether the packet was ARP.
+func (c *Cache) Update(packet gopacket.Packet) bool {
+	a, ok := packet.Layer(layers.LayerTypeARP).(*layers.ARP)
+	if !ok {
+		return false
+	}
+	ip := net.IP(a.SourceProtAddress).To4()
+	mac := net.HardwareAddr(a.SourceHwAddress)
+	if ip == nil || ip.IsUnspecified() || len(mac) != 6 || bytes.Equal(mac, c.ignore) {
+		return true
+	}
+
+	now := packet.Metadata().Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[ip.String()]
+	if !ok {
+		e = &Entry{IP: append(net.IP(nil), ip...), FirstSeen: now}
+		c.entries[ip.String()] = e
+	}
+	e.MAC = append(net.HardwareAddr(nil), mac...)
+	e.LastSeen = now
+	return true
+}
+
+// Lookup returns the MAC last seen for ip
+func (c *Cache) Lookup(ip net.IP) (net.HardwareAddr, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[ip.String()]
+	if !ok {
+		return nil, false
+	}
+	return e.MAC, true
+}
+
+// Entries returns the known bindings sorted by IP
+func (c *Cache) Entries() []Entry {
+	c.mu.Lock()
+	out := make([]Entry, 0, len(c.entries))
+	for _, e := range c.entries {
+		out = append(out, *e)
+	}
+	c.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool { return bytes.Compare(out[i].IP, out[j].IP) < 0 })
+	return out
+}
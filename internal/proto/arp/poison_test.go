@@ -0,0 +1,97 @@
+package arp
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func decodeARP(t *testing.T, data []byte) (*layers.Ethernet, *layers.ARP) {
+	t.Helper()
+	pkt := gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)
+	eth, _ := pkt.Layer(layers.LayerTypeEthernet).(*layers.Ethernet)
+	a, _ := pkt.Layer(layers.LayerTypeARP).(*layers.ARP)
+	if eth == nil || a == nil {
+		t.Fatal("Not an ARP packet")
+	}
+	return eth, a
+}
+
+func TestPoisoner(t *testing.T) {
+	ours, _ := net.ParseMAC("00:11:22:33:44:55")
+	victimMAC, _ := net.ParseMAC("aa:aa:aa:aa:aa:01")
+	gwMAC, _ := net.ParseMAC("aa:aa:aa:aa:aa:fe")
+	victim, gw := net.ParseIP("10.0.0.10").To4(), net.ParseIP("10.0.0.1").To4()
+
+	cache := NewCache(ours)
+	p := NewPoisoner(PoisonConfig{
+		OurMAC:   ours,
+		OurIP:    net.ParseIP("10.0.0.99"),
+		Targets:  []net.IP{victim},
+		Gateways: []net.IP{gw},
+		Method:   MethodReply,
+		Cache:    cache,
+	})
+
+	round, err := p.Packets()
+	if err != nil {
+		t.Fatalf("Packets: %v", err)
+	}
+	if len(round) != 2 {
+		t.Fatalf("Expected two resolution requests, got %d packets", len(round))
+	}
+	for _, pkt := range round {
+		if _, a := decodeARP(t, pkt); a.Operation != layers.ARPRequest {
+			t.Errorf("Unresolved host should be asked for its MAC")
+		}
+	}
+
+	// The answers, and one of our own spoofed replies that must not be learned
+	for _, pkt := range [][]byte{
+		mustCraft(CraftARPReply(victimMAC, ours, victim, net.ParseIP("10.0.0.99"))),
+		mustCraft(CraftARPReply(gwMAC, ours, gw, net.ParseIP("10.0.0.99"))),
+		mustCraft(CraftARPReply(ours, victimMAC, net.ParseIP("10.0.0.50"), victim)),
+	} {
+		cache.Update(gopacket.NewPacket(pkt, layers.LayerTypeEthernet, gopacket.Default))
+	}
+	if _, ok := cache.Lookup(net.ParseIP("10.0.0.50")); ok {
+		t.Error("Cache learned a binding we sent ourselves")
+	}
+
+	round, _ = p.Packets()
+	if len(round) != 2 {
+		t.Fatalf("Expected one poison packet per direction, got %d", len(round))
+	}
+	eth, a := decodeARP(t, round[0])
+	if eth.DstMAC.String() != victimMAC.String() || !net.IP(a.SourceProtAddress).Equal(gw) || net.HardwareAddr(a.SourceHwAddress).String() != ours.String() {
+		t.Errorf("Victim should be told the gateway is at our MAC: %+v", a)
+	}
+	eth, a = decodeARP(t, round[1])
+	if eth.DstMAC.String() != gwMAC.String() || !net.IP(a.SourceProtAddress).Equal(victim) {
+		t.Errorf("Gateway should be told the victim is at our MAC: %+v", a)
+	}
+
+	restore, _ := p.RestorePackets()
+	if len(restore) != 2 {
+		t.Fatalf("Expected two restore packets, got %d", len(restore))
+	}
+	_, a = decodeARP(t, restore[0])
+	if net.HardwareAddr(a.SourceHwAddress).String() != gwMAC.String() || !net.IP(a.SourceProtAddress).Equal(gw) {
+		t.Errorf("Restore should carry the gateway's real MAC: %+v", a)
+	}
+
+	p.cfg.Method = MethodRequest
+	round, _ = p.Packets()
+	if _, a = decodeARP(t, round[0]); a.Operation != layers.ARPRequest {
+		t.Error("Request method should poison with unicast requests")
+	}
+}
+
+func mustCraft(pkt []byte, err error) []byte {
+	if err != nil {
+		panic(err)
+	}
+	return pkt
+}
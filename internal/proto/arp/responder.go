@@ -0,0 +1,201 @@
+package arp
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// ResponderConfig configuration for a reactive ARP responder
+type ResponderConfig struct {
+	OurMAC net.HardwareAddr
+	// IPs are always answered for with OurMAC
+	IPs []net.IP
+	// FollowUps extra replies are sent FollowUpInterval apart after each answer,
+	// to win against the real host's reply.
+	FollowUps        int
+	FollowUpInterval time.Duration
+	// Claim, when set, is a range whose unused addresses are answered for. When an
+	// address is first asked for we probe it ourselves, and claim it if the probe goes
+	// unanswered for ClaimTimeout. The owner's replies to other hosts are unicast and
+	// not seen on a switched network, but its reply to our probe comes to us.
+	Claim        *net.IPNet
+	ClaimTimeout time.Duration
+	StopChan     chan struct{}
+}
+
+// ResponderStats counters of a responder
+type ResponderStats struct {
+	Requests uint64
+	Answered uint64
+}
+
+type asker struct {
+	mac net.HardwareAddr
+	ip  net.IP
+}
+
+type pendingReply struct {
+	due    time.Time
+	target net.IP
+	to     asker
+}
+
+// Responder answers ARP requests for a set of addresses as soon as they are seen.
+// Handle is fed the captured traffic and Due is polled for delayed replies. It is safe for concurrent use.
+type Responder struct {
+	cfg    ResponderConfig
+	answer map[string]bool
+
+	mu        sync.Mutex
+	stats     ResponderStats
+	claims    map[string]pendingReply
+	claimed   map[string]net.IP
+	inUse     map[string]bool
+	followUps []pendingReply
+}
+
+// NewResponder creates a responder
+func NewResponder(cfg ResponderConfig) *Responder {
+	if cfg.FollowUpInterval == 0 {
+		cfg.FollowUpInterval = 100 * time.Millisecond
+	}
+	if cfg.ClaimTimeout == 0 {
+		cfg.ClaimTimeout = time.Second
+	}
+	r := &Responder{
+		cfg:     cfg,
+		answer:  make(map[string]bool),
+		claims:  make(map[string]pendingReply),
+		claimed: make(map[string]net.IP),
+		inUse:   make(map[string]bool),
+	}
+	for _, ip := range cfg.IPs {
+		r.answer[ip.To4().String()] = true
+	}
+	return r
+}
+
+// Handle processes a captured packet and returns the replies to send right away
+func (r *Responder) Handle(packet gopacket.Packet) [][]byte {
+	a, ok := packet.Layer(layers.LayerTypeARP).(*layers.ARP)
+	if !ok || bytes.Equal(a.SourceHwAddress, r.cfg.OurMAC) {
+		return nil
+	}
+	now := packet.Metadata().Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	sender := net.IP(a.SourceProtAddress).To4()
+	target := net.IP(a.DstProtAddress).To4()
+	if sender == nil || target == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Someone else owns the sender address: never claim it
+	if !sender.IsUnspecified() && r.cfg.Claim != nil && r.cfg.Claim.Contains(sender) {
+		r.inUse[sender.String()] = true
+		delete(r.claims, sender.String())
+		delete(r.claimed, sender.String())
+	}
+
+	if a.Operation != layers.ARPRequest {
+		return nil
+	}
+	r.stats.Requests++
+
+	to := asker{mac: append(net.HardwareAddr(nil), a.SourceHwAddress...), ip: append(net.IP(nil), sender...)}
+	key := target.String()
+	if r.answer[key] || r.claimed[key] != nil {
+		return r.reply(now, target, to)
+	}
+
+	if r.cfg.Claim != nil && r.cfg.Claim.Contains(target) && !r.inUse[key] {
+		if _, waiting := r.claims[key]; !waiting {
+			// An RFC 5227 probe, from 0.0.0.0 so that nobody caches us for any address
+			probe, err := CraftARPRequest(r.cfg.OurMAC, net.IPv4zero, target)
+			if err != nil {
+				return nil
+			}
+			r.claims[key] = pendingReply{due: now.Add(r.cfg.ClaimTimeout), target: target, to: to}
+			return [][]byte{probe}
+		}
+	}
+	return nil
+}
+
+// Due returns the follow-ups, and the claim replies of the addresses whose probe went unanswered
+func (r *Responder) Due(now time.Time) [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out [][]byte
+	for key, p := range r.claims {
+		if now.Before(p.due) {
+			continue
+		}
+		delete(r.claims, key)
+		r.claimed[key] = p.target
+		out = append(out, r.reply(now, p.target, p.to)...)
+	}
+
+	rest := r.followUps[:0]
+	for _, p := range r.followUps {
+		if now.Before(p.due) {
+			rest = append(rest, p)
+			continue
+		}
+		if pkt, err := CraftARPReply(r.cfg.OurMAC, p.to.mac, p.target, p.to.ip); err == nil {
+			out = append(out, pkt)
+		}
+	}
+	r.followUps = rest
+	return out
+}
+
+// reply answers to with target at our MAC and schedules the follow-ups. The caller holds r.mu.
+func (r *Responder) reply(now time.Time, target net.IP, to asker) [][]byte {
+	pkt, err := CraftARPReply(r.cfg.OurMAC, to.mac, target, to.ip)
+	if err != nil {
+		return nil
+	}
+	r.stats.Answered++
+	for i := 1; i <= r.cfg.FollowUps; i++ {
+		r.followUps = append(r.followUps, pendingReply{due: now.Add(time.Duration(i) * r.cfg.FollowUpInterval), target: target, to: to})
+	}
+	return [][]byte{pkt}
+}
+
+// Stats returns a snapshot of the responder counters
+func (r *Responder) Stats() ResponderStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+// Claimed returns the addresses claimed in the Claim range, sorted
+func (r *Responder) Claimed() []net.IP {
+	r.mu.Lock()
+	out := make([]net.IP, 0, len(r.claimed))
+	for _, ip := range r.claimed {
+		out = append(out, ip)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool { return bytes.Compare(out[i], out[j]) < 0 })
+	return out
+}
+
+// StopChan returns the channel that stops the responder
+func (r *Responder) StopChan() chan struct{} {
+	return r.cfg.StopChan
+}
@@ -0,0 +1,126 @@
+package arp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func arpPacket(t *testing.T, data []byte, at time.Time) gopacket.Packet {
+	t.Helper()
+	pkt := gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)
+	pkt.Metadata().Timestamp = at
+	return pkt
+}
+
+func TestResponderAnswersConfiguredIPs(t *testing.T) {
+	ours, _ := net.ParseMAC("00:11:22:33:44:55")
+	asker, _ := net.ParseMAC("aa:aa:aa:aa:aa:01")
+	r := NewResponder(ResponderConfig{
+		OurMAC:           ours,
+		IPs:              []net.IP{net.ParseIP("10.0.0.1")},
+		FollowUps:        2,
+		FollowUpInterval: 100 * time.Millisecond,
+	})
+
+	now := time.Now()
+	req, _ := CraftARPRequest(asker, net.ParseIP("10.0.0.10"), net.ParseIP("10.0.0.1"))
+	replies := r.Handle(arpPacket(t, req, now))
+	if len(replies) != 1 {
+		t.Fatalf("Expected an immediate reply, got %d", len(replies))
+	}
+	eth, a := decodeARP(t, replies[0])
+	if eth.DstMAC.String() != asker.String() || a.Operation != layers.ARPReply ||
+		!net.IP(a.SourceProtAddress).Equal(net.ParseIP("10.0.0.1")) || net.HardwareAddr(a.SourceHwAddress).String() != ours.String() {
+		t.Errorf("Unexpected reply: %+v", a)
+	}
+
+	if due := r.Due(now.Add(50 * time.Millisecond)); len(due) != 0 {
+		t.Errorf("Follow-up sent early")
+	}
+	if due := r.Due(now.Add(250 * time.Millisecond)); len(due) != 2 {
+		t.Errorf("Expected 2 follow-ups, got %d", len(due))
+	}
+
+	other, _ := CraftARPRequest(asker, net.ParseIP("10.0.0.10"), net.ParseIP("10.0.0.2"))
+	if replies := r.Handle(arpPacket(t, other, now)); len(replies) != 0 {
+		t.Error("Answered for an address outside the set")
+	}
+	if s := r.Stats(); s.Requests != 2 || s.Answered != 1 {
+		t.Errorf("Unexpected stats: %+v", s)
+	}
+}
+
+func TestResponderClaimsUnusedAddresses(t *testing.T) {
+	ours, _ := net.ParseMAC("00:11:22:33:44:55")
+	asker, _ := net.ParseMAC("aa:aa:aa:aa:aa:01")
+	_, claim, _ := net.ParseCIDR("10.0.0.0/24")
+	r := NewResponder(ResponderConfig{OurMAC: ours, Claim: claim, ClaimTimeout: time.Second})
+
+	now := time.Now()
+	req, _ := CraftARPRequest(asker, net.ParseIP("10.0.0.10"), net.ParseIP("10.0.0.50"))
+	probes := r.Handle(arpPacket(t, req, now))
+	if len(probes) != 1 {
+		t.Fatalf("Expected a probe, got %d packets", len(probes))
+	}
+	if _, a := decodeARP(t, probes[0]); a.Operation != layers.ARPRequest || !net.IP(a.SourceProtAddress).Equal(net.IPv4zero) ||
+		!net.IP(a.DstProtAddress).Equal(net.ParseIP("10.0.0.50")) {
+		t.Errorf("Unexpected probe: %+v", a)
+	}
+	if due := r.Due(now.Add(500 * time.Millisecond)); len(due) != 0 {
+		t.Fatal("Claimed an address before the timeout")
+	}
+	// Asking again while the probe is out does not probe again
+	if again := r.Handle(arpPacket(t, req, now.Add(500*time.Millisecond))); len(again) != 0 {
+		t.Error("Probed twice")
+	}
+
+	due := r.Due(now.Add(1500 * time.Millisecond))
+	if len(due) != 1 {
+		t.Fatalf("Expected one claim reply, got %d", len(due))
+	}
+	if eth, a := decodeARP(t, due[0]); !net.IP(a.SourceProtAddress).Equal(net.ParseIP("10.0.0.50")) || eth.DstMAC.String() != asker.String() {
+		t.Errorf("Claimed the wrong address: %v to %v", net.IP(a.SourceProtAddress), eth.DstMAC)
+	}
+	if claimed := r.Claimed(); len(claimed) != 1 || claimed[0].String() != "10.0.0.50" {
+		t.Errorf("Claimed = %v", claimed)
+	}
+
+	// Once claimed, later requests are answered straight away
+	if replies := r.Handle(arpPacket(t, req, now.Add(2*time.Second))); len(replies) != 1 {
+		t.Error("Claimed address not answered immediately")
+	}
+}
+
+func TestResponderKeepsAddressesWhoseOwnerRepliesUnicast(t *testing.T) {
+	ours, _ := net.ParseMAC("00:11:22:33:44:55")
+	asker, _ := net.ParseMAC("aa:aa:aa:aa:aa:01")
+	owner, _ := net.ParseMAC("aa:aa:aa:aa:aa:02")
+	_, claim, _ := net.ParseCIDR("10.0.0.0/24")
+	r := NewResponder(ResponderConfig{OurMAC: ours, Claim: claim, ClaimTimeout: time.Second})
+
+	// The owner of 10.0.0.60 answers the asker unicast, which a switch never shows us
+	now := time.Now()
+	req, _ := CraftARPRequest(asker, net.ParseIP("10.0.0.10"), net.ParseIP("10.0.0.60"))
+	if probes := r.Handle(arpPacket(t, req, now)); len(probes) != 1 {
+		t.Fatalf("Expected a probe, got %d packets", len(probes))
+	}
+
+	// It answers our probe too, and that reply is addressed to us
+	reply, _ := CraftARPReply(owner, ours, net.ParseIP("10.0.0.60"), net.IPv4zero)
+	r.Handle(arpPacket(t, reply, now.Add(10*time.Millisecond)))
+
+	if due := r.Due(now.Add(1500 * time.Millisecond)); len(due) != 0 {
+		t.Error("Claimed an address whose owner answered the probe")
+	}
+	// The asker asking again after the timeout is not a reason to claim it either
+	if replies := r.Handle(arpPacket(t, req, now.Add(2*time.Second))); len(replies) != 0 {
+		t.Error("Probed or answered for an address that is in use")
+	}
+	if due := r.Due(now.Add(5 * time.Second)); len(due) != 0 || len(r.Claimed()) != 0 {
+		t.Error("Claimed an address that is in use")
+	}
+}
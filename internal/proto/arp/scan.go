@@ -0,0 +1,163 @@
+package arp
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gnpaone/l2star/internal/utils"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// ScanHost is a host that answered the scan
+type ScanHost struct {
+	IP        net.IP           `json:"ip"`
+	MAC       net.HardwareAddr `json:"-"`
+	Vendor    string           `json:"vendor"`
+	FirstSeen time.Time        `json:"first_seen"`
+	LastSeen  time.Time        `json:"last_seen"`
+	// Latency is the time from our request to the first reply, zero if the host replied unasked.
+	Latency time.Duration `json:"-"`
+}
+
+// MarshalJSON writes the MAC as text and the latency in milliseconds
+func (h ScanHost) MarshalJSON() ([]byte, error) {
+	type plain ScanHost
+	return json.Marshal(struct {
+		plain
+		MAC       string  `json:"mac"`
+		LatencyMS float64 `json:"latency_ms"`
+	}{plain(h), h.MAC.String(), float64(h.Latency) / float64(time.Millisecond)})
+}
+
+// Scanner sweeps a list of addresses with ARP requests and records who answers.
+// Next sends the requests; Update must be fed the captured traffic. It is safe for concurrent use.
+type Scanner struct {
+	ourMAC  net.HardwareAddr
+	ourIP   net.IP
+	targets []net.IP
+
+	mu     sync.Mutex
+	next   int
+	sentAt map[string]time.Time
+	hosts  map[string]*ScanHost
+}
+
+// NewScanner creates a scanner for targets. ourIP may be nil to send ARP probes.
+func NewScanner(ourMAC net.HardwareAddr, ourIP net.IP, targets []net.IP) *Scanner {
+	if ourIP == nil {
+		ourIP = net.IPv4zero
+	}
+	return &Scanner{
+		ourMAC:  ourMAC,
+		ourIP:   ourIP,
+		targets: targets,
+		sentAt:  make(map[string]time.Time),
+		hosts:   make(map[string]*ScanHost),
+	}
+}
+
+// Next returns the request for the next address, or nil once the sweep is done.
+// It can be used as a core.PacketGenerator.
+func (s *Scanner) Next() ([]byte, error) {
+	s.mu.Lock()
+	if s.next >= len(s.targets) {
+		s.mu.Unlock()
+		return nil, nil
+	}
+	ip := s.targets[s.next]
+	s.next++
+	s.sentAt[ip.String()] = time.Now()
+	s.mu.Unlock()
+
+	return CraftARPRequest(s.ourMAC, s.ourIP, ip)
+}
+
+// Progress returns how many requests were sent out of the total
+func (s *Scanner) Progress() (sent, total int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.next, len(s.targets)
+}
+
+// Update records the sender of an ARP reply. It reports whether the packet was ARP.
+func (s *Scanner) Update(packet gopacket.Packet) bool {
+	a, ok := packet.Layer(layers.LayerTypeARP).(*layers.ARP)
+	if !ok {
+		return false
+	}
+	mac := net.HardwareAddr(a.SourceHwAddress)
+	ip := net.IP(a.SourceProtAddress).To4()
+	if a.Operation != layers.ARPReply || ip == nil || bytes.Equal(mac, s.ourMAC) {
+		return true
+	}
+
+	now := packet.Metadata().Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.hosts[ip.String()]
+	if !ok {
+		h = &ScanHost{IP: append(net.IP(nil), ip...), FirstSeen: now}
+		if sent, asked := s.sentAt[ip.String()]; asked && now.After(sent) {
+			h.Latency = now.Sub(sent)
+		}
+		s.hosts[ip.String()] = h
+	}
+	h.MAC = append(net.HardwareAddr(nil), mac...)
+	h.Vendor = utils.Vendor(mac)
+	h.LastSeen = now
+	return true
+}
+
+// Hosts returns the hosts that answered, sorted by IP
+func (s *Scanner) Hosts() []ScanHost {
+	s.mu.Lock()
+	out := make([]ScanHost, 0, len(s.hosts))
+	for _, h := range s.hosts {
+		out = append(out, *h)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool { return bytes.Compare(out[i].IP, out[j].IP) < 0 })
+	return out
+}
+
+// WriteCSV writes the host table as CSV with a header row
+func WriteCSV(w io.Writer, hosts []ScanHost) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"ip", "mac", "vendor", "first_seen", "last_seen", "latency_ms"})
+	for _, h := range hosts {
+		cw.Write([]string{
+			h.IP.String(),
+			h.MAC.String(),
+			h.Vendor,
+			h.FirstSeen.Format(time.RFC3339),
+			h.LastSeen.Format(time.RFC3339),
+			fmt.Sprintf("%.3f", float64(h.Latency)/float64(time.Millisecond)),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the host table as an indented JSON array
+func WriteJSON(w io.Writer, hosts []ScanHost) error {
+	if hosts == nil {
+		hosts = []ScanHost{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(hosts)
+}
@@ -0,0 +1,75 @@
+package arp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func TestScanner(t *testing.T) {
+	ours, _ := net.ParseMAC("00:11:22:33:44:55")
+	_, subnet, _ := net.ParseCIDR("10.0.0.0/29")
+	targets := Hosts(subnet)
+	if len(targets) != 6 || targets[0].String() != "10.0.0.1" || targets[5].String() != "10.0.0.6" {
+		t.Fatalf("Hosts(/29) = %v", targets)
+	}
+
+	s := NewScanner(ours, net.ParseIP("10.0.0.99"), targets)
+	for i := 0; i < len(targets); i++ {
+		pkt, err := s.Next()
+		if err != nil || pkt == nil {
+			t.Fatalf("Next %d: %v", i, err)
+		}
+		if _, a := decodeARP(t, pkt); !net.IP(a.DstProtAddress).Equal(targets[i]) {
+			t.Errorf("Request %d asks for %v", i, net.IP(a.DstProtAddress))
+		}
+	}
+	if pkt, _ := s.Next(); pkt != nil {
+		t.Error("Scanner kept sending after the sweep")
+	}
+	if sent, total := s.Progress(); sent != 6 || total != 6 {
+		t.Errorf("Progress = %d/%d", sent, total)
+	}
+
+	vmware, _ := net.ParseMAC("00:50:56:aa:bb:cc")
+	reply, _ := CraftARPReply(vmware, ours, net.ParseIP("10.0.0.3"), net.ParseIP("10.0.0.99"))
+	s.Update(gopacket.NewPacket(reply, layers.LayerTypeEthernet, gopacket.Default))
+	// Requests, including ours, are not answers
+	req, _ := CraftARPRequest(ours, net.ParseIP("10.0.0.99"), net.ParseIP("10.0.0.4"))
+	s.Update(gopacket.NewPacket(req, layers.LayerTypeEthernet, gopacket.Default))
+
+	hosts := s.Hosts()
+	if len(hosts) != 1 {
+		t.Fatalf("Expected one host, got %d", len(hosts))
+	}
+	h := hosts[0]
+	if h.IP.String() != "10.0.0.3" || h.Vendor != "VMware" || h.Latency <= 0 {
+		t.Errorf("Unexpected host: %+v", h)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, hosts); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], "10.0.0.3,00:50:56:aa:bb:cc,VMware,") {
+		t.Errorf("Unexpected CSV:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteJSON(&buf, hosts); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if decoded[0]["mac"] != "00:50:56:aa:bb:cc" || decoded[0]["ip"] != "10.0.0.3" || decoded[0]["latency_ms"] == nil {
+		t.Errorf("Unexpected JSON: %s", buf.String())
+	}
+}
@@ -0,0 +1,34 @@
+package arp
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+// MaxSubnetHosts caps the addresses Hosts expands a subnet to (a /20)
+const MaxSubnetHosts = 4096
+
+// Hosts returns the host addresses of an IPv4 subnet, without the network and
+// broadcast addresses when it is larger than a /31. Large subnets are cut at MaxSubnetHosts.
+func Hosts(subnet *net.IPNet) []net.IP {
+	base := subnet.IP.To4()
+	if base == nil {
+		return nil
+	}
+	ones, bits := subnet.Mask.Size()
+	size := uint64(1) << uint(bits-ones)
+
+	first, last := uint64(0), size-1
+	if size > 2 {
+		first, last = 1, size-2
+	}
+
+	start := uint64(binary.BigEndian.Uint32(base.Mask(subnet.Mask)))
+	var out []net.IP
+	for host := first; host <= last && len(out) < MaxSubnetHosts; host++ {
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, uint32(start+host))
+		out = append(out, ip)
+	}
+	return out
+}
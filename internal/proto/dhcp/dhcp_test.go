@@ -1,8 +1,10 @@
 package dhcp
 
 import (
+	"bytes"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
@@ -51,3 +53,59 @@ func TestCraftDHCPOffer(t *testing.T) {
 		t.Errorf("Expected offeredIP %v, got %v", offeredIP, dhcp.YourClientIP)
 	}
 }
+
+func TestCraftDHCPOfferWithOptions(t *testing.T) {
+	srcMAC := net.HardwareAddr{0x00, 0x11, 0x00, 0x22, 0x33, 0x44}
+	dstMAC := net.HardwareAddr{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}
+	serverIP := net.IPv4(192, 168, 1, 1)
+
+	routes, err := ParseRoutes("10.0.0.0/8 via 192.168.1.1, 172.16.1.0/24 via 192.168.1.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := ParseRawOptions("15:override.lab, 150:0xc0a80101")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{
+		Domain:    "corp.lab",
+		Search:    []string{"corp.lab", "lab"},
+		LeaseTime: time.Hour,
+		RenewTime: 30 * time.Minute,
+		Routes:    routes,
+		Raw:       raw,
+	}
+	Presets["pxe"](&opts, serverIP)
+
+	packet, err := CraftDHCPOfferWithOptions(srcMAC, dstMAC, serverIP, net.IPv4(192, 168, 1, 50), 7, opts)
+	if err != nil {
+		t.Fatalf("Failed to craft DHCP Offer: %v", err)
+	}
+	pkt := gopacket.NewPacket(packet, layers.LayerTypeEthernet, gopacket.Default)
+	dhcp, ok := pkt.Layer(layers.LayerTypeDHCPv4).(*layers.DHCPv4)
+	if !ok {
+		t.Fatal("No DHCP layer")
+	}
+
+	expected := map[layers.DHCPOpt][]byte{
+		layers.DHCPOptDomainName:           []byte("override.lab"),
+		layers.DHCPOptDomainSearch:         {4, 'c', 'o', 'r', 'p', 3, 'l', 'a', 'b', 0, 3, 'l', 'a', 'b', 0},
+		layers.DHCPOptT1:                   {0, 0, 0x07, 0x08},
+		layers.DHCPOptClasslessStaticRoute: {8, 10, 192, 168, 1, 1, 24, 172, 16, 1, 192, 168, 1, 2},
+		OptTFTPServer:                      []byte("192.168.1.1"),
+		OptBootfile:                        []byte("pxelinux.0"),
+		layers.DHCPOpt(150):                {192, 168, 1, 1},
+	}
+	for code, data := range expected {
+		if got := option(dhcp, code); !bytes.Equal(got, data) {
+			t.Errorf("Option %d: expected %v, got %v", code, data, got)
+		}
+	}
+	if !bytes.HasPrefix(dhcp.File, []byte("pxelinux.0")) || !dhcp.NextServerIP.Equal(serverIP) {
+		t.Errorf("Expected boot file and next server in the header, got %q %v", dhcp.File, dhcp.NextServerIP)
+	}
+
+	if _, err := ParseRawOptions("255:x"); err == nil {
+		t.Error("Accepted the End option as a raw option")
+	}
+}
@@ -0,0 +1,347 @@
+package dhcp
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// DefaultFingerprints is the built-in fingerprint database, in the format read by LoadFingerprints
+const DefaultFingerprints = `# DHCP fingerprint database: "<option 55 list> | <OS>" or "vendor:<option 60 prefix> | <OS>"
+1,3,6,15,31,33,43,44,46,47,119,121,249,252 | Windows 8/10/11
+1,15,3,6,44,46,47,31,33,121,249,43,252 | Windows 7
+1,15,3,6,44,46,47,31,33,121,249,43 | Windows Vista/7
+1,121,3,6,15,119,252,95,44,46 | macOS
+1,121,3,6,15,108,114,119,252,95,44,46 | macOS 12+
+1,121,3,6,15,119,252 | iOS
+1,3,6,15,26,28,51,58,59,43 | Android 8+
+1,3,6,15,26,28,51,58,59,43,114,108 | Android 11+
+1,33,3,6,15,28,51,58,59 | Android (legacy)
+1,28,2,3,15,6,119,12,44,47,26,121,42 | Linux (dhclient)
+1,3,6,12,15,28,42,51,54,58,59,119,121 | Linux (systemd-networkd)
+1,3,6,12,15,28,42 | Linux (NetworkManager internal)
+vendor:MSFT | Windows
+vendor:android-dhcp | Android
+vendor:dhcpcd | Linux/BSD (dhcpcd)
+vendor:udhcp | Embedded Linux (BusyBox udhcpc)
+vendor:Cisco | Cisco device
+vendor:PXEClient | PXE boot ROM
+`
+
+// FingerprintDB matches DHCP client fingerprints to operating systems. It is safe for concurrent use.
+type FingerprintDB struct {
+	mu      sync.RWMutex
+	exact   map[string]string
+	vendors []vendorRule
+}
+
+type vendorRule struct {
+	prefix string
+	os     string
+}
+
+// NewFingerprintDB creates a database holding DefaultFingerprints
+func NewFingerprintDB() *FingerprintDB {
+	db := &FingerprintDB{exact: make(map[string]string)}
+	db.Load(strings.NewReader(DefaultFingerprints))
+	return db
+}
+
+// Load adds the entries read from r, one "<option 55 list> | <OS>" or
+// "vendor:<prefix> | <OS>" per line. Blank lines and # comments are skipped.
+// It returns the number of entries added.
+func (db *FingerprintDB) Load(r io.Reader) (int, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	n := 0
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, name, ok := strings.Cut(text, "|")
+		key, name = strings.TrimSpace(key), strings.TrimSpace(name)
+		if !ok || key == "" || name == "" {
+			return n, fmt.Errorf("line %d: expected \"<fingerprint> | <OS>\"", line)
+		}
+		if prefix, isVendor := strings.CutPrefix(key, "vendor:"); isVendor {
+			db.vendors = append(db.vendors, vendorRule{prefix: strings.TrimSpace(prefix), os: name})
+		} else {
+			db.exact[strings.ReplaceAll(key, " ", "")] = name
+		}
+		n++
+	}
+	return n, scanner.Err()
+}
+
+// LoadFile adds the entries of the database file at path, see Load
+func (db *FingerprintDB) LoadFile(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return db.Load(f)
+}
+
+// Match returns the OS of a fingerprint, trying the exact option 55 list before the
+// vendor class prefixes, with the latest loaded entries first. It returns "" when unknown.
+func (db *FingerprintDB) Match(fingerprint, vendor string) string {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if name, ok := db.exact[fingerprint]; ok {
+		return name
+	}
+	for i := len(db.vendors) - 1; i >= 0; i-- {
+		if vendor != "" && strings.HasPrefix(vendor, db.vendors[i].prefix) {
+			return db.vendors[i].os
+		}
+	}
+	return ""
+}
+
+// Fingerprint returns the parameter request list (option 55) of d as a comma separated list
+func Fingerprint(d *layers.DHCPv4) string {
+	params := option(d, layers.DHCPOptParamsRequest)
+	codes := make([]string, len(params))
+	for i, p := range params {
+		codes[i] = strconv.Itoa(int(p))
+	}
+	return strings.Join(codes, ",")
+}
+
+// ClientInfo is what a DHCP client revealed about itself
+type ClientInfo struct {
+	MAC         net.HardwareAddr
+	IP          net.IP
+	Hostname    string
+	VendorClass string
+	Fingerprint string
+	OS          string
+	Messages    int
+	FirstSeen   time.Time
+	LastSeen    time.Time
+}
+
+// ServerInfo is a DHCP server seen answering
+type ServerInfo struct {
+	IP        net.IP
+	MAC       net.HardwareAddr
+	Offers    int
+	Acks      int
+	Naks      int
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// Ours is set for replies sent from our own MAC
+	Ours bool
+	// Rogue is set for servers not expected to answer, see Servers
+	Rogue bool
+}
+
+// Inventory passively records the DHCP clients and servers seen on the wire. It is safe for concurrent use.
+type Inventory struct {
+	db     *FingerprintDB
+	ourMAC net.HardwareAddr
+
+	mu      sync.Mutex
+	clients map[string]*ClientInfo
+	servers map[string]*ServerInfo
+	// bindings are the leases seen ACKed, by client MAC
+	bindings map[string]binding
+}
+
+// binding is a lease a server ACKed
+type binding struct {
+	Client
+	server net.IP
+}
+
+// NewInventory creates an inventory matching fingerprints against db. Replies sent by ourMAC are marked as ours.
+func NewInventory(db *FingerprintDB, ourMAC net.HardwareAddr) *Inventory {
+	return &Inventory{
+		db:       db,
+		ourMAC:   ourMAC,
+		clients:  make(map[string]*ClientInfo),
+		servers:  make(map[string]*ServerInfo),
+		bindings: make(map[string]binding),
+	}
+}
+
+// DB returns the fingerprint database of the inventory
+func (inv *Inventory) DB() *FingerprintDB {
+	return inv.db
+}
+
+// Update records a DHCP packet and reports whether it was one
+func (inv *Inventory) Update(packet gopacket.Packet) bool {
+	d, ok := packet.Layer(layers.LayerTypeDHCPv4).(*layers.DHCPv4)
+	if !ok {
+		return false
+	}
+	now := packet.Metadata().Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	if d.Operation == layers.DHCPOpReply {
+		inv.updateServer(packet, d, now)
+		return true
+	}
+
+	if messageType(d) == layers.DHCPMsgTypeRelease {
+		delete(inv.bindings, d.ClientHWAddr.String())
+	}
+	c := inv.client(d.ClientHWAddr, now)
+	c.Messages++
+	if name := option(d, layers.DHCPOptHostname); len(name) > 0 {
+		c.Hostname = string(name)
+	}
+	if vendor := option(d, layers.DHCPOptClassID); len(vendor) > 0 {
+		c.VendorClass = string(vendor)
+	}
+	if fp := Fingerprint(d); fp != "" {
+		c.Fingerprint = fp
+	}
+	if requested := option(d, layers.DHCPOptRequestIP); len(requested) == 4 {
+		c.IP = net.IP(append([]byte(nil), requested...))
+	} else if !d.ClientIP.IsUnspecified() {
+		c.IP = append(net.IP(nil), d.ClientIP.To4()...)
+	}
+	c.OS = inv.db.Match(c.Fingerprint, c.VendorClass)
+	return true
+}
+
+// updateServer records a server reply. The caller holds inv.mu.
+func (inv *Inventory) updateServer(packet gopacket.Packet, d *layers.DHCPv4, now time.Time) {
+	id := net.IP(option(d, layers.DHCPOptServerID))
+	if len(id) != 4 {
+		ip, ok := packet.Layer(layers.LayerTypeIPv4).(*layers.IPv4)
+		if !ok {
+			return
+		}
+		id = ip.SrcIP
+	}
+
+	s := inv.servers[id.String()]
+	if s == nil {
+		s = &ServerInfo{IP: append(net.IP(nil), id.To4()...), FirstSeen: now}
+		inv.servers[id.String()] = s
+	}
+	s.LastSeen = now
+	if eth, ok := packet.Layer(layers.LayerTypeEthernet).(*layers.Ethernet); ok {
+		s.MAC = append(net.HardwareAddr(nil), eth.SrcMAC...)
+		s.Ours = bytes.Equal(eth.SrcMAC, inv.ourMAC)
+	}
+
+	switch messageType(d) {
+	case layers.DHCPMsgTypeOffer:
+		s.Offers++
+	case layers.DHCPMsgTypeAck:
+		s.Acks++
+		if !d.YourClientIP.IsUnspecified() {
+			ip := append(net.IP(nil), d.YourClientIP.To4()...)
+			c := inv.client(d.ClientHWAddr, now)
+			c.IP = ip
+			inv.bindings[c.MAC.String()] = binding{Client: Client{MAC: c.MAC, IP: ip}, server: s.IP}
+		}
+	case layers.DHCPMsgTypeNak:
+		s.Naks++
+	}
+}
+
+// client returns the entry of mac, creating it. The caller holds inv.mu.
+func (inv *Inventory) client(mac net.HardwareAddr, now time.Time) *ClientInfo {
+	c := inv.clients[mac.String()]
+	if c == nil {
+		c = &ClientInfo{MAC: append(net.HardwareAddr(nil), mac...), FirstSeen: now}
+		inv.clients[mac.String()] = c
+	}
+	c.LastSeen = now
+	return c
+}
+
+// Rematch matches every client again, after the database changed
+func (inv *Inventory) Rematch() {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	for _, c := range inv.clients {
+		c.OS = inv.db.Match(c.Fingerprint, c.VendorClass)
+	}
+}
+
+// Clients returns a copy of the client inventory, sorted by MAC
+func (inv *Inventory) Clients() []ClientInfo {
+	inv.mu.Lock()
+	out := make([]ClientInfo, 0, len(inv.clients))
+	for _, c := range inv.clients {
+		out = append(out, *c)
+	}
+	inv.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool { return bytes.Compare(out[i].MAC, out[j].MAC) < 0 })
+	return out
+}
+
+// Bindings returns the leases the server at serverIP was seen ACKing and that were not
+// released since, sorted by IP
+func (inv *Inventory) Bindings(serverIP net.IP) []Client {
+	inv.mu.Lock()
+	var out []Client
+	for _, b := range inv.bindings {
+		if b.server.Equal(serverIP) {
+			out = append(out, b.Client)
+		}
+	}
+	inv.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool { return ipToUint(out[i].IP) < ipToUint(out[j].IP) })
+	return out
+}
+
+// Servers returns the servers seen answering, oldest first. A server is flagged rogue when
+// it is not in trusted, or, without a trusted list, when it is not the first server seen.
+// Our own server is never flagged.
+func (inv *Inventory) Servers(trusted []net.IP) []ServerInfo {
+	inv.mu.Lock()
+	out := make([]ServerInfo, 0, len(inv.servers))
+	for _, s := range inv.servers {
+		out = append(out, *s)
+	}
+	inv.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool { return out[i].FirstSeen.Before(out[j].FirstSeen) })
+	first := true
+	for i := range out {
+		if out[i].Ours {
+			continue
+		}
+		if len(trusted) > 0 {
+			out[i].Rogue = true
+			for _, ip := range trusted {
+				if ip.Equal(out[i].IP) {
+					out[i].Rogue = false
+				}
+			}
+		} else {
+			out[i].Rogue = !first
+		}
+		first = false
+	}
+	return out
+}
@@ -0,0 +1,103 @@
+package dhcp
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket/layers"
+)
+
+func TestInventoryFingerprintsClients(t *testing.T) {
+	db := NewFingerprintDB()
+	inv := NewInventory(db, serverMAC)
+	client := net.HardwareAddr{0xaa, 0, 0, 0, 0, 1}
+	now := time.Now()
+
+	inv.Update(clientPacket(t, layers.DHCPMsgTypeDiscover, client, 1, now,
+		layers.NewDHCPOption(layers.DHCPOptHostname, []byte("DESKTOP-1")),
+		layers.NewDHCPOption(layers.DHCPOptClassID, []byte("MSFT 5.0")),
+		layers.NewDHCPOption(layers.DHCPOptParamsRequest, []byte{1, 3, 6, 15, 31, 33, 43, 44, 46, 47, 119, 121, 249, 252})))
+
+	clients := inv.Clients()
+	if len(clients) != 1 {
+		t.Fatalf("Expected one client, got %d", len(clients))
+	}
+	c := clients[0]
+	if c.Hostname != "DESKTOP-1" || c.VendorClass != "MSFT 5.0" || c.Fingerprint != "1,3,6,15,31,33,43,44,46,47,119,121,249,252" {
+		t.Errorf("Unexpected client: %+v", c)
+	}
+	if c.OS != "Windows 8/10/11" {
+		t.Errorf("Expected an exact fingerprint match, got %q", c.OS)
+	}
+
+	// Entries loaded later take precedence for vendor classes
+	if _, err := db.Load(strings.NewReader("vendor:MSFT 5.0 | Windows lab image\n9,9,9 | Lab printer\n")); err != nil {
+		t.Fatal(err)
+	}
+	if name := db.Match("1,3", "MSFT 5.0"); name != "Windows lab image" {
+		t.Errorf("Expected the loaded vendor rule, got %q", name)
+	}
+	if _, err := db.Load(strings.NewReader("no separator")); err == nil {
+		t.Error("Accepted a line without an OS")
+	}
+}
+
+func TestInventoryFlagsRogueServers(t *testing.T) {
+	inv := NewInventory(NewFingerprintDB(), net.HardwareAddr{0x02, 0, 0, 0, 0, 0x99})
+	req := &layers.DHCPv4{Xid: 1, ClientHWAddr: net.HardwareAddr{0xaa, 0, 0, 0, 0, 1}}
+	legit := net.IPv4(10, 0, 0, 1).To4()
+	rogue := net.IPv4(10, 0, 0, 66).To4()
+
+	for i, ip := range []net.IP{legit, rogue} {
+		offer, err := CraftReply(net.HardwareAddr{0x02, 0, 0, 0, 0, byte(i)}, ip, req, layers.DHCPMsgTypeOffer, net.IPv4(10, 0, 0, 100), Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkt := decode(offer)
+		pkt.Metadata().Timestamp = time.Now().Add(time.Duration(i) * time.Second)
+		inv.Update(pkt)
+	}
+
+	servers := inv.Servers(nil)
+	if len(servers) != 2 || servers[0].Rogue || !servers[1].Rogue || !servers[1].IP.Equal(rogue) {
+		t.Errorf("Expected the second server flagged, got %+v", servers)
+	}
+	servers = inv.Servers([]net.IP{rogue})
+	if !servers[0].Rogue || servers[1].Rogue {
+		t.Errorf("Expected servers outside the trusted list flagged, got %+v", servers)
+	}
+}
+
+func TestInventoryBindings(t *testing.T) {
+	inv := NewInventory(NewFingerprintDB(), net.HardwareAddr{0x02, 0, 0, 0, 0, 0x99})
+	other := net.IPv4(10, 0, 0, 66).To4()
+	now := time.Now()
+
+	for i, server := range []net.IP{serverIP, serverIP, other} {
+		client := net.HardwareAddr{0xaa, 0, 0, 0, 0, byte(i + 1)}
+		// Requests name an address, only the ACK binds it
+		inv.Update(clientPacket(t, layers.DHCPMsgTypeRequest, client, uint32(i), now,
+			layers.NewDHCPOption(layers.DHCPOptRequestIP, []byte{10, 0, 0, byte(150 + i)})))
+		if i == 1 {
+			continue
+		}
+		req := &layers.DHCPv4{Xid: uint32(i), ClientHWAddr: client}
+		ack, err := CraftReply(serverMAC, server, req, layers.DHCPMsgTypeAck, net.IPv4(10, 0, 0, byte(100+i)), Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		inv.Update(decode(ack))
+	}
+
+	bindings := inv.Bindings(serverIP)
+	if len(bindings) != 1 || !bindings[0].IP.Equal(net.IPv4(10, 0, 0, 100)) || bindings[0].MAC[5] != 1 {
+		t.Fatalf("Expected the one lease ACKed by the server, got %+v", bindings)
+	}
+
+	inv.Update(clientPacket(t, layers.DHCPMsgTypeRelease, bindings[0].MAC, 9, now))
+	if bindings := inv.Bindings(serverIP); len(bindings) != 0 {
+		t.Errorf("Released lease still bound: %+v", bindings)
+	}
+}
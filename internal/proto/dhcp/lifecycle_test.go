@@ -0,0 +1,97 @@
+package dhcp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket/layers"
+)
+
+func TestReleasesFreeServerLeases(t *testing.T) {
+	server := newTestServer(RespondAlways)
+	s := NewStarver(StarveConfig{})
+	discover, _ := s.Next()
+	offer := server.Handle(decode(discover))
+	request := s.Handle(decode(offer[0]))
+	s.Handle(decode(server.Handle(decode(request[0]))[0]))
+
+	leases := s.Leases()
+	if len(leases) != 1 {
+		t.Fatalf("Expected one lease, got %d", len(leases))
+	}
+	clients, err := ParseClients(leases[0].MAC.String() + "@" + leases[0].IP.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	releases, err := CraftReleases(clients, serverIP, nil)
+	if err != nil || len(releases) != 1 {
+		t.Fatalf("Expected one release, got %d (%v)", len(releases), err)
+	}
+	pkt := decode(releases[0])
+	if eth := pkt.Layer(layers.LayerTypeEthernet).(*layers.Ethernet); eth.DstMAC.String() != layers.EthernetBroadcast.String() {
+		t.Errorf("Expected a broadcast frame for an unknown server MAC, got %v", eth.DstMAC)
+	}
+	server.Handle(pkt)
+	if n := len(server.Leases()); n != 0 {
+		t.Errorf("Expected the lease to be released, %d left", n)
+	}
+
+	if _, err := ParseClients("10.0.0.1"); err == nil {
+		t.Error("Accepted a client without a MAC")
+	}
+}
+
+func TestDeclinerQuarantinesOffers(t *testing.T) {
+	server := newTestServer(RespondAlways)
+	d := NewDecliner(nil)
+	client := net.HardwareAddr{0xaa, 0, 0, 0, 0, 1}
+
+	offer := server.Handle(clientPacket(t, layers.DHCPMsgTypeDiscover, client, 1, time.Now()))
+	declines := d.Handle(decode(offer[0]))
+	if len(declines) != 1 {
+		t.Fatalf("Expected a DECLINE, got %d", len(declines))
+	}
+	server.Handle(decode(declines[0]))
+
+	leases := server.Leases()
+	if len(leases) != 1 || leases[0].State != LeaseDeclined || !leases[0].IP.Equal(net.IPv4(10, 0, 0, 100)) {
+		t.Errorf("Expected the offered address quarantined, got %+v", leases)
+	}
+	if declined := d.Declined(); len(declined) != 1 || declined[0].Client.String() != client.String() {
+		t.Errorf("Unexpected declined list: %+v", declined)
+	}
+
+	// The next client gets the other address, and then the pool is empty
+	offer = server.Handle(clientPacket(t, layers.DHCPMsgTypeDiscover, net.HardwareAddr{0xaa, 0, 0, 0, 0, 2}, 2, time.Now()))
+	if len(offer) != 1 {
+		t.Fatal("Expected a second offer")
+	}
+	d.Handle(decode(offer[0]))
+	if d.Offers() != 2 || len(d.Declined()) != 2 {
+		t.Errorf("Expected both offers declined, got %d of %d", len(d.Declined()), d.Offers())
+	}
+}
+
+func TestDeclinerDeclinesRepeatedOffers(t *testing.T) {
+	d := NewDecliner(nil)
+	client := net.HardwareAddr{0xaa, 0, 0, 0, 0, 1}
+
+	offer := newTestServer(RespondAlways).Handle(clientPacket(t, layers.DHCPMsgTypeDiscover, client, 1, time.Now()))
+	if len(d.Handle(decode(offer[0]))) != 1 {
+		t.Fatal("Expected the first OFFER declined")
+	}
+	if n := len(d.Handle(decode(offer[0]))); n != 0 {
+		t.Errorf("A copy of the same OFFER was declined again (%d)", n)
+	}
+
+	// The server forgot the quarantine and offers the address again in a new transaction
+	offer = newTestServer(RespondAlways).Handle(clientPacket(t, layers.DHCPMsgTypeDiscover, client, 2, time.Now()))
+	if n := len(d.Handle(decode(offer[0]))); n != 1 {
+		t.Fatalf("Expected the re-offered address declined again, got %d", n)
+	}
+	if declined := d.Declined(); len(declined) != 1 || declined[0].Xid != 2 {
+		t.Errorf("Unexpected declined list: %+v", declined)
+	}
+}
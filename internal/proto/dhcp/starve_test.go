@@ -0,0 +1,131 @@
+package dhcp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func decode(data []byte) gopacket.Packet {
+	return gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)
+}
+
+func TestStarverDrainsPool(t *testing.T) {
+	server := newTestServer(RespondAlways)
+	s := NewStarver(StarveConfig{StallAfter: time.Second})
+
+	// relay hands every packet of one side to the other until nothing is left to send
+	relay := func(packets [][]byte, toServer bool) {
+		for len(packets) > 0 {
+			var next [][]byte
+			for _, p := range packets {
+				if toServer {
+					next = append(next, server.Handle(decode(p))...)
+				} else {
+					next = append(next, s.Handle(decode(p))...)
+				}
+			}
+			packets = next
+			toServer = !toServer
+		}
+	}
+
+	for i := 0; i < stallDiscovers+2; i++ {
+		discover, err := s.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		relay([][]byte{discover}, true)
+	}
+
+	st := s.Stats()
+	if st.Offers != 2 || st.Requests != 2 || st.Acks != 2 || st.Leases != 2 {
+		t.Fatalf("Expected both pool addresses leased, got %+v", st)
+	}
+	if st.SubnetSize != 254 {
+		t.Errorf("Expected a /24 subnet size of 254, got %d", st.SubnetSize)
+	}
+	leases := s.Leases()
+	if !leases[0].IP.Equal(net.IPv4(10, 0, 0, 100)) || !leases[0].Server.Equal(serverIP) {
+		t.Errorf("Unexpected lease: %+v", leases[0])
+	}
+	if leases[0].LeaseTime != time.Hour || leases[0].ServerMAC.String() != serverMAC.String() {
+		t.Errorf("Unexpected lease details: %+v", leases[0])
+	}
+	if server.Stats().Exhausted == 0 {
+		t.Error("Server pool should be exhausted")
+	}
+
+	if s.Stalled(st.LastOffer) {
+		t.Error("Stalled right after an offer")
+	}
+	if !s.Stalled(st.LastOffer.Add(2 * time.Second)) {
+		t.Error("Expected the starvation to detect the server stopped offering")
+	}
+
+	releases, err := s.ReleasePackets()
+	if err != nil || len(releases) != 2 {
+		t.Fatalf("Expected 2 releases, got %d (%v)", len(releases), err)
+	}
+	relay(releases, true)
+	if n := len(server.Leases()); n != 0 {
+		t.Errorf("Expected the server to free the released leases, %d left", n)
+	}
+}
+
+func TestStarverThroughRelay(t *testing.T) {
+	agentMAC := net.HardwareAddr{0x02, 0, 0, 0, 0, 0x10}
+	router := net.HardwareAddr{0x02, 0, 0, 0, 0, 0xfe}
+	circuit, _ := ParseAgentID("0x000403e80001")
+	relay := &Relay{
+		AgentIP:   net.IPv4(10, 9, 0, 2).To4(),
+		AgentMAC:  agentMAC,
+		ServerIP:  net.IPv4(10, 1, 1, 1).To4(),
+		NextHop:   router,
+		CircuitID: circuit,
+		RemoteID:  []byte("sw1"),
+	}
+	s := NewStarver(StarveConfig{Relay: relay})
+
+	check := func(data []byte, msgType layers.DHCPMsgType) *layers.DHCPv4 {
+		t.Helper()
+		pkt := decode(data)
+		eth := pkt.Layer(layers.LayerTypeEthernet).(*layers.Ethernet)
+		ip := pkt.Layer(layers.LayerTypeIPv4).(*layers.IPv4)
+		udp := pkt.Layer(layers.LayerTypeUDP).(*layers.UDP)
+		d := pkt.Layer(layers.LayerTypeDHCPv4).(*layers.DHCPv4)
+		if eth.SrcMAC.String() != agentMAC.String() || eth.DstMAC.String() != router.String() {
+			t.Errorf("Expected frame from agent to next hop, got %v -> %v", eth.SrcMAC, eth.DstMAC)
+		}
+		if !ip.SrcIP.Equal(relay.AgentIP) || !ip.DstIP.Equal(relay.ServerIP) || udp.SrcPort != 67 || udp.DstPort != 67 {
+			t.Errorf("Expected unicast from agent to server port 67, got %v:%d -> %v:%d", ip.SrcIP, udp.SrcPort, ip.DstIP, udp.DstPort)
+		}
+		if messageType(d) != msgType || !d.RelayAgentIP.Equal(relay.AgentIP) || d.HardwareOpts != 1 {
+			t.Errorf("Unexpected %v: giaddr %v hops %d", messageType(d), d.RelayAgentIP, d.HardwareOpts)
+		}
+		want := append(append([]byte{1, 6}, circuit...), 2, 3, 's', 'w', '1')
+		if got := option(d, OptRelayAgentInfo); string(got) != string(want) {
+			t.Errorf("Expected option 82 %x, got %x", want, got)
+		}
+		return d
+	}
+
+	discover, err := s.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := check(discover, layers.DHCPMsgTypeDiscover)
+
+	offer, err := CraftReply(router, relay.ServerIP, d, layers.DHCPMsgTypeOffer, net.IPv4(10, 9, 0, 50), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := s.Handle(decode(offer))
+	if len(requests) != 1 {
+		t.Fatalf("Expected a relayed REQUEST, got %d", len(requests))
+	}
+	check(requests[0], layers.DHCPMsgTypeRequest)
+}
@@ -0,0 +1,157 @@
+package dtp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// DTP TLV types
+const (
+	TLVDomain   = 0x0001
+	TLVStatus   = 0x0002
+	TLVType     = 0x0003
+	TLVNeighbor = 0x0004
+)
+
+// Administrative modes carried in the low bits of the status TLV
+const (
+	ModeOn        = 0x01
+	ModeOff       = 0x02
+	ModeDesirable = 0x03
+	ModeAuto      = 0x04
+)
+
+// StatusTrunking is the operational status bit set once the port is trunking
+const StatusTrunking = 0x80
+
+// ciscoOUI is the SNAP organizational code DTP is sent under
+var ciscoOUI = []byte{0x00, 0x00, 0x0c}
+
+// snapTypeDTP is the SNAP protocol ID of DTP
+const snapTypeDTP = 0x2004
+
+// DecodeFromBytes decodes a DTP PDU (version and TLVs) that follows the SNAP header
+func (d *CustomDTPLayer) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if len(data) < 1 {
+		df.SetTruncated()
+		return errors.New("DTP header too short")
+	}
+	*d = CustomDTPLayer{BaseLayer: layers.BaseLayer{Contents: data}}
+	d.Version = data[0]
+
+	data = data[1:]
+	for len(data) > 0 {
+		if len(data) < 4 {
+			// Switches pad the frame to the Ethernet minimum after the last TLV
+			break
+		}
+		typ := binary.BigEndian.Uint16(data[0:2])
+		length := int(binary.BigEndian.Uint16(data[2:4]))
+		if typ == 0 && length == 0 {
+			break
+		}
+		if length < 4 || length > len(data) {
+			return errors.New("DTP TLV length invalid")
+		}
+		v := data[4:length]
+		data = data[length:]
+
+		switch typ {
+		case TLVDomain:
+			d.Domain = string(bytes.TrimRight(v, "\x00"))
+		case TLVStatus:
+			if len(v) >= 1 {
+				d.Status = v[0]
+			}
+		case TLVType:
+			if len(v) >= 1 {
+				d.Type = v[0]
+			}
+		case TLVNeighbor:
+			if len(v) >= 6 {
+				d.Neighbor = net.HardwareAddr(append([]byte(nil), v[:6]...))
+			}
+		}
+	}
+	return nil
+}
+
+func (d *CustomDTPLayer) CanDecode() gopacket.LayerClass {
+	return LayerTypeCustomDTP
+}
+
+func (d *CustomDTPLayer) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypeZero
+}
+
+func decodeDTP(data []byte, p gopacket.PacketBuilder) error {
+	d := &CustomDTPLayer{}
+	if err := d.DecodeFromBytes(data, p); err != nil {
+		return err
+	}
+	p.AddLayer(d)
+	return nil
+}
+
+// DecodeDTP extracts a DTP PDU from a captured Ethernet frame.
+// It returns nil if the frame is not DTP.
+func DecodeDTP(packet gopacket.Packet) *CustomDTPLayer {
+	snapLayer := packet.Layer(layers.LayerTypeSNAP)
+	if snapLayer == nil {
+		return nil
+	}
+	snap := snapLayer.(*layers.SNAP)
+	if !bytes.Equal(snap.OrganizationalCode, ciscoOUI) || snap.Type != snapTypeDTP {
+		return nil
+	}
+
+	d := &CustomDTPLayer{}
+	if err := d.DecodeFromBytes(snap.Payload, gopacket.NilDecodeFeedback); err != nil {
+		return nil
+	}
+	return d
+}
+
+// Mode returns the administrative switchport mode announced in a status byte
+func Mode(status byte) string {
+	switch status & 0x07 {
+	case ModeOn:
+		return "trunk"
+	case ModeOff:
+		return "access"
+	case ModeDesirable:
+		return "dynamic desirable"
+	case ModeAuto:
+		return "dynamic auto"
+	}
+	return "unknown"
+}
+
+// SilentMode classifies a port that sends no DTP at all, which is what switchport
+// nonegotiate does: tagged frames on the link mean it trunks anyway.
+func SilentMode(tagged bool) string {
+	if tagged {
+		return "trunk (nonegotiate)"
+	}
+	return "access (nonegotiate)"
+}
+
+// Encapsulation returns the administrative trunk encapsulation announced in a type byte
+func Encapsulation(typ byte) string {
+	switch typ & 0x07 {
+	case 0x00:
+		return "negotiate"
+	case 0x01:
+		return "native"
+	case 0x02:
+		return "ISL"
+	case 0x05:
+		return "802.1Q"
+	}
+	return "unknown"
+}
@@ -8,22 +8,39 @@ import (
 	"github.com/google/gopacket/layers"
 )
 
+// Trunk encapsulations for the type TLV: operational type in the top bits, administrative type in the low bits
+const (
+	EncapNegotiate = 0xa0
+	EncapDot1Q     = 0xa5
+	EncapISL       = 0x42
+)
+
+// Config is what our DTP frames announce besides the mode
+type Config struct {
+	// Domain must match the switch's VTP domain for the negotiation to succeed. Empty announces no domain.
+	Domain string
+	// Encapsulation is one of the Encap constants, 0 means EncapNegotiate.
+	Encapsulation byte
+}
+
 // CraftDTPDesirablePacket creates a DTP packet asking to be a trunk (Dynamic Desirable)
 func CraftDTPDesirablePacket(srcMAC net.HardwareAddr) ([]byte, error) {
-	return craftDTPPacket(srcMAC, 0x03)
+	return CraftDTPPacket(srcMAC, ModeDesirable, Config{})
 }
 
 // CraftDTPTrunkPacket creates a DTP packet forcing trunk mode (Trunk / On)
 func CraftDTPTrunkPacket(srcMAC net.HardwareAddr) ([]byte, error) {
-	return craftDTPPacket(srcMAC, 0x81)
+	return CraftDTPPacket(srcMAC, StatusTrunking|ModeOn, Config{})
 }
 
 // CraftDTPAutoPacket creates a DTP packet requesting trunk if neighbor asks (Dynamic Auto)
 func CraftDTPAutoPacket(srcMAC net.HardwareAddr) ([]byte, error) {
-	return craftDTPPacket(srcMAC, 0x04)
+	return CraftDTPPacket(srcMAC, ModeAuto, Config{})
 }
 
-func craftDTPPacket(srcMAC net.HardwareAddr, status byte) ([]byte, error) {
+// CraftDTPPacket creates a DTP packet with the given status byte. Like a switch, it
+// carries our MAC in the Neighbor TLV.
+func CraftDTPPacket(srcMAC net.HardwareAddr, status byte, cfg Config) ([]byte, error) {
 	eth := layers.Ethernet{
 		SrcMAC:       srcMAC,
 		DstMAC:       net.HardwareAddr{0x01, 0x00, 0x0c, 0xcc, 0xcc, 0xcc},
@@ -36,10 +53,16 @@ func craftDTPPacket(srcMAC net.HardwareAddr, status byte) ([]byte, error) {
 		Control: 0x03,
 	}
 
+	encap := cfg.Encapsulation
+	if encap == 0 {
+		encap = EncapNegotiate
+	}
+
 	dtpLayer := &CustomDTPLayer{
-		Domain: "trunk",
-		Status: status,
-		Type:   0xa0,
+		Domain:   cfg.Domain,
+		Status:   status,
+		Type:     encap,
+		Neighbor: srcMAC,
 	}
 
 	buf := gopacket.NewSerializeBuffer()
@@ -58,24 +81,31 @@ func craftDTPPacket(srcMAC net.HardwareAddr, status byte) ([]byte, error) {
 
 type CustomDTPLayer struct {
 	layers.BaseLayer
-	Domain string
-	Status byte
-	Type   byte
+	Version  byte
+	Domain   string
+	Status   byte
+	Type     byte
+	Neighbor net.HardwareAddr
 }
 
-var LayerTypeCustomDTP = gopacket.RegisterLayerType(2004, gopacket.LayerTypeMetadata{Name: "CustomDTP", Decoder: gopacket.DecodeFunc(nil)})
+var LayerTypeCustomDTP = gopacket.RegisterLayerType(2004, gopacket.LayerTypeMetadata{Name: "CustomDTP", Decoder: gopacket.DecodeFunc(decodeDTP)})
 
 func (d *CustomDTPLayer) LayerType() gopacket.LayerType {
 	return LayerTypeCustomDTP
 }
 
+// SerializeTo writes the SNAP header followed by the DTP PDU. The domain is NUL
+// terminated as on Cisco switches.
 func (d *CustomDTPLayer) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
-	domainBytes := []byte(d.Domain)
+	domainBytes := append([]byte(d.Domain), 0x00)
 
 	length := 5 + 1
 	length += 4 + len(domainBytes)
 	length += 5
 	length += 5
+	if len(d.Neighbor) == 6 {
+		length += 4 + 6
+	}
 
 	bytes, err := b.PrependBytes(length)
 	if err != nil {
@@ -97,9 +127,12 @@ func (d *CustomDTPLayer) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.S
 		offset += 4 + len(v)
 	}
 
-	writeTLV(0x0001, domainBytes)
-	writeTLV(0x0002, []byte{d.Status})
-	writeTLV(0x0003, []byte{d.Type})
+	writeTLV(TLVDomain, domainBytes)
+	writeTLV(TLVStatus, []byte{d.Status})
+	writeTLV(TLVType, []byte{d.Type})
+	if len(d.Neighbor) == 6 {
+		writeTLV(TLVNeighbor, d.Neighbor)
+	}
 
 	return nil
 }
@@ -1,8 +1,13 @@
 package dtp
 
 import (
+	"encoding/hex"
+	"fmt"
 	"net"
 	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
 )
 
 // CraftDTPDesirablePacket creates a DTP packet asking to be a trunk (Dynamic Desirable)
@@ -59,3 +64,91 @@ func verifyDTPPacket(t *testing.T, packet []byte, expectedStatus byte) {
 		t.Errorf("Did not find expected status 0x%x in packet", expectedStatus)
 	}
 }
+
+// switchFrame builds a DTP frame the way a Catalyst sends it: NUL padded domain, neighbor TLV and Ethernet padding
+func switchFrame(t *testing.T, status, typ byte) gopacket.Packet {
+	t.Helper()
+	raw, _ := hex.DecodeString(
+		"01000ccccccc" + "001e1300aa01" + "002a" +
+			"aaaa03" + "00000c" + "2004" +
+			"01" +
+			"0001000d" + "4c41420000000000" + "00" +
+			"00020005" + fmt.Sprintf("%02x", status) +
+			"00030005" + fmt.Sprintf("%02x", typ) +
+			"0004000a" + "001e1300aa01" +
+			"0000000000")
+	return gopacket.NewPacket(raw, layers.LinkTypeEthernet, gopacket.Default)
+}
+
+func TestDecodeDTP(t *testing.T) {
+	d := DecodeDTP(switchFrame(t, 0x03, 0xa5))
+	if d == nil {
+		t.Fatal("DecodeDTP returned nil")
+	}
+	if d.Version != 1 || d.Domain != "LAB" || d.Status != 0x03 || d.Type != 0xa5 {
+		t.Errorf("Unexpected DTP fields: %+v", d)
+	}
+	if d.Neighbor.String() != "00:1e:13:00:aa:01" {
+		t.Errorf("Neighbor = %s", d.Neighbor)
+	}
+	if Mode(d.Status) != "dynamic desirable" || Encapsulation(d.Type) != "802.1Q" {
+		t.Errorf("Mode/Encapsulation = %s/%s", Mode(d.Status), Encapsulation(d.Type))
+	}
+
+	mac, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+	ours, _ := CraftDTPAutoPacket(mac)
+	d = DecodeDTP(gopacket.NewPacket(ours, layers.LinkTypeEthernet, gopacket.Default))
+	if d == nil || d.Status != 0x04 || Mode(d.Status) != "dynamic auto" {
+		t.Errorf("Could not decode our own frame: %+v", d)
+	}
+}
+
+func TestPortMonitorNegotiation(t *testing.T) {
+	p := NewPortMonitor()
+	if p.State().Seen() {
+		t.Fatal("Fresh monitor should not have seen DTP")
+	}
+
+	p.Update(switchFrame(t, 0x04, 0xa5))
+	s := p.State()
+	if s.Mode() != "dynamic auto" || s.Trunking() || s.Negotiated || s.Domain != "LAB" {
+		t.Errorf("Unexpected state after access frame: %+v", s)
+	}
+
+	p.Update(switchFrame(t, 0x84, 0xa5))
+	s = p.State()
+	if !s.Trunking() || !s.Negotiated || s.TrunkSince.IsZero() || s.Frames != 2 {
+		t.Errorf("Port should have negotiated a trunk: %+v", s)
+	}
+
+	p.Update(switchFrame(t, 0x04, 0xa5))
+	if s = p.State(); s.Trunking() || s.Negotiated {
+		t.Errorf("Port fell back to access but state still trunking: %+v", s)
+	}
+}
+
+func TestCraftDTPPacketConfig(t *testing.T) {
+	mac, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+
+	packet, err := CraftDTPPacket(mac, ModeDesirable, Config{Domain: "LAB", Encapsulation: EncapISL})
+	if err != nil {
+		t.Fatalf("Failed to craft DTP packet: %v", err)
+	}
+
+	d := DecodeDTP(gopacket.NewPacket(packet, layers.LinkTypeEthernet, gopacket.Default))
+	if d == nil {
+		t.Fatal("Could not decode crafted DTP packet")
+	}
+	if d.Domain != "LAB" || d.Status != ModeDesirable || Encapsulation(d.Type) != "ISL" {
+		t.Errorf("Unexpected DTP fields: %+v", d)
+	}
+	if d.Neighbor.String() != mac.String() {
+		t.Errorf("Neighbor TLV = %s, want our MAC %s", d.Neighbor, mac)
+	}
+
+	packet, _ = CraftDTPDesirablePacket(mac)
+	d = DecodeDTP(gopacket.NewPacket(packet, layers.LinkTypeEthernet, gopacket.Default))
+	if d.Domain != "" || Encapsulation(d.Type) != "negotiate" {
+		t.Errorf("Default packet should announce no domain and negotiate: %+v", d)
+	}
+}
@@ -0,0 +1,100 @@
+package dtp
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// PortState is what the switch told us about the port through DTP
+type PortState struct {
+	SwitchMAC net.HardwareAddr
+	Domain    string
+	Status    byte
+	Type      byte
+	Frames    int
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// TrunkSince is when the switch started reporting the port as trunking. It is zero while the port is access.
+	TrunkSince time.Time
+	// Negotiated is set when the port was seen moving from access to trunking.
+	Negotiated bool
+}
+
+// Seen reports whether any DTP frame was heard
+func (s PortState) Seen() bool {
+	return s.Frames > 0
+}
+
+// Mode returns the switchport mode configured on the switch side
+func (s PortState) Mode() string {
+	return Mode(s.Status)
+}
+
+// Trunking reports whether the switch's last frame had the port trunking
+func (s PortState) Trunking() bool {
+	return s.Status&StatusTrunking != 0
+}
+
+// PortMonitor follows the DTP frames sent by the switch on our port. It is safe for concurrent use.
+type PortMonitor struct {
+	mu    sync.Mutex
+	state PortState
+}
+
+// NewPortMonitor creates a monitor that has not heard any DTP yet
+func NewPortMonitor() *PortMonitor {
+	return &PortMonitor{}
+}
+
+// Update records the DTP frame carried by packet, if any. It reports whether the packet was DTP.
+func (p *PortMonitor) Update(packet gopacket.Packet) bool {
+	d := DecodeDTP(packet)
+	if d == nil {
+		return false
+	}
+
+	now := packet.Metadata().Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := &p.state
+	if s.Frames == 0 {
+		s.FirstSeen = now
+	}
+	wasTrunking := s.Frames > 0 && s.Trunking()
+
+	if eth, ok := packet.LinkLayer().(*layers.Ethernet); ok {
+		s.SwitchMAC = eth.SrcMAC
+	}
+	s.Domain = d.Domain
+	s.Status = d.Status
+	s.Type = d.Type
+	s.Frames++
+	s.LastSeen = now
+
+	switch {
+	case s.Trunking() && s.TrunkSince.IsZero():
+		s.TrunkSince = now
+		if s.Frames > 1 && !wasTrunking {
+			s.Negotiated = true
+		}
+	case !s.Trunking():
+		s.TrunkSince = time.Time{}
+		s.Negotiated = false
+	}
+	return true
+}
+
+// State returns a snapshot of the port state
+func (p *PortMonitor) State() PortState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state
+}
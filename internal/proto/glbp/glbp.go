@@ -0,0 +1,318 @@
+package glbp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Port is the UDP port GLBP is sent on
+const Port = 3222
+
+// Multicast is the destination of GLBP messages
+var Multicast = net.IPv4(224, 0, 0, 102)
+
+// GLBP TLV types
+const (
+	TLVHello           = 1
+	TLVRequestResponse = 2
+	TLVAuth            = 3
+)
+
+// Authentication types of the auth TLV
+const (
+	AuthNone      = 0
+	AuthPlaintext = 1
+	AuthMD5       = 2
+	AuthMD5Chain  = 3
+)
+
+// Virtual gateway (AVG) and virtual forwarder (AVF) states
+const (
+	StateDisabled = 0x01
+	StateInit     = 0x02
+	StateListen   = 0x04
+	StateSpeak    = 0x08
+	StateStandby  = 0x10
+	StateActive   = 0x20
+)
+
+// StateName returns the name of a GLBP gateway or forwarder state
+func StateName(state uint8) string {
+	switch state {
+	case StateDisabled:
+		return "Disabled"
+	case StateInit:
+		return "Init"
+	case StateListen:
+		return "Listen"
+	case StateSpeak:
+		return "Speak"
+	case StateStandby:
+		return "Standby"
+	case StateActive:
+		return "Active"
+	}
+	return "Unknown"
+}
+
+// VirtualMAC returns the MAC of forwarder fwd of group, 00:07:b4:0g:gg:ff
+func VirtualMAC(group uint16, fwd uint8) net.HardwareAddr {
+	return net.HardwareAddr{0x00, 0x07, 0xb4, byte(group>>8) & 0x03, byte(group), fwd}
+}
+
+// Config describes the GLBP group we speak in
+type Config struct {
+	Group     uint16 // 0-1023
+	VIP       net.IP
+	Priority  uint8
+	Hellotime time.Duration
+	Holdtime  time.Duration
+	Redirect  time.Duration
+	Timeout   time.Duration
+	// Auth is the plaintext authentication string
+	Auth string
+	// Forwarder, when not 0, is announced as an Active forwarder of ours with Weight
+	Forwarder uint8
+	Weight    uint8
+	SourceIP  net.IP
+}
+
+// Hello is the virtual gateway part of a message
+type Hello struct {
+	State     uint8
+	Priority  uint8
+	Hellotime time.Duration
+	Holdtime  time.Duration
+	Redirect  time.Duration
+	Timeout   time.Duration
+	VIP       net.IP
+}
+
+// Forwarder is a request/response TLV describing a virtual forwarder
+type Forwarder struct {
+	Number     uint8
+	State      uint8
+	Priority   uint8
+	Weight     uint8
+	VirtualMAC net.HardwareAddr
+}
+
+// CraftGLBP creates a GLBP hello claiming state as virtual gateway of the group of cfg,
+// with our forwarder and the authentication when configured.
+func CraftGLBP(srcMAC net.HardwareAddr, state uint8, cfg Config) ([]byte, error) {
+	if cfg.SourceIP.To4() == nil || cfg.VIP.To4() == nil {
+		return nil, errors.New("GLBP needs an IPv4 source and virtual IP")
+	}
+	msg := &CustomGLBPLayer{
+		Version: 1,
+		Group:   cfg.Group,
+		OwnerID: srcMAC,
+		Hello: &Hello{
+			State:     state,
+			Priority:  cfg.Priority,
+			Hellotime: cfg.Hellotime,
+			Holdtime:  cfg.Holdtime,
+			Redirect:  cfg.Redirect,
+			Timeout:   cfg.Timeout,
+			VIP:       cfg.VIP,
+		},
+		Auth: cfg.Auth,
+	}
+	if cfg.Forwarder != 0 {
+		msg.Forwarders = []Forwarder{{
+			Number:     cfg.Forwarder,
+			State:      StateActive,
+			Priority:   cfg.Priority,
+			Weight:     cfg.Weight,
+			VirtualMAC: VirtualMAC(cfg.Group, cfg.Forwarder),
+		}}
+	}
+	if cfg.Auth != "" {
+		msg.AuthType = AuthPlaintext
+	}
+
+	eth := layers.Ethernet{
+		SrcMAC:       srcMAC,
+		DstMAC:       net.HardwareAddr{0x01, 0x00, 0x5e, 0x00, 0x00, 0x66},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip := layers.IPv4{
+		Version:  4,
+		TTL:      255,
+		SrcIP:    cfg.SourceIP.To4(),
+		DstIP:    Multicast,
+		Protocol: layers.IPProtocolUDP,
+	}
+	udp := layers.UDP{
+		SrcPort: Port,
+		DstPort: Port,
+	}
+	udp.SetNetworkLayerForChecksum(&ip)
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{
+		FixLengths:       true,
+		ComputeChecksums: true,
+	}
+	if err := gopacket.SerializeLayers(buf, opts, &eth, &ip, &udp, msg); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// CustomGLBPLayer is a GLBP message: a 12 byte header followed by hello, request/response and auth TLVs
+type CustomGLBPLayer struct {
+	layers.BaseLayer
+	Version uint8
+	Group   uint16
+	// OwnerID is the real MAC of the sending router
+	OwnerID    net.HardwareAddr
+	Hello      *Hello
+	Forwarders []Forwarder
+	AuthType   uint8
+	Auth       string
+}
+
+var LayerTypeCustomGLBP = gopacket.RegisterLayerType(2008, gopacket.LayerTypeMetadata{Name: "CustomGLBP", Decoder: gopacket.DecodeFunc(decodeGLBP)})
+
+func (g *CustomGLBPLayer) LayerType() gopacket.LayerType {
+	return LayerTypeCustomGLBP
+}
+
+func millis(d time.Duration) uint32 {
+	return uint32(d / time.Millisecond)
+}
+
+// SerializeTo writes the header and TLVs
+func (g *CustomGLBPLayer) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	msg := make([]byte, 12)
+	msg[0] = g.Version
+	binary.BigEndian.PutUint16(msg[2:4], g.Group)
+	copy(msg[6:12], g.OwnerID)
+
+	if h := g.Hello; h != nil {
+		tlv := make([]byte, 28)
+		tlv[0] = TLVHello
+		tlv[1] = byte(len(tlv))
+		tlv[3] = h.State
+		tlv[5] = h.Priority
+		binary.BigEndian.PutUint32(tlv[8:12], millis(h.Hellotime))
+		binary.BigEndian.PutUint32(tlv[12:16], millis(h.Holdtime))
+		binary.BigEndian.PutUint16(tlv[16:18], uint16(h.Redirect/time.Second))
+		binary.BigEndian.PutUint16(tlv[18:20], uint16(h.Timeout/time.Second))
+		tlv[22] = 1 // IPv4
+		tlv[23] = 4
+		copy(tlv[24:28], h.VIP.To4())
+		msg = append(msg, tlv...)
+	}
+	for _, f := range g.Forwarders {
+		tlv := make([]byte, 20)
+		tlv[0] = TLVRequestResponse
+		tlv[1] = byte(len(tlv))
+		tlv[2] = f.Number
+		tlv[3] = f.State
+		tlv[5] = f.Priority
+		tlv[6] = f.Weight
+		copy(tlv[14:20], f.VirtualMAC)
+		msg = append(msg, tlv...)
+	}
+	if g.AuthType != AuthNone {
+		tlv := []byte{TLVAuth, byte(4 + len(g.Auth)), g.AuthType, byte(len(g.Auth))}
+		msg = append(msg, append(tlv, g.Auth...)...)
+	}
+
+	payload, err := b.PrependBytes(len(msg))
+	if err != nil {
+		return err
+	}
+	copy(payload, msg)
+	return nil
+}
+
+// DecodeFromBytes decodes the header and the TLVs it knows, skipping the others
+func (g *CustomGLBPLayer) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if len(data) < 12 {
+		df.SetTruncated()
+		return errors.New("GLBP message too short")
+	}
+	*g = CustomGLBPLayer{BaseLayer: layers.BaseLayer{Contents: data}}
+	g.Version = data[0]
+	g.Group = binary.BigEndian.Uint16(data[2:4])
+	g.OwnerID = net.HardwareAddr(append([]byte(nil), data[6:12]...))
+
+	for rest := data[12:]; len(rest) >= 2; {
+		typ, length := rest[0], int(rest[1])
+		if length < 2 || length > len(rest) {
+			df.SetTruncated()
+			return errors.New("GLBP TLV length invalid")
+		}
+		v := rest[2:length]
+		rest = rest[length:]
+
+		switch {
+		case typ == TLVHello && len(v) >= 22:
+			h := &Hello{
+				State:     v[1],
+				Priority:  v[3],
+				Hellotime: time.Duration(binary.BigEndian.Uint32(v[6:10])) * time.Millisecond,
+				Holdtime:  time.Duration(binary.BigEndian.Uint32(v[10:14])) * time.Millisecond,
+				Redirect:  time.Duration(binary.BigEndian.Uint16(v[14:16])) * time.Second,
+				Timeout:   time.Duration(binary.BigEndian.Uint16(v[16:18])) * time.Second,
+			}
+			if addrLen := int(v[21]); len(v) >= 22+addrLen {
+				h.VIP = net.IP(append([]byte(nil), v[22:22+addrLen]...))
+			}
+			g.Hello = h
+		case typ == TLVRequestResponse && len(v) >= 18:
+			g.Forwarders = append(g.Forwarders, Forwarder{
+				Number:     v[0],
+				State:      v[1],
+				Priority:   v[3],
+				Weight:     v[4],
+				VirtualMAC: net.HardwareAddr(append([]byte(nil), v[12:18]...)),
+			})
+		case typ == TLVAuth && len(v) >= 2:
+			g.AuthType = v[0]
+			if g.AuthType == AuthPlaintext && len(v) >= 2+int(v[1]) {
+				g.Auth = string(bytes.TrimRight(v[2:2+int(v[1])], "\x00"))
+			}
+		}
+	}
+	return nil
+}
+
+func (g *CustomGLBPLayer) CanDecode() gopacket.LayerClass {
+	return LayerTypeCustomGLBP
+}
+
+func (g *CustomGLBPLayer) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeGLBP(data []byte, p gopacket.PacketBuilder) error {
+	g := &CustomGLBPLayer{}
+	if err := g.DecodeFromBytes(data, p); err != nil {
+		return err
+	}
+	p.AddLayer(g)
+	return nil
+}
+
+// DecodeGLBP returns the GLBP message carried by packet, or nil
+func DecodeGLBP(packet gopacket.Packet) *CustomGLBPLayer {
+	udp, ok := packet.Layer(layers.LayerTypeUDP).(*layers.UDP)
+	if !ok || udp.DstPort != Port {
+		return nil
+	}
+	g := &CustomGLBPLayer{}
+	if err := g.DecodeFromBytes(udp.Payload, gopacket.NilDecodeFeedback); err != nil {
+		return nil
+	}
+	return g
+}
@@ -0,0 +1,105 @@
+package glbp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+var ourMAC = net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+
+func testConfig(src string) Config {
+	return Config{
+		Group:     1,
+		VIP:       net.ParseIP("10.0.0.1"),
+		Priority:  200,
+		Hellotime: 3 * time.Second,
+		Holdtime:  10 * time.Second,
+		Redirect:  600 * time.Second,
+		Timeout:   14400 * time.Second,
+		Auth:      "s3cret",
+		Forwarder: 2,
+		Weight:    100,
+		SourceIP:  net.ParseIP(src),
+	}
+}
+
+func TestCraftGLBPRoundTrip(t *testing.T) {
+	data, err := CraftGLBP(ourMAC, StateActive, testConfig("10.0.0.66"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkt := gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)
+	if ip := pkt.NetworkLayer().(*layers.IPv4); !ip.DstIP.Equal(Multicast) || !ip.SrcIP.Equal(net.ParseIP("10.0.0.66")) {
+		t.Errorf("Unexpected IP header %v -> %v", ip.SrcIP, ip.DstIP)
+	}
+
+	msg := DecodeGLBP(pkt)
+	if msg == nil {
+		t.Fatal("GLBP not decoded")
+	}
+	if msg.Version != 1 || msg.Group != 1 || msg.OwnerID.String() != ourMAC.String() || msg.AuthType != AuthPlaintext || msg.Auth != "s3cret" {
+		t.Errorf("Unexpected header: %+v", msg)
+	}
+	h := msg.Hello
+	if h == nil || h.State != StateActive || h.Priority != 200 || h.Hellotime != 3*time.Second || h.Holdtime != 10*time.Second ||
+		h.Redirect != 600*time.Second || h.Timeout != 14400*time.Second || !h.VIP.Equal(net.ParseIP("10.0.0.1")) {
+		t.Errorf("Unexpected hello: %+v", h)
+	}
+	if len(msg.Forwarders) != 1 {
+		t.Fatalf("Expected our forwarder, got %d", len(msg.Forwarders))
+	}
+	if f := msg.Forwarders[0]; f.Number != 2 || f.State != StateActive || f.Weight != 100 || f.VirtualMAC.String() != "00:07:b4:00:01:02" {
+		t.Errorf("Unexpected forwarder: %+v", f)
+	}
+}
+
+func TestGroupTableLearnsAVGAndForwarders(t *testing.T) {
+	table := NewGroupTable(ourMAC)
+	avgMAC := net.HardwareAddr{0x00, 0x0c, 0, 0, 0, 1}
+	avfMAC := net.HardwareAddr{0x00, 0x0c, 0, 0, 0, 2}
+	now := time.Now()
+
+	hello := func(mac net.HardwareAddr, src string, state uint8, priority uint8, fwd uint8, at time.Time) gopacket.Packet {
+		cfg := testConfig(src)
+		cfg.Priority, cfg.Forwarder = priority, fwd
+		data, err := CraftGLBP(mac, state, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkt := gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)
+		pkt.Metadata().Timestamp = at
+		return pkt
+	}
+	table.Update(hello(avgMAC, "10.0.0.2", StateActive, 110, 1, now))
+	table.Update(hello(avfMAC, "10.0.0.3", StateStandby, 100, 2, now))
+	table.Update(hello(ourMAC, "10.0.0.66", StateActive, 255, 3, now))
+
+	groups := table.Groups()
+	if len(groups) != 1 {
+		t.Fatalf("Expected one group, got %d", len(groups))
+	}
+	g := groups[0]
+	if len(g.Routers) != 2 || len(g.Forwarders) != 2 {
+		t.Fatalf("Expected our own hellos ignored, got %d routers and %d forwarders", len(g.Routers), len(g.Forwarders))
+	}
+	if avg, ok := g.AVG(); !ok || !avg.IP.Equal(net.ParseIP("10.0.0.2")) || avg.MAC.String() != avgMAC.String() {
+		t.Errorf("Unexpected AVG: %+v", avg)
+	}
+	if f := g.Forwarders[1]; f.Number != 2 || f.Weight != 100 || !f.Owner.Equal(net.ParseIP("10.0.0.3")) {
+		t.Errorf("Unexpected forwarder: %+v", f)
+	}
+	if cfg := g.Config(255); cfg.Forwarder != 3 || cfg.Group != 1 || cfg.Auth != "s3cret" {
+		t.Errorf("Unexpected takeover config: %+v", cfg)
+	}
+
+	// After our takeover the former AVG falls back and stays the upstream
+	table.Update(hello(avgMAC, "10.0.0.2", StateStandby, 110, 1, now.Add(time.Second)))
+	g = table.Groups()[0]
+	if up, ok := g.Upstream(); !ok || !up.IP.Equal(net.ParseIP("10.0.0.2")) || up.Previous != StateActive {
+		t.Errorf("Unexpected upstream: %+v", up)
+	}
+}
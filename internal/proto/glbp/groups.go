@@ -0,0 +1,245 @@
+package glbp
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Router is a router speaking in a GLBP group
+type Router struct {
+	IP       net.IP
+	MAC      net.HardwareAddr
+	State    uint8 // virtual gateway state
+	Priority uint8
+	// Previous is the gateway state the router announced before its current one
+	Previous uint8
+	Changed  time.Time
+	LastSeen time.Time
+}
+
+// VirtualForwarder is an AVF of a group and its virtual MAC
+type VirtualForwarder struct {
+	Number     uint8
+	VirtualMAC net.HardwareAddr
+	State      uint8
+	Weight     uint8
+	// Owner is the IP of the router announcing the forwarder Active
+	Owner    net.IP
+	LastSeen time.Time
+}
+
+// Group is a GLBP group learned from the wire
+type Group struct {
+	Number     uint16
+	VIP        net.IP
+	Auth       string
+	AuthType   uint8
+	Hellotime  time.Duration
+	Holdtime   time.Duration
+	Redirect   time.Duration
+	Timeout    time.Duration
+	Routers    []Router
+	Forwarders []VirtualForwarder
+}
+
+// AVG returns the active virtual gateway of the group, if any
+func (g Group) AVG() (Router, bool) {
+	for _, r := range g.Routers {
+		if r.State == StateActive {
+			return r, true
+		}
+	}
+	return Router{}, false
+}
+
+// Upstream returns the real router that routes for the group once we are AVG: the former AVG,
+// or the router with the highest priority
+func (g Group) Upstream() (Router, bool) {
+	var best Router
+	for _, r := range g.Routers {
+		if r.Previous == StateActive || r.State == StateActive {
+			return r, true
+		}
+		if best.IP == nil || r.Priority > best.Priority || (r.Priority == best.Priority && bytes.Compare(r.IP, best.IP) > 0) {
+			best = r
+		}
+	}
+	return best, best.IP != nil
+}
+
+// FreeForwarder returns the lowest forwarder number not used in the group, from 1 to 4
+func (g Group) FreeForwarder() uint8 {
+	used := make(map[uint8]bool)
+	for _, f := range g.Forwarders {
+		used[f.Number] = true
+	}
+	for n := uint8(1); n <= 4; n++ {
+		if !used[n] {
+			return n
+		}
+	}
+	return 0
+}
+
+// Config returns the settings to speak in the group with priority
+func (g Group) Config(priority uint8) Config {
+	return Config{
+		Group:     g.Number,
+		VIP:       g.VIP,
+		Priority:  priority,
+		Hellotime: g.Hellotime,
+		Holdtime:  g.Holdtime,
+		Redirect:  g.Redirect,
+		Timeout:   g.Timeout,
+		Auth:      g.Auth,
+		Forwarder: g.FreeForwarder(),
+	}
+}
+
+type group struct {
+	Group
+	routers    map[string]*Router
+	forwarders map[uint8]*VirtualForwarder
+}
+
+// GroupTable keeps track of the GLBP groups, routers and forwarders seen on an interface. It is safe for concurrent use.
+type GroupTable struct {
+	// ignore is our own MAC, whose hellos are not learned
+	ignore net.HardwareAddr
+
+	mu     sync.Mutex
+	groups map[uint16]*group
+}
+
+// NewGroupTable creates an empty table. Hellos sent by ignore, our own MAC, are not learned.
+func NewGroupTable(ignore net.HardwareAddr) *GroupTable {
+	return &GroupTable{ignore: ignore, groups: make(map[uint16]*group)}
+}
+
+// Update records the GLBP message carried by packet, if any. It reports whether the packet was GLBP.
+func (t *GroupTable) Update(packet gopacket.Packet) bool {
+	msg := DecodeGLBP(packet)
+	if msg == nil {
+		return false
+	}
+	if bytes.Equal(msg.OwnerID, t.ignore) {
+		return true
+	}
+	ip, ok := packet.NetworkLayer().(*layers.IPv4)
+	if !ok {
+		return true
+	}
+	now := packet.Metadata().Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	g := t.groups[msg.Group]
+	if g == nil {
+		g = &group{Group: Group{Number: msg.Group}, routers: make(map[string]*Router), forwarders: make(map[uint8]*VirtualForwarder)}
+		t.groups[msg.Group] = g
+	}
+	g.AuthType = msg.AuthType
+	g.Auth = msg.Auth
+
+	src := ip.SrcIP.String()
+	r := g.routers[src]
+	if h := msg.Hello; h != nil {
+		if h.VIP != nil && !h.VIP.IsUnspecified() {
+			g.VIP = h.VIP
+		}
+		g.Hellotime, g.Holdtime = h.Hellotime, h.Holdtime
+		g.Redirect, g.Timeout = h.Redirect, h.Timeout
+
+		if r == nil {
+			r = &Router{IP: append(net.IP(nil), ip.SrcIP...), State: h.State, Previous: h.State, Changed: now}
+			g.routers[src] = r
+		}
+		if r.State != h.State {
+			r.Previous = r.State
+			r.State = h.State
+			r.Changed = now
+		}
+		r.Priority = h.Priority
+	}
+	if r != nil {
+		r.MAC = msg.OwnerID
+		r.LastSeen = now
+	}
+
+	for _, f := range msg.Forwarders {
+		vf := g.forwarders[f.Number]
+		if vf == nil {
+			vf = &VirtualForwarder{Number: f.Number}
+			g.forwarders[f.Number] = vf
+		}
+		vf.VirtualMAC = f.VirtualMAC
+		// Every router lists the forwarders it knows of, the owner is the one announcing it Active
+		if f.State == StateActive {
+			vf.State = f.State
+			vf.Weight = f.Weight
+			vf.Owner = append(net.IP(nil), ip.SrcIP...)
+			vf.LastSeen = now
+		}
+	}
+	return true
+}
+
+// Groups returns the learned groups sorted by number, with their routers sorted by IP and their
+// forwarders by number. Routers and forwarders silent for longer than the holdtime are dropped.
+func (t *GroupTable) Groups() []Group {
+	now := time.Now()
+
+	t.mu.Lock()
+	out := make([]Group, 0, len(t.groups))
+	for _, g := range t.groups {
+		hold := g.Holdtime
+		if hold == 0 {
+			hold = 10 * time.Second
+		}
+		c := g.Group
+		c.Routers = nil
+		for key, r := range g.routers {
+			if now.Sub(r.LastSeen) > hold {
+				delete(g.routers, key)
+				continue
+			}
+			c.Routers = append(c.Routers, *r)
+		}
+		c.Forwarders = nil
+		for key, f := range g.forwarders {
+			if !f.LastSeen.IsZero() && now.Sub(f.LastSeen) > hold {
+				delete(g.forwarders, key)
+				continue
+			}
+			c.Forwarders = append(c.Forwarders, *f)
+		}
+		sort.Slice(c.Routers, func(i, j int) bool { return bytes.Compare(c.Routers[i].IP, c.Routers[j].IP) < 0 })
+		sort.Slice(c.Forwarders, func(i, j int) bool { return c.Forwarders[i].Number < c.Forwarders[j].Number })
+		out = append(out, c)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Number < out[j].Number })
+	return out
+}
+
+// RouterMAC returns the real MAC of the router at ip, as carried in its messages
+func (t *GroupTable) RouterMAC(ip net.IP) (net.HardwareAddr, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, g := range t.groups {
+		if r := g.routers[ip.String()]; r != nil && r.MAC != nil {
+			return r.MAC, true
+		}
+	}
+	return nil, false
+}
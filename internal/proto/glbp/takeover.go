@@ -0,0 +1,58 @@
+package glbp
+
+import (
+	"net"
+	"sync"
+)
+
+// Takeover speaks for us as the active virtual gateway (AVG) of a group. When stopped it
+// announces priority 0 in Listen so the standby gateway takes the role back without waiting
+// for the holdtime. It is safe for concurrent use.
+type Takeover struct {
+	srcMAC net.HardwareAddr
+	cfg    Config
+
+	mu   sync.Mutex
+	sent uint64
+}
+
+// NewTakeover creates a takeover of the group of cfg, sent from srcMAC
+func NewTakeover(srcMAC net.HardwareAddr, cfg Config) *Takeover {
+	return &Takeover{srcMAC: srcMAC, cfg: cfg}
+}
+
+// Packets returns the next hello
+func (t *Takeover) Packets() ([][]byte, error) {
+	pkt, err := CraftGLBP(t.srcMAC, StateActive, t.cfg)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	t.sent++
+	t.mu.Unlock()
+	return [][]byte{pkt}, nil
+}
+
+// ResignPackets returns the hello that gives the AVG role up
+func (t *Takeover) ResignPackets() ([][]byte, error) {
+	cfg := t.cfg
+	cfg.Priority = 0
+	cfg.Forwarder = 0
+	pkt, err := CraftGLBP(t.srcMAC, StateListen, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{pkt}, nil
+}
+
+// Config returns the group settings the takeover speaks with
+func (t *Takeover) Config() Config {
+	return t.cfg
+}
+
+// Sent returns the number of hellos sent
+func (t *Takeover) Sent() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sent
+}
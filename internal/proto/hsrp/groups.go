@@ -0,0 +1,239 @@
+package hsrp
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Router is a router speaking in an HSRP group
+type Router struct {
+	IP       net.IP
+	MAC      net.HardwareAddr
+	State    uint8
+	Priority uint8
+	// Previous is the state the router announced before its current one
+	Previous uint8
+	Changed  time.Time
+	LastSeen time.Time
+}
+
+// Group is an HSRP group learned from the wire
+type Group struct {
+	Version uint8 // 1 or 2
+	Number  uint16
+	VIP     net.IP
+	Auth    string
+	// MD5 is set when the group authenticates with MD5 under KeyID, whose key we do not know
+	MD5       bool
+	KeyID     uint32
+	Hellotime uint8
+	Holdtime  uint8
+	Routers   []Router
+}
+
+// Router returns the router of the group in state, if any
+func (g Group) Router(state uint8) (Router, bool) {
+	for _, r := range g.Routers {
+		if r.State == state {
+			return r, true
+		}
+	}
+	return Router{}, false
+}
+
+// Upstream returns the real router that routes for the group once we hold Active:
+// the one with the highest priority, ties going to the highest address as in the election
+func (g Group) Upstream() (Router, bool) {
+	var best Router
+	for _, r := range g.Routers {
+		if best.IP == nil || r.Priority > best.Priority || (r.Priority == best.Priority && bytes.Compare(r.IP, best.IP) > 0) {
+			best = r
+		}
+	}
+	return best, best.IP != nil
+}
+
+// Config returns the settings to speak in the group with priority
+func (g Group) Config(priority uint8) Config {
+	return Config{
+		Version:   g.Version,
+		Group:     g.Number,
+		VIP:       g.VIP,
+		Priority:  priority,
+		Hellotime: g.Hellotime,
+		Holdtime:  g.Holdtime,
+		Auth:      g.Auth,
+		KeyID:     g.KeyID,
+	}
+}
+
+// IPv6 reports whether the group is an HSRPv2 group for IPv6
+func (g Group) IPv6() bool {
+	return g.VIP != nil && g.VIP.To4() == nil
+}
+
+type groupKey struct {
+	version uint8
+	number  uint16
+	ipv6    bool
+}
+
+type group struct {
+	Group
+	routers map[string]*Router
+}
+
+// GroupTable keeps track of the HSRP groups and routers seen on an interface. It is safe for concurrent use.
+type GroupTable struct {
+	// ignore is our own MAC, whose hellos are not learned
+	ignore net.HardwareAddr
+
+	mu        sync.Mutex
+	groups    map[groupKey]*group
+	ignoreIPs map[string]bool
+}
+
+// NewGroupTable creates an empty table. Hellos sent by ignore, our own MAC, are not learned.
+func NewGroupTable(ignore net.HardwareAddr) *GroupTable {
+	return &GroupTable{ignore: ignore, groups: make(map[groupKey]*group), ignoreIPs: make(map[string]bool)}
+}
+
+// IgnoreIP stops learning hellos sent from ip, the source address of our own messages.
+// Our hellos claiming Active are sent from the virtual MAC, so they cannot be told apart by MAC.
+func (t *GroupTable) IgnoreIP(ip net.IP) {
+	t.mu.Lock()
+	t.ignoreIPs[ip.String()] = true
+	t.mu.Unlock()
+}
+
+// Update records the HSRP message carried by packet, if any. It reports whether the packet was HSRP.
+func (t *GroupTable) Update(packet gopacket.Packet) bool {
+	h := DecodeHSRP(packet)
+	if h == nil {
+		return false
+	}
+	eth, _ := packet.LinkLayer().(*layers.Ethernet)
+	if (eth != nil && bytes.Equal(eth.SrcMAC, t.ignore)) || bytes.Equal(h.Identifier, t.ignore) {
+		return true
+	}
+	var src net.IP
+	if ip, ok := packet.NetworkLayer().(*layers.IPv4); ok {
+		src = ip.SrcIP
+	} else if ip6, ok := packet.NetworkLayer().(*layers.IPv6); ok {
+		src = ip6.SrcIP
+	}
+	if src == nil {
+		return true
+	}
+
+	now := packet.Metadata().Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.ignoreIPs[src.String()] {
+		return true
+	}
+	// HSRPv1 messages carry version 0
+	version := h.Version
+	if version == 0 {
+		version = 1
+	}
+	key := groupKey{version: version, number: h.Group, ipv6: h.VIP != nil && h.VIP.To4() == nil}
+	g := t.groups[key]
+	if g == nil {
+		g = &group{Group: Group{Version: version, Number: h.Group}, routers: make(map[string]*Router)}
+		t.groups[key] = g
+	}
+	if h.VIP != nil && !h.VIP.IsUnspecified() {
+		g.VIP = h.VIP
+	}
+	g.Auth = h.Auth
+	g.MD5 = h.Digest != nil
+	g.KeyID = h.KeyID
+	g.Hellotime = h.Hellotime
+	g.Holdtime = h.Holdtime
+
+	r := g.routers[src.String()]
+	if r == nil {
+		r = &Router{IP: append(net.IP(nil), src...), State: h.State, Previous: h.State, Changed: now}
+		g.routers[src.String()] = r
+	}
+	if h.OpCode == OpResign {
+		// A resigning router leaves Active, it will announce its next state in its following hello
+		h.State = StateSpeak
+	}
+	if r.State != h.State {
+		r.Previous = r.State
+		r.State = h.State
+		r.Changed = now
+	}
+	// The Active router sends from the virtual MAC, HSRPv2 carries the real one as identifier
+	switch {
+	case h.Identifier != nil:
+		r.MAC = h.Identifier
+	case eth != nil && !bytes.Equal(eth.SrcMAC, g.Config(0).VirtualMAC()):
+		r.MAC = append(net.HardwareAddr(nil), eth.SrcMAC...)
+	}
+	r.Priority = h.Priority
+	r.LastSeen = now
+	return true
+}
+
+// Groups returns the learned groups sorted by version, address family and number, with their routers
+// sorted by IP. Routers silent for longer than the group's holdtime are dropped.
+func (t *GroupTable) Groups() []Group {
+	now := time.Now()
+
+	t.mu.Lock()
+	out := make([]Group, 0, len(t.groups))
+	for _, g := range t.groups {
+		hold := time.Duration(g.Holdtime) * time.Second
+		if hold == 0 {
+			hold = 10 * time.Second
+		}
+		c := g.Group
+		c.Routers = nil
+		for key, r := range g.routers {
+			if now.Sub(r.LastSeen) > hold {
+				delete(g.routers, key)
+				continue
+			}
+			c.Routers = append(c.Routers, *r)
+		}
+		sort.Slice(c.Routers, func(i, j int) bool { return bytes.Compare(c.Routers[i].IP, c.Routers[j].IP) < 0 })
+		out = append(out, c)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Version != out[j].Version {
+			return out[i].Version < out[j].Version
+		}
+		if out[i].IPv6() != out[j].IPv6() {
+			return !out[i].IPv6()
+		}
+		return out[i].Number < out[j].Number
+	})
+	return out
+}
+
+// RouterMAC returns the real MAC of the router at ip, as learned from its hellos
+func (t *GroupTable) RouterMAC(ip net.IP) (net.HardwareAddr, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, g := range t.groups {
+		if r := g.routers[ip.String()]; r != nil && r.MAC != nil {
+			return r.MAC, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,104 @@
+package hsrp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// routerHello builds a hello from a router at src
+func routerHello(t *testing.T, mac net.HardwareAddr, src net.IP, state, priority uint8, at time.Time) gopacket.Packet {
+	t.Helper()
+	cfg := Config{Group: 10, VIP: net.IPv4(10, 0, 0, 1), Priority: priority, Hellotime: 3, Holdtime: 10, Auth: "s3cret", SourceIP: src}
+	data, err := CraftHSRP(mac, OpHello, state, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkt := gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)
+	pkt.Metadata().Timestamp = at
+	return pkt
+}
+
+func TestGroupTableLearnsGroups(t *testing.T) {
+	ours := net.HardwareAddr{0x02, 0, 0, 0, 0, 0x99}
+	table := NewGroupTable(ours)
+	table.IgnoreIP(net.IPv4(10, 0, 0, 66))
+	r1 := net.HardwareAddr{0x00, 0x0c, 0, 0, 0, 1}
+	r2 := net.HardwareAddr{0x00, 0x0c, 0, 0, 0, 2}
+	now := time.Now()
+
+	table.Update(routerHello(t, r1, net.IPv4(10, 0, 0, 2), StateActive, 110, now))
+	table.Update(routerHello(t, r2, net.IPv4(10, 0, 0, 3), StateStandby, 100, now))
+	table.Update(routerHello(t, ours, net.IPv4(10, 0, 0, 66), StateActive, 255, now))
+
+	groups := table.Groups()
+	if len(groups) != 1 {
+		t.Fatalf("Expected one group, got %d", len(groups))
+	}
+	g := groups[0]
+	if g.Number != 10 || !g.VIP.Equal(net.IPv4(10, 0, 0, 1)) || g.Auth != "s3cret" || g.Hellotime != 3 || g.Holdtime != 10 {
+		t.Errorf("Unexpected group: %+v", g)
+	}
+	if len(g.Routers) != 2 {
+		t.Fatalf("Expected our own hellos ignored, got %d routers", len(g.Routers))
+	}
+	if active, ok := g.Router(StateActive); !ok || !active.IP.Equal(net.IPv4(10, 0, 0, 2)) || active.Priority != 110 {
+		t.Errorf("Unexpected active router: %+v", active)
+	}
+
+	// After our takeover the real active router drops to Speak
+	table.Update(routerHello(t, r1, net.IPv4(10, 0, 0, 2), StateSpeak, 110, now.Add(time.Second)))
+	g = table.Groups()[0]
+	if r := g.Routers[0]; r.State != StateSpeak || r.Previous != StateActive {
+		t.Errorf("Expected the router to move from Active to Speak, got %s from %s", StateName(r.State), StateName(r.Previous))
+	}
+
+	if up, ok := g.Upstream(); !ok || !up.IP.Equal(net.IPv4(10, 0, 0, 2)) {
+		t.Errorf("Expected the priority 110 router upstream, got %+v", up)
+	}
+	if mac, ok := table.RouterMAC(net.IPv4(10, 0, 0, 2)); !ok || mac.String() != r1.String() {
+		t.Errorf("Expected the real MAC of the former Active router, got %v", mac)
+	}
+
+	cfg := g.Config(255)
+	if cfg.Group != 10 || cfg.Auth != "s3cret" || cfg.Priority != 255 || !cfg.VIP.Equal(g.VIP) {
+		t.Errorf("Unexpected takeover config: %+v", cfg)
+	}
+}
+
+func TestTakeoverCoupHelloResign(t *testing.T) {
+	ours := net.HardwareAddr{0x02, 0, 0, 0, 0, 0x99}
+	cfg := Config{Group: 10, VIP: net.IPv4(10, 0, 0, 1), Priority: 255, Hellotime: 3, Holdtime: 10, SourceIP: net.IPv4(10, 0, 0, 66)}
+	takeover := NewTakeover(ours, cfg)
+
+	opcodes := func(packets [][]byte) []uint8 {
+		var ops []uint8
+		for _, data := range packets {
+			h := DecodeHSRP(gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default))
+			if h == nil || h.State != StateActive {
+				t.Fatalf("Unexpected message %+v", h)
+			}
+			ops = append(ops, h.OpCode)
+		}
+		return ops
+	}
+
+	first, _ := takeover.Packets()
+	if ops := opcodes(first); len(ops) != 2 || ops[0] != OpCoup || ops[1] != OpHello {
+		t.Errorf("Expected a Coup then a Hello, got %v", ops)
+	}
+	next, _ := takeover.Packets()
+	if ops := opcodes(next); len(ops) != 1 || ops[0] != OpHello {
+		t.Errorf("Expected only a Hello, got %v", ops)
+	}
+	resign, _ := takeover.ResignPackets()
+	if ops := opcodes(resign); len(ops) != 1 || ops[0] != OpResign {
+		t.Errorf("Expected a Resign, got %v", ops)
+	}
+	if hellos, coups := takeover.Counts(); hellos != 2 || coups != 1 {
+		t.Errorf("Counts = %d hellos, %d coups", hellos, coups)
+	}
+}
@@ -1,56 +1,443 @@
 package hsrp
 
 import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
 	"net"
 
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
 )
 
-// CraftHSRPState creates an HSRP Hello/Coup packet claiming a state.
-func CraftHSRPState(srcMAC net.HardwareAddr, vip net.IP, priority uint8, state uint8, group uint8) ([]byte, error) {
-	dstMAC := net.HardwareAddr{0x00, 0x00, 0x0c, 0x07, 0xac, byte(group)}
+// Port is the UDP port HSRP is sent on, PortIPv6 the one of HSRPv2 for IPv6 groups
+const (
+	Port     = 1985
+	PortIPv6 = 2029
+)
 
-	eth := layers.Ethernet{
-		SrcMAC:       srcMAC,
-		DstMAC:       dstMAC,
-		EthernetType: layers.EthernetTypeIPv4,
-	}
+// Multicast destinations of HSRPv1, HSRPv2 and HSRPv2 for IPv6
+var (
+	GroupV1   = net.IPv4(224, 0, 0, 2)
+	GroupV2   = net.IPv4(224, 0, 0, 102)
+	GroupIPv6 = net.ParseIP("ff02::66")
+)
+
+// HSRPv2 TLV types
+const (
+	TLVGroupState = 1
+	TLVInterface  = 2
+	TLVTextAuth   = 3
+	TLVMD5Auth    = 4
+)
 
-	ip := layers.IPv4{
-		Version:  4,
-		TTL:      1,
-		SrcIP:    vip,
-		DstIP:    net.ParseIP("224.0.0.2"),
-		Protocol: layers.IPProtocolUDP,
+// HSRP opcodes
+const (
+	OpHello     = 0
+	OpCoup      = 1
+	OpResign    = 2
+	OpAdvertise = 3
+)
+
+// HSRP states
+const (
+	StateInitial = 0
+	StateLearn   = 1
+	StateListen  = 2
+	StateSpeak   = 4
+	StateStandby = 8
+	StateActive  = 16
+)
+
+// StateName returns the name of an HSRP state
+func StateName(state uint8) string {
+	switch state {
+	case StateInitial:
+		return "Initial"
+	case StateLearn:
+		return "Learn"
+	case StateListen:
+		return "Listen"
+	case StateSpeak:
+		return "Speak"
+	case StateStandby:
+		return "Standby"
+	case StateActive:
+		return "Active"
 	}
+	return "Unknown"
+}
 
-	udp := layers.UDP{
-		SrcPort: 1985,
-		DstPort: 1985,
+// DefaultAuth is the plaintext authentication routers use when none is configured
+const DefaultAuth = "cisco"
+
+// Config describes the HSRP group we speak in
+type Config struct {
+	// Version is 1 or 2, 0 means 1. IPv6 VIPs need version 2.
+	Version   uint8
+	Group     uint16
+	VIP       net.IP
+	Priority  uint8
+	Hellotime uint8
+	Holdtime  uint8
+	// Auth is the plaintext authentication string, at most 8 bytes
+	Auth string
+	// MD5Key, when set, authenticates HSRPv2 messages with an MD5 TLV under KeyID instead of Auth
+	MD5Key string
+	KeyID  uint32
+	// SourceIP is the interface address messages are sent from. IPv6 groups default to our link-local address.
+	SourceIP net.IP
+}
+
+// VirtualMAC returns the MAC the group's VIP resolves to: 00:00:0c:07:ac:xx for HSRPv1,
+// 00:00:0c:9f:fx:xx for HSRPv2 and 00:05:73:a0:0x:xx for HSRPv2 IPv6 groups.
+func (c Config) VirtualMAC() net.HardwareAddr {
+	switch {
+	case c.VIP != nil && c.VIP.To4() == nil:
+		return net.HardwareAddr{0x00, 0x05, 0x73, 0xa0, byte(c.Group>>8) & 0x0f, byte(c.Group)}
+	case c.Version == 2:
+		return net.HardwareAddr{0x00, 0x00, 0x0c, 0x9f, 0xf0 | byte(c.Group>>8)&0x0f, byte(c.Group)}
 	}
-	udp.SetNetworkLayerForChecksum(&ip)
+	return net.HardwareAddr{0x00, 0x00, 0x0c, 0x07, 0xac, byte(c.Group)}
+}
 
-	payload := make([]byte, 20)
-	payload[0] = 0
-	payload[1] = 0
-	payload[2] = state
-	payload[3] = 3
-	payload[4] = 10
-	payload[5] = priority
-	payload[6] = group
-	payload[7] = 0
-	copy(payload[8:], []byte("cisco\x00\x00\x00"))
-	copy(payload[16:], vip.To4())
+// CraftHSRPState creates an HSRPv1 message with opcode op claiming a state, sent from srcIP.
+func CraftHSRPState(srcMAC net.HardwareAddr, srcIP, vip net.IP, priority, op, state, group uint8) ([]byte, error) {
+	return CraftHSRP(srcMAC, op, state, Config{
+		Group:     uint16(group),
+		VIP:       vip,
+		Priority:  priority,
+		Hellotime: 3,
+		Holdtime:  10,
+		Auth:      DefaultAuth,
+		SourceIP:  srcIP,
+	})
+}
+
+// CraftHSRP creates an HSRP message with opcode op claiming state in the group of cfg.
+// Like a real router, messages claiming Active are sent from the virtual MAC so switches learn it on our port.
+func CraftHSRP(srcMAC net.HardwareAddr, op, state uint8, cfg Config) ([]byte, error) {
+	hsrp := &CustomHSRPLayer{
+		Version:    cfg.Version,
+		OpCode:     op,
+		State:      state,
+		Hellotime:  cfg.Hellotime,
+		Holdtime:   cfg.Holdtime,
+		Priority:   cfg.Priority,
+		Group:      cfg.Group,
+		Identifier: srcMAC,
+		Auth:       cfg.Auth,
+		VIP:        cfg.VIP,
+		KeyID:      cfg.KeyID,
+		MD5Key:     cfg.MD5Key,
+	}
+	if hsrp.Version == 0 {
+		hsrp.Version = 1
+	}
+	if hsrp.Version == 1 && cfg.VIP.To4() == nil {
+		return nil, errors.New("IPv6 groups need HSRPv2")
+	}
+	ethSrc := srcMAC
+	if state == StateActive {
+		ethSrc = cfg.VirtualMAC()
+	}
 
+	udp := layers.UDP{
+		SrcPort: Port,
+		DstPort: Port,
+	}
 	buf := gopacket.NewSerializeBuffer()
 	opts := gopacket.SerializeOptions{
 		FixLengths:       true,
 		ComputeChecksums: true,
 	}
-	err := gopacket.SerializeLayers(buf, opts, &eth, &ip, &udp, gopacket.Payload(payload))
+
+	var err error
+	switch {
+	case cfg.VIP.To4() == nil:
+		udp.SrcPort, udp.DstPort = PortIPv6, PortIPv6
+		eth := layers.Ethernet{
+			SrcMAC:       ethSrc,
+			DstMAC:       net.HardwareAddr{0x33, 0x33, 0x00, 0x00, 0x00, 0x66},
+			EthernetType: layers.EthernetTypeIPv6,
+		}
+		src := cfg.SourceIP
+		if src == nil || src.To4() != nil {
+			src = linkLocal(srcMAC)
+		}
+		ip := layers.IPv6{
+			Version:    6,
+			HopLimit:   255,
+			SrcIP:      src,
+			DstIP:      GroupIPv6,
+			NextHeader: layers.IPProtocolUDP,
+		}
+		hsrp.SourceIP = ip.SrcIP
+		udp.SetNetworkLayerForChecksum(&ip)
+		err = gopacket.SerializeLayers(buf, opts, &eth, &ip, &udp, hsrp)
+	case cfg.SourceIP.To4() == nil:
+		return nil, errors.New("no IPv4 source address")
+	default:
+		eth := layers.Ethernet{
+			SrcMAC:       ethSrc,
+			DstMAC:       net.HardwareAddr{0x01, 0x00, 0x5e, 0x00, 0x00, 0x02},
+			EthernetType: layers.EthernetTypeIPv4,
+		}
+		ip := layers.IPv4{
+			Version:  4,
+			TTL:      1,
+			SrcIP:    cfg.SourceIP.To4(),
+			DstIP:    GroupV1,
+			Protocol: layers.IPProtocolUDP,
+		}
+		if hsrp.Version == 2 {
+			eth.DstMAC = net.HardwareAddr{0x01, 0x00, 0x5e, 0x00, 0x00, 0x66}
+			ip.DstIP = GroupV2
+		}
+		hsrp.SourceIP = ip.SrcIP
+		udp.SetNetworkLayerForChecksum(&ip)
+		err = gopacket.SerializeLayers(buf, opts, &eth, &ip, &udp, hsrp)
+	}
 	if err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
 }
+
+// linkLocal returns the EUI-64 IPv6 link-local address of mac
+func linkLocal(mac net.HardwareAddr) net.IP {
+	ip := make(net.IP, net.IPv6len)
+	ip[0], ip[1] = 0xfe, 0x80
+	if len(mac) == 6 {
+		ip[8], ip[9], ip[10] = mac[0]^0x02, mac[1], mac[2]
+		ip[11], ip[12] = 0xff, 0xfe
+		ip[13], ip[14], ip[15] = mac[3], mac[4], mac[5]
+	}
+	return ip
+}
+
+// MD5Digest returns the keyed MD5 of an HSRP message whose digest field is zeroed,
+// computed RFC 1828 style over the key padded to 64 bytes, the message and the key again
+func MD5Digest(key string, message []byte) []byte {
+	h := md5.New()
+	h.Write([]byte(key))
+	if pad := len(key) % 64; pad != 0 || len(key) == 0 {
+		h.Write(make([]byte, 64-pad))
+	}
+	h.Write(message)
+	h.Write([]byte(key))
+	return h.Sum(nil)
+}
+
+// CustomHSRPLayer is an HSRP message. Version 1 messages carry version 0 on the wire,
+// version 2 ones are TLV encoded and carry the group state, the identifier and the authentication.
+type CustomHSRPLayer struct {
+	layers.BaseLayer
+	Version   uint8
+	OpCode    uint8
+	State     uint8
+	Hellotime uint8
+	Holdtime  uint8
+	Priority  uint8
+	Group     uint16
+	Auth      string
+	VIP       net.IP
+	// HSRPv2 only
+	Identifier net.HardwareAddr
+	KeyID      uint32
+	Digest     []byte
+	// SourceIP is carried in the MD5 TLV
+	SourceIP net.IP
+	// MD5Key is not sent, it keys the MD5 TLV when serializing
+	MD5Key string
+}
+
+var LayerTypeCustomHSRP = gopacket.RegisterLayerType(2006, gopacket.LayerTypeMetadata{Name: "CustomHSRP", Decoder: gopacket.DecodeFunc(decodeHSRP)})
+
+func (h *CustomHSRPLayer) LayerType() gopacket.LayerType {
+	return LayerTypeCustomHSRP
+}
+
+// SerializeTo writes the 20 byte HSRPv1 message, or the HSRPv2 TLVs
+func (h *CustomHSRPLayer) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	if h.Version == 2 {
+		return h.serializeV2(b)
+	}
+	payload, err := b.PrependBytes(20)
+	if err != nil {
+		return err
+	}
+	payload[0] = 0
+	payload[1] = h.OpCode
+	payload[2] = h.State
+	payload[3] = h.Hellotime
+	payload[4] = h.Holdtime
+	payload[5] = h.Priority
+	payload[6] = byte(h.Group)
+	payload[7] = 0
+	auth := make([]byte, 8)
+	copy(auth, h.Auth)
+	copy(payload[8:16], auth)
+	copy(payload[16:20], h.VIP.To4())
+	return nil
+}
+
+func (h *CustomHSRPLayer) serializeV2(b gopacket.SerializeBuffer) error {
+	msg := make([]byte, 42)
+	msg[0] = TLVGroupState
+	msg[1] = 40
+	msg[2] = 2
+	msg[3] = h.OpCode
+	msg[4] = h.State
+	msg[5] = 4
+	binary.BigEndian.PutUint16(msg[6:8], h.Group)
+	copy(msg[8:14], h.Identifier)
+	binary.BigEndian.PutUint32(msg[14:18], uint32(h.Priority))
+	binary.BigEndian.PutUint32(msg[18:22], uint32(h.Hellotime)*1000)
+	binary.BigEndian.PutUint32(msg[22:26], uint32(h.Holdtime)*1000)
+	if vip4 := h.VIP.To4(); vip4 != nil {
+		copy(msg[26:42], vip4)
+	} else {
+		msg[5] = 6
+		copy(msg[26:42], h.VIP.To16())
+	}
+
+	switch {
+	case h.MD5Key != "":
+		auth := make([]byte, 30)
+		auth[0] = TLVMD5Auth
+		auth[1] = 28
+		auth[2] = 1 // MD5
+		copy(auth[6:10], h.SourceIP.To4())
+		binary.BigEndian.PutUint32(auth[10:14], h.KeyID)
+		msg = append(msg, auth...)
+		h.Digest = MD5Digest(h.MD5Key, msg)
+		copy(msg[len(msg)-16:], h.Digest)
+	case h.Auth != "":
+		auth := make([]byte, 10)
+		auth[0] = TLVTextAuth
+		auth[1] = 8
+		copy(auth[2:], h.Auth)
+		msg = append(msg, auth...)
+	}
+
+	payload, err := b.PrependBytes(len(msg))
+	if err != nil {
+		return err
+	}
+	copy(payload, msg)
+	return nil
+}
+
+// DecodeFromBytes decodes an HSRPv1 message or HSRPv2 TLVs
+func (h *CustomHSRPLayer) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if len(data) >= 2 && data[0] == TLVGroupState && data[1] == 40 {
+		return h.decodeV2(data, df)
+	}
+	if len(data) < 20 {
+		df.SetTruncated()
+		return errors.New("HSRP message too short")
+	}
+	*h = CustomHSRPLayer{BaseLayer: layers.BaseLayer{Contents: data[:20], Payload: data[20:]}}
+	h.Version = 1
+	h.OpCode = data[1]
+	h.State = data[2]
+	h.Hellotime = data[3]
+	h.Holdtime = data[4]
+	h.Priority = data[5]
+	h.Group = uint16(data[6])
+	h.Auth = string(bytes.TrimRight(data[8:16], "\x00"))
+	h.VIP = net.IP(append([]byte(nil), data[16:20]...))
+	return nil
+}
+
+func (h *CustomHSRPLayer) decodeV2(data []byte, df gopacket.DecodeFeedback) error {
+	*h = CustomHSRPLayer{BaseLayer: layers.BaseLayer{Contents: data}}
+	for rest := data; len(rest) >= 2; {
+		typ, length := rest[0], int(rest[1])
+		if 2+length > len(rest) {
+			df.SetTruncated()
+			return errors.New("HSRPv2 TLV length invalid")
+		}
+		v := rest[2 : 2+length]
+		rest = rest[2+length:]
+
+		switch {
+		case typ == TLVGroupState && length >= 40:
+			h.Version = v[0]
+			h.OpCode = v[1]
+			h.State = v[2]
+			h.Group = binary.BigEndian.Uint16(v[4:6])
+			h.Identifier = net.HardwareAddr(append([]byte(nil), v[6:12]...))
+			h.Priority = uint8(min(binary.BigEndian.Uint32(v[12:16]), 255))
+			h.Hellotime = seconds(binary.BigEndian.Uint32(v[16:20]))
+			h.Holdtime = seconds(binary.BigEndian.Uint32(v[20:24]))
+			if v[3] == 6 {
+				h.VIP = net.IP(append([]byte(nil), v[24:40]...))
+			} else {
+				h.VIP = net.IP(append([]byte(nil), v[24:28]...))
+			}
+		case typ == TLVTextAuth && length >= 8:
+			h.Auth = string(bytes.TrimRight(v[:8], "\x00"))
+		case typ == TLVMD5Auth && length >= 28:
+			h.SourceIP = net.IP(append([]byte(nil), v[4:8]...))
+			h.KeyID = binary.BigEndian.Uint32(v[8:12])
+			h.Digest = append([]byte(nil), v[12:28]...)
+		}
+	}
+	if h.Version != 2 {
+		return errors.New("HSRPv2 group state TLV missing")
+	}
+	return nil
+}
+
+// VerifyMD5 reports whether the MD5 TLV of a decoded HSRPv2 message matches key
+func (h *CustomHSRPLayer) VerifyMD5(key string) bool {
+	if len(h.Digest) != 16 {
+		return false
+	}
+	msg := append([]byte(nil), h.Contents...)
+	for rest := msg; len(rest) >= 2 && 2+int(rest[1]) <= len(rest); rest = rest[2+int(rest[1]):] {
+		if rest[0] == TLVMD5Auth && rest[1] >= 28 {
+			copy(rest[14:30], make([]byte, 16))
+		}
+	}
+	return bytes.Equal(MD5Digest(key, msg), h.Digest)
+}
+
+// seconds converts an HSRPv2 timer in milliseconds to whole seconds, rounding up
+func seconds(ms uint32) uint8 {
+	return uint8(min((ms+999)/1000, 255))
+}
+
+func (h *CustomHSRPLayer) CanDecode() gopacket.LayerClass {
+	return LayerTypeCustomHSRP
+}
+
+func (h *CustomHSRPLayer) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypeZero
+}
+
+func decodeHSRP(data []byte, p gopacket.PacketBuilder) error {
+	h := &CustomHSRPLayer{}
+	if err := h.DecodeFromBytes(data, p); err != nil {
+		return err
+	}
+	p.AddLayer(h)
+	return nil
+}
+
+// DecodeHSRP extracts an HSRP message from a captured packet.
+// It returns nil if the packet is not HSRP.
+func DecodeHSRP(packet gopacket.Packet) *CustomHSRPLayer {
+	udp, ok := packet.Layer(layers.LayerTypeUDP).(*layers.UDP)
+	if !ok || (udp.DstPort != Port && udp.DstPort != PortIPv6) {
+		return nil
+	}
+	h := &CustomHSRPLayer{}
+	if err := h.DecodeFromBytes(udp.Payload, gopacket.NilDecodeFeedback); err != nil {
+		return nil
+	}
+	return h
+}
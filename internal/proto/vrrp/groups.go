@@ -0,0 +1,188 @@
+package vrrp
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Router is a router heard advertising for a virtual router. Only the master advertises,
+// so every router listed was master at some point.
+type Router struct {
+	IP       net.IP
+	MAC      net.HardwareAddr
+	Priority uint8
+	LastSeen time.Time
+}
+
+// Group is a virtual router learned from the wire
+type Group struct {
+	Version   uint8 // 2 or 3
+	VRID      uint8
+	Addresses []net.IP
+	AuthType  uint8
+	Auth      string
+	Interval  time.Duration
+	// Master is the address of the router advertising last
+	Master  net.IP
+	Routers []Router
+}
+
+// IPv6 reports whether the group is an IPv6 virtual router
+func (g Group) IPv6() bool {
+	return len(g.Addresses) > 0 && g.Addresses[0].To4() == nil
+}
+
+// Upstream returns the real router that routes for the group once we are master: the current master
+func (g Group) Upstream() (Router, bool) {
+	for _, r := range g.Routers {
+		if r.IP.Equal(g.Master) {
+			return r, true
+		}
+	}
+	return Router{}, false
+}
+
+// Config returns the settings to advertise for the group with priority
+func (g Group) Config(priority uint8) Config {
+	return Config{
+		Version:   g.Version,
+		VRID:      g.VRID,
+		Addresses: g.Addresses,
+		Priority:  priority,
+		Interval:  g.Interval,
+		Auth:      g.Auth,
+	}
+}
+
+type groupKey struct {
+	version uint8
+	vrid    uint8
+	ipv6    bool
+}
+
+type group struct {
+	Group
+	routers map[string]*Router
+}
+
+// GroupTable keeps track of the virtual routers and masters seen on an interface. It is safe for concurrent use.
+type GroupTable struct {
+	mu        sync.Mutex
+	groups    map[groupKey]*group
+	ignoreIPs map[string]bool
+}
+
+// NewGroupTable creates an empty table
+func NewGroupTable() *GroupTable {
+	return &GroupTable{groups: make(map[groupKey]*group), ignoreIPs: make(map[string]bool)}
+}
+
+// IgnoreIP stops learning advertisements sent from ip, the source address of our own.
+// They are sent from the virtual MAC, so they cannot be told apart by MAC.
+func (t *GroupTable) IgnoreIP(ip net.IP) {
+	t.mu.Lock()
+	t.ignoreIPs[ip.String()] = true
+	t.mu.Unlock()
+}
+
+// Update records the VRRP advertisement carried by packet, if any. It reports whether the packet was VRRP.
+func (t *GroupTable) Update(packet gopacket.Packet) bool {
+	v := DecodeVRRP(packet)
+	if v == nil {
+		return false
+	}
+	var src net.IP
+	switch ip := packet.NetworkLayer().(type) {
+	case *layers.IPv4:
+		src = ip.SrcIP
+	case *layers.IPv6:
+		src = ip.SrcIP
+	}
+	now := packet.Metadata().Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.ignoreIPs[src.String()] {
+		return true
+	}
+	key := groupKey{version: v.Version, vrid: v.VRID, ipv6: src.To4() == nil}
+	g := t.groups[key]
+	if g == nil {
+		g = &group{Group: Group{Version: v.Version, VRID: v.VRID}, routers: make(map[string]*Router)}
+		t.groups[key] = g
+	}
+	g.Addresses = v.Addresses
+	g.AuthType = v.AuthType
+	g.Auth = v.Auth
+	g.Interval = v.Interval
+	g.Master = append(net.IP(nil), src...)
+
+	r := g.routers[src.String()]
+	if r == nil {
+		r = &Router{IP: g.Master}
+		g.routers[src.String()] = r
+	}
+	// Masters send from the virtual MAC, the real one is only known from other traffic
+	if eth, ok := packet.LinkLayer().(*layers.Ethernet); ok && !bytes.Equal(eth.SrcMAC, g.Config(0).VirtualMAC()) {
+		r.MAC = append(net.HardwareAddr(nil), eth.SrcMAC...)
+	}
+	r.Priority = v.Priority
+	r.LastSeen = now
+	return true
+}
+
+// Groups returns the learned groups sorted by version, address family and VRID, with their
+// routers sorted by IP. Routers silent for longer than three advertisement intervals are dropped.
+func (t *GroupTable) Groups() []Group {
+	now := time.Now()
+
+	t.mu.Lock()
+	out := make([]Group, 0, len(t.groups))
+	for _, g := range t.groups {
+		down := 3*g.Interval + time.Second
+		c := g.Group
+		c.Routers = nil
+		for key, r := range g.routers {
+			if now.Sub(r.LastSeen) > down {
+				delete(g.routers, key)
+				continue
+			}
+			c.Routers = append(c.Routers, *r)
+		}
+		sort.Slice(c.Routers, func(i, j int) bool { return bytes.Compare(c.Routers[i].IP, c.Routers[j].IP) < 0 })
+		out = append(out, c)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Version != out[j].Version {
+			return out[i].Version < out[j].Version
+		}
+		if out[i].IPv6() != out[j].IPv6() {
+			return !out[i].IPv6()
+		}
+		return out[i].VRID < out[j].VRID
+	})
+	return out
+}
+
+// RouterMAC returns the real MAC of the router at ip, when it was seen sending from it
+func (t *GroupTable) RouterMAC(ip net.IP) (net.HardwareAddr, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, g := range t.groups {
+		if r := g.routers[ip.String()]; r != nil && r.MAC != nil {
+			return r.MAC, true
+		}
+	}
+	return nil, false
+}
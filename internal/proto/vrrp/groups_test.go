@@ -0,0 +1,80 @@
+package vrrp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// masterAdvert builds an advertisement from the master at src
+func masterAdvert(t *testing.T, src net.IP, priority uint8, at time.Time) gopacket.Packet {
+	t.Helper()
+	cfg := Config{Version: 3, VRID: 10, Addresses: []net.IP{net.ParseIP("10.0.0.1")}, Priority: priority, Interval: time.Second, SourceIP: src}
+	data, err := CraftVRRP(ourMAC, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkt := gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)
+	pkt.Metadata().Timestamp = at
+	return pkt
+}
+
+func TestGroupTableLearnsMasters(t *testing.T) {
+	table := NewGroupTable()
+	table.IgnoreIP(net.ParseIP("10.0.0.66"))
+	now := time.Now()
+
+	table.Update(masterAdvert(t, net.ParseIP("10.0.0.2"), 110, now))
+	table.Update(masterAdvert(t, net.ParseIP("10.0.0.66"), 255, now))
+
+	groups := table.Groups()
+	if len(groups) != 1 {
+		t.Fatalf("Expected one group, got %d", len(groups))
+	}
+	g := groups[0]
+	if g.Version != 3 || g.VRID != 10 || len(g.Addresses) != 1 || g.Interval != time.Second || !g.Master.Equal(net.ParseIP("10.0.0.2")) {
+		t.Errorf("Unexpected group: %+v", g)
+	}
+	if len(g.Routers) != 1 {
+		t.Fatalf("Expected our own advertisements ignored, got %d routers", len(g.Routers))
+	}
+	if up, ok := g.Upstream(); !ok || up.Priority != 110 {
+		t.Errorf("Unexpected upstream: %+v", up)
+	}
+
+	// A backup taking over becomes the master
+	table.Update(masterAdvert(t, net.ParseIP("10.0.0.3"), 100, now.Add(time.Second)))
+	if g = table.Groups()[0]; !g.Master.Equal(net.ParseIP("10.0.0.3")) || len(g.Routers) != 2 {
+		t.Errorf("Expected 10.0.0.3 master, got %v with %d routers", g.Master, len(g.Routers))
+	}
+
+	cfg := g.Config(PriorityOwner)
+	if cfg.VRID != 10 || cfg.Priority != 255 || cfg.VirtualMAC().String() != "00:00:5e:00:01:0a" {
+		t.Errorf("Unexpected takeover config: %+v", cfg)
+	}
+}
+
+func TestGroupTableIgnoresOurIPv6Adverts(t *testing.T) {
+	table := NewGroupTable()
+	table.IgnoreIP(LinkLocal(ourMAC))
+	advert := func(src net.IP, priority uint8) gopacket.Packet {
+		cfg := Config{Version: 3, VRID: 10, Addresses: []net.IP{net.ParseIP("2001:db8::1")}, Priority: priority, Interval: time.Second, SourceIP: src}
+		data, err := CraftVRRP(ourMAC, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)
+	}
+
+	// Without a Source IP our adverts are sent from our link-local address
+	table.Update(advert(nil, 255))
+	table.Update(advert(net.ParseIP("fe80::2"), 110))
+
+	groups := table.Groups()
+	if len(groups) != 1 || len(groups[0].Routers) != 1 || !groups[0].Master.Equal(net.ParseIP("fe80::2")) {
+		t.Fatalf("Expected only fe80::2 learned, got %+v", groups)
+	}
+}
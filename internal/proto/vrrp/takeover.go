@@ -0,0 +1,57 @@
+package vrrp
+
+import (
+	"net"
+	"sync"
+)
+
+// Takeover advertises for a virtual router as its master, and advertises priority 0
+// when stopped so a backup takes over at once instead of waiting for the master
+// down interval. It is safe for concurrent use.
+type Takeover struct {
+	srcMAC net.HardwareAddr
+	cfg    Config
+
+	mu   sync.Mutex
+	sent uint64
+}
+
+// NewTakeover creates a takeover of the virtual router of cfg, sent from srcMAC
+func NewTakeover(srcMAC net.HardwareAddr, cfg Config) *Takeover {
+	return &Takeover{srcMAC: srcMAC, cfg: cfg}
+}
+
+// Packets returns the next advertisement
+func (t *Takeover) Packets() ([][]byte, error) {
+	pkt, err := CraftVRRP(t.srcMAC, t.cfg)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	t.sent++
+	t.mu.Unlock()
+	return [][]byte{pkt}, nil
+}
+
+// ResignPackets returns the priority 0 advertisement that hands the virtual router back
+func (t *Takeover) ResignPackets() ([][]byte, error) {
+	cfg := t.cfg
+	cfg.Priority = PriorityResign
+	pkt, err := CraftVRRP(t.srcMAC, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{pkt}, nil
+}
+
+// Config returns the virtual router settings the takeover advertises with
+func (t *Takeover) Config() Config {
+	return t.cfg
+}
+
+// Sent returns the number of advertisements sent
+func (t *Takeover) Sent() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sent
+}
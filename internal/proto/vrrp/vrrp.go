@@ -0,0 +1,352 @@
+package vrrp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Multicast destinations of VRRP over IPv4 and IPv6
+var (
+	GroupIPv4 = net.IPv4(224, 0, 0, 18)
+	GroupIPv6 = net.ParseIP("ff02::12")
+)
+
+// TypeAdvertisement is the only VRRP message type
+const TypeAdvertisement = 1
+
+// Authentication types of VRRPv2
+const (
+	AuthNone      = 0
+	AuthPlaintext = 1
+	AuthAH        = 2
+)
+
+// PriorityOwner is the priority of the router owning the virtual addresses,
+// PriorityResign the one a master advertises when it stops
+const (
+	PriorityOwner  = 255
+	PriorityResign = 0
+)
+
+// Config describes the virtual router we advertise for
+type Config struct {
+	// Version is 2 or 3, 0 means 3. IPv6 needs version 3.
+	Version   uint8
+	VRID      uint8
+	Addresses []net.IP
+	Priority  uint8
+	// Interval is the advertisement interval, whole seconds for VRRPv2 and centiseconds for VRRPv3
+	Interval time.Duration
+	// Auth is the VRRPv2 plaintext authentication, at most 8 bytes
+	Auth string
+	// SourceIP is the interface address advertisements are sent from. IPv6 defaults to our link-local address.
+	SourceIP net.IP
+}
+
+// IPv6 reports whether the virtual router is an IPv6 one
+func (c Config) IPv6() bool {
+	return len(c.Addresses) > 0 && c.Addresses[0].To4() == nil
+}
+
+// VirtualMAC returns the MAC of the virtual router, 00:00:5e:00:01:xx for IPv4 and 00:00:5e:00:02:xx for IPv6
+func (c Config) VirtualMAC() net.HardwareAddr {
+	if c.IPv6() {
+		return net.HardwareAddr{0x00, 0x00, 0x5e, 0x00, 0x02, c.VRID}
+	}
+	return net.HardwareAddr{0x00, 0x00, 0x5e, 0x00, 0x01, c.VRID}
+}
+
+// CraftVRRP creates an advertisement for the virtual router of cfg. Like a real master,
+// it is sent from the virtual MAC so switches learn it on our port.
+func CraftVRRP(srcMAC net.HardwareAddr, cfg Config) ([]byte, error) {
+	if len(cfg.Addresses) == 0 {
+		return nil, errors.New("no virtual address")
+	}
+	vrrp := &CustomVRRPLayer{
+		Version:   cfg.Version,
+		Type:      TypeAdvertisement,
+		VRID:      cfg.VRID,
+		Priority:  cfg.Priority,
+		Interval:  cfg.Interval,
+		Addresses: cfg.Addresses,
+	}
+	if vrrp.Version == 0 {
+		vrrp.Version = 3
+	}
+	if cfg.IPv6() && vrrp.Version != 3 {
+		return nil, errors.New("IPv6 virtual routers need VRRPv3")
+	}
+	if vrrp.Version == 2 && cfg.Auth != "" {
+		vrrp.AuthType = AuthPlaintext
+		vrrp.Auth = cfg.Auth
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{
+		FixLengths:       true,
+		ComputeChecksums: true,
+	}
+
+	var err error
+	if cfg.IPv6() {
+		src := cfg.SourceIP
+		if src == nil || src.To4() != nil {
+			src = LinkLocal(srcMAC)
+		}
+		eth := layers.Ethernet{
+			SrcMAC:       cfg.VirtualMAC(),
+			DstMAC:       net.HardwareAddr{0x33, 0x33, 0x00, 0x00, 0x00, 0x12},
+			EthernetType: layers.EthernetTypeIPv6,
+		}
+		ip := layers.IPv6{
+			Version:    6,
+			HopLimit:   255,
+			SrcIP:      src,
+			DstIP:      GroupIPv6,
+			NextHeader: layers.IPProtocolVRRP,
+		}
+		vrrp.SetNetworkLayerForChecksum(&ip)
+		err = gopacket.SerializeLayers(buf, opts, &eth, &ip, vrrp)
+	} else {
+		if cfg.SourceIP.To4() == nil {
+			return nil, errors.New("no IPv4 source address")
+		}
+		eth := layers.Ethernet{
+			SrcMAC:       cfg.VirtualMAC(),
+			DstMAC:       net.HardwareAddr{0x01, 0x00, 0x5e, 0x00, 0x00, 0x12},
+			EthernetType: layers.EthernetTypeIPv4,
+		}
+		ip := layers.IPv4{
+			Version:  4,
+			TTL:      255,
+			SrcIP:    cfg.SourceIP.To4(),
+			DstIP:    GroupIPv4,
+			Protocol: layers.IPProtocolVRRP,
+		}
+		vrrp.SetNetworkLayerForChecksum(&ip)
+		err = gopacket.SerializeLayers(buf, opts, &eth, &ip, vrrp)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// LinkLocal returns the EUI-64 IPv6 link-local address of mac, the source of IPv6
+// adverts sent without a Source IP
+func LinkLocal(mac net.HardwareAddr) net.IP {
+	ip := make(net.IP, net.IPv6len)
+	ip[0], ip[1] = 0xfe, 0x80
+	if len(mac) == 6 {
+		ip[8], ip[9], ip[10] = mac[0]^0x02, mac[1], mac[2]
+		ip[11], ip[12] = 0xff, 0xfe
+		ip[13], ip[14], ip[15] = mac[3], mac[4], mac[5]
+	}
+	return ip
+}
+
+// CustomVRRPLayer is a VRRPv2 or VRRPv3 advertisement. gopacket only knows VRRPv2 over IPv4.
+type CustomVRRPLayer struct {
+	layers.BaseLayer
+	Version   uint8
+	Type      uint8
+	VRID      uint8
+	Priority  uint8
+	Interval  time.Duration
+	Addresses []net.IP
+	Checksum  uint16
+	// VRRPv2 only
+	AuthType uint8
+	Auth     string
+
+	pseudo gopacket.NetworkLayer
+}
+
+var LayerTypeCustomVRRP = gopacket.RegisterLayerType(2007, gopacket.LayerTypeMetadata{Name: "CustomVRRP", Decoder: gopacket.DecodeFunc(decodeVRRP)})
+
+func (v *CustomVRRPLayer) LayerType() gopacket.LayerType {
+	return LayerTypeCustomVRRP
+}
+
+// SetNetworkLayerForChecksum sets the IP header whose pseudo-header the VRRPv3 checksum covers
+func (v *CustomVRRPLayer) SetNetworkLayerForChecksum(l gopacket.NetworkLayer) {
+	v.pseudo = l
+}
+
+// SerializeTo writes the advertisement and its checksum
+func (v *CustomVRRPLayer) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	addrLen := 4
+	if len(v.Addresses) > 0 && v.Addresses[0].To4() == nil {
+		addrLen = 16
+	}
+	size := 8 + addrLen*len(v.Addresses)
+	if v.Version == 2 {
+		size += 8
+	}
+	payload, err := b.PrependBytes(size)
+	if err != nil {
+		return err
+	}
+	for i := range payload {
+		payload[i] = 0
+	}
+
+	payload[0] = v.Version<<4 | v.Type&0x0f
+	payload[1] = v.VRID
+	payload[2] = v.Priority
+	payload[3] = byte(len(v.Addresses))
+	if v.Version == 2 {
+		payload[4] = v.AuthType
+		payload[5] = byte(v.Interval / time.Second)
+	} else {
+		binary.BigEndian.PutUint16(payload[4:6], uint16(v.Interval/(10*time.Millisecond))&0x0fff)
+	}
+	for i, addr := range v.Addresses {
+		if addrLen == 4 {
+			copy(payload[8+4*i:], addr.To4())
+		} else {
+			copy(payload[8+16*i:], addr.To16())
+		}
+	}
+	if v.Version == 2 {
+		copy(payload[size-8:], v.Auth)
+	}
+
+	if opts.ComputeChecksums {
+		v.Checksum = checksum(payload, v.pseudoHeader(len(payload)))
+		binary.BigEndian.PutUint16(payload[6:8], v.Checksum)
+	}
+	return nil
+}
+
+// pseudoHeader returns the pseudo-header VRRPv3 checksums cover, nil for VRRPv2
+func (v *CustomVRRPLayer) pseudoHeader(length int) []byte {
+	if v.Version == 2 {
+		return nil
+	}
+	switch ip := v.pseudo.(type) {
+	case *layers.IPv4:
+		h := make([]byte, 12)
+		copy(h[0:4], ip.SrcIP.To4())
+		copy(h[4:8], ip.DstIP.To4())
+		h[9] = byte(layers.IPProtocolVRRP)
+		binary.BigEndian.PutUint16(h[10:12], uint16(length))
+		return h
+	case *layers.IPv6:
+		h := make([]byte, 40)
+		copy(h[0:16], ip.SrcIP.To16())
+		copy(h[16:32], ip.DstIP.To16())
+		binary.BigEndian.PutUint32(h[32:36], uint32(length))
+		h[39] = byte(layers.IPProtocolVRRP)
+		return h
+	}
+	return nil
+}
+
+// checksum is the Internet checksum of data with its checksum field zeroed, preceded by pseudo
+func checksum(data, pseudo []byte) uint16 {
+	var sum uint32
+	add := func(b []byte) {
+		for i := 0; i+1 < len(b); i += 2 {
+			sum += uint32(b[i])<<8 | uint32(b[i+1])
+		}
+		if len(b)%2 == 1 {
+			sum += uint32(b[len(b)-1]) << 8
+		}
+	}
+	add(pseudo)
+	add(data[:6])
+	add(data[8:])
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return ^uint16(sum)
+}
+
+// decode decodes a VRRPv2 or VRRPv3 advertisement, IPv6 addresses when ipv6 is set
+func (v *CustomVRRPLayer) decode(data []byte, ipv6 bool) error {
+	if len(data) < 8 {
+		return errors.New("VRRP message too short")
+	}
+	*v = CustomVRRPLayer{BaseLayer: layers.BaseLayer{Contents: data}}
+	v.Version = data[0] >> 4
+	v.Type = data[0] & 0x0f
+	v.VRID = data[1]
+	v.Priority = data[2]
+	count := int(data[3])
+	v.Checksum = binary.BigEndian.Uint16(data[6:8])
+
+	addrLen := 4
+	switch v.Version {
+	case 2:
+		v.AuthType = data[4]
+		v.Interval = time.Duration(data[5]) * time.Second
+	case 3:
+		v.Interval = time.Duration(binary.BigEndian.Uint16(data[4:6])&0x0fff) * 10 * time.Millisecond
+		if ipv6 {
+			addrLen = 16
+		}
+	default:
+		return errors.New("unknown VRRP version")
+	}
+	if len(data) < 8+addrLen*count {
+		return errors.New("VRRP address list truncated")
+	}
+	for i := 0; i < count; i++ {
+		v.Addresses = append(v.Addresses, net.IP(append([]byte(nil), data[8+addrLen*i:8+addrLen*(i+1)]...)))
+	}
+	if auth := data[8+addrLen*count:]; v.Version == 2 && v.AuthType == AuthPlaintext && len(auth) >= 8 {
+		v.Auth = string(bytes.TrimRight(auth[:8], "\x00"))
+	}
+	return nil
+}
+
+// DecodeFromBytes decodes an advertisement carried over IPv4
+func (v *CustomVRRPLayer) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	return v.decode(data, false)
+}
+
+func (v *CustomVRRPLayer) CanDecode() gopacket.LayerClass {
+	return LayerTypeCustomVRRP
+}
+
+func (v *CustomVRRPLayer) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeVRRP(data []byte, p gopacket.PacketBuilder) error {
+	v := &CustomVRRPLayer{}
+	if err := v.DecodeFromBytes(data, p); err != nil {
+		return err
+	}
+	p.AddLayer(v)
+	return nil
+}
+
+// DecodeVRRP returns the VRRP advertisement carried by packet, or nil.
+// gopacket's own VRRP layer does not handle VRRPv3, so the IP payload is decoded here.
+func DecodeVRRP(packet gopacket.Packet) *CustomVRRPLayer {
+	v := &CustomVRRPLayer{}
+	switch ip := packet.NetworkLayer().(type) {
+	case *layers.IPv4:
+		if ip.Protocol != layers.IPProtocolVRRP || v.decode(ip.Payload, false) != nil {
+			return nil
+		}
+	case *layers.IPv6:
+		if ip.NextHeader != layers.IPProtocolVRRP || v.decode(ip.Payload, true) != nil {
+			return nil
+		}
+	default:
+		return nil
+	}
+	if v.Type != TypeAdvertisement {
+		return nil
+	}
+	return v
+}
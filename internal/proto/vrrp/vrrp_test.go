@@ -0,0 +1,104 @@
+package vrrp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+var ourMAC = net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+
+func TestCraftVRRPv2(t *testing.T) {
+	cfg := Config{Version: 2, VRID: 7, Addresses: []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.254")},
+		Priority: PriorityOwner, Interval: time.Second, Auth: "s3cret", SourceIP: net.ParseIP("10.0.0.66")}
+	data, err := CraftVRRP(ourMAC, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkt := gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)
+	if eth := pkt.LinkLayer().(*layers.Ethernet); eth.SrcMAC.String() != "00:00:5e:00:01:07" || eth.DstMAC.String() != "01:00:5e:00:00:12" {
+		t.Errorf("Expected the virtual MAC to multicast, got %v to %v", eth.SrcMAC, eth.DstMAC)
+	}
+	ip := pkt.NetworkLayer().(*layers.IPv4)
+	if !ip.SrcIP.Equal(cfg.SourceIP) || !ip.DstIP.Equal(GroupIPv4) || ip.TTL != 255 {
+		t.Errorf("Unexpected IP header %v -> %v ttl %d", ip.SrcIP, ip.DstIP, ip.TTL)
+	}
+
+	// gopacket's own VRRPv2 decoder agrees with the encoding
+	ref, ok := pkt.Layer(layers.LayerTypeVRRP).(*layers.VRRPv2)
+	if !ok {
+		t.Fatal("gopacket did not decode the VRRPv2 advertisement")
+	}
+	v := DecodeVRRP(pkt)
+	if v == nil {
+		t.Fatal("VRRP not decoded")
+	}
+	if ref.Checksum != v.Checksum || ref.VirtualRtrID != 7 || ref.AdverInt != 1 || len(ref.IPAddress) != 2 {
+		t.Errorf("gopacket decoded %+v", ref)
+	}
+	if v.Version != 2 || v.VRID != 7 || v.Priority != 255 || v.Interval != time.Second ||
+		len(v.Addresses) != 2 || !v.Addresses[1].Equal(net.ParseIP("10.0.0.254")) || v.AuthType != AuthPlaintext || v.Auth != "s3cret" {
+		t.Errorf("Unexpected advertisement: %+v", v)
+	}
+}
+
+func TestCraftVRRPv3IPv6(t *testing.T) {
+	cfg := Config{Version: 3, VRID: 42, Addresses: []net.IP{net.ParseIP("fe80::1"), net.ParseIP("2001:db8::1")},
+		Priority: PriorityOwner, Interval: 50 * time.Millisecond}
+	data, err := CraftVRRP(ourMAC, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkt := gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)
+	if eth := pkt.LinkLayer().(*layers.Ethernet); eth.SrcMAC.String() != "00:00:5e:00:02:2a" {
+		t.Errorf("Expected the IPv6 virtual MAC, got %v", eth.SrcMAC)
+	}
+	ip6 := pkt.NetworkLayer().(*layers.IPv6)
+	if !ip6.DstIP.Equal(GroupIPv6) || !ip6.SrcIP.IsLinkLocalUnicast() {
+		t.Errorf("Unexpected IPv6 header %v -> %v", ip6.SrcIP, ip6.DstIP)
+	}
+	v := DecodeVRRP(pkt)
+	if v == nil {
+		t.Fatal("VRRPv3 not decoded")
+	}
+	if v.Version != 3 || v.VRID != 42 || v.Interval != 50*time.Millisecond || len(v.Addresses) != 2 || !v.Addresses[1].Equal(net.ParseIP("2001:db8::1")) {
+		t.Errorf("Unexpected advertisement: %+v", v)
+	}
+	v.SetNetworkLayerForChecksum(ip6)
+	if sum := checksum(ip6.Payload, v.pseudoHeader(len(ip6.Payload))); sum != v.Checksum {
+		t.Errorf("Checksum %#04x, expected %#04x", v.Checksum, sum)
+	}
+
+	cfg.Version = 2
+	if _, err := CraftVRRP(ourMAC, cfg); err == nil {
+		t.Error("Crafted an IPv6 virtual router over VRRPv2")
+	}
+}
+
+func TestTakeoverResignsWithPriorityZero(t *testing.T) {
+	cfg := Config{VRID: 1, Addresses: []net.IP{net.ParseIP("10.0.0.1")}, Priority: PriorityOwner, Interval: time.Second, SourceIP: net.ParseIP("10.0.0.66")}
+	takeover := NewTakeover(ourMAC, cfg)
+
+	adverts, _ := takeover.Packets()
+	resign, _ := takeover.ResignPackets()
+	if len(adverts) != 1 || len(resign) != 1 {
+		t.Fatalf("Expected one advertisement each, got %d and %d", len(adverts), len(resign))
+	}
+	decode := func(data []byte) *CustomVRRPLayer {
+		return DecodeVRRP(gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default))
+	}
+	if v := decode(adverts[0]); v == nil || v.Version != 3 || v.Priority != PriorityOwner {
+		t.Errorf("Unexpected advertisement: %+v", v)
+	}
+	if v := decode(resign[0]); v == nil || v.Priority != PriorityResign {
+		t.Errorf("Unexpected resign: %+v", v)
+	}
+	if takeover.Sent() != 1 {
+		t.Errorf("Sent = %d", takeover.Sent())
+	}
+}
@@ -0,0 +1,121 @@
+package vtp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// DecodeFromBytes decodes a VTP Summary, Subset or Advertisement Request that follows the SNAP header
+func (v *CustomVTPLayer) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if len(data) < 4+domainLen {
+		df.SetTruncated()
+		return errors.New("VTP header too short")
+	}
+	*v = CustomVTPLayer{BaseLayer: layers.BaseLayer{Contents: data}}
+	v.Version = data[0]
+	v.Code = data[1]
+	nameLen := min(int(data[3]), domainLen)
+	v.Domain = string(data[4 : 4+nameLen])
+
+	rest := data[4+domainLen:]
+	switch v.Code {
+	case CodeSummary:
+		v.Followers = data[2]
+		if len(rest) < 4+4+12+16 {
+			df.SetTruncated()
+			return errors.New("VTP summary truncated")
+		}
+		v.Revision = binary.BigEndian.Uint32(rest)
+		v.Updater = net.IP(append([]byte(nil), rest[4:8]...))
+		v.Timestamp = string(bytes.TrimRight(rest[8:20], "\x00"))
+		copy(v.MD5[:], rest[20:36])
+	case CodeSubset:
+		v.Sequence = data[2]
+		if len(rest) < 4 {
+			df.SetTruncated()
+			return errors.New("VTP subset truncated")
+		}
+		v.Revision = binary.BigEndian.Uint32(rest)
+		vlans, err := decodeVLANs(rest[4:])
+		if err != nil {
+			return err
+		}
+		v.VLANs = vlans
+	case CodeAdvertRequest:
+		if len(rest) >= 2 {
+			v.StartValue = binary.BigEndian.Uint16(rest)
+		}
+	default:
+		return errors.New("unsupported VTP code")
+	}
+	return nil
+}
+
+// decodeVLANs parses the VLAN information fields of a Subset
+func decodeVLANs(data []byte) ([]VLAN, error) {
+	var vlans []VLAN
+	for len(data) > 0 {
+		length := int(data[0])
+		if length == 0 {
+			// Ethernet padding
+			break
+		}
+		if length < 12 || length > len(data) {
+			return nil, errors.New("VTP VLAN info length invalid")
+		}
+		info := data[:length]
+		data = data[length:]
+
+		nameLen := min(int(info[3]), length-12)
+		vlans = append(vlans, VLAN{
+			Status: info[1],
+			Type:   info[2],
+			ID:     binary.BigEndian.Uint16(info[4:]),
+			MTU:    binary.BigEndian.Uint16(info[6:]),
+			SAID:   binary.BigEndian.Uint32(info[8:]),
+			Name:   string(info[12 : 12+nameLen]),
+		})
+	}
+	return vlans, nil
+}
+
+func (v *CustomVTPLayer) CanDecode() gopacket.LayerClass {
+	return LayerTypeCustomVTP
+}
+
+func (v *CustomVTPLayer) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypeZero
+}
+
+func decodeVTP(data []byte, p gopacket.PacketBuilder) error {
+	v := &CustomVTPLayer{}
+	if err := v.DecodeFromBytes(data, p); err != nil {
+		return err
+	}
+	p.AddLayer(v)
+	return nil
+}
+
+// DecodeVTP extracts a VTP PDU from a captured Ethernet frame.
+// It returns nil if the frame is not VTP.
+func DecodeVTP(packet gopacket.Packet) *CustomVTPLayer {
+	snapLayer := packet.Layer(layers.LayerTypeSNAP)
+	if snapLayer == nil {
+		return nil
+	}
+	snap := snapLayer.(*layers.SNAP)
+	if !bytes.Equal(snap.OrganizationalCode, ciscoOUI) || snap.Type != snapTypeVTP {
+		return nil
+	}
+
+	v := &CustomVTPLayer{}
+	if err := v.DecodeFromBytes(snap.Payload, gopacket.NilDecodeFeedback); err != nil {
+		return nil
+	}
+	return v
+}
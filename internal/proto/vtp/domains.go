@@ -0,0 +1,121 @@
+package vtp
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Domain is a VTP domain learned from the wire
+type Domain struct {
+	Name     string
+	Version  byte
+	Revision uint32
+	Updater  net.IP
+	// SrcMAC is the switch that sent the last Summary
+	SrcMAC   net.HardwareAddr
+	VLANs    []VLAN
+	LastSeen time.Time
+}
+
+// DomainTable keeps track of the VTP domains advertised on an interface. It is safe for concurrent use.
+type DomainTable struct {
+	mu      sync.Mutex
+	domains map[string]*Domain
+}
+
+// NewDomainTable creates an empty domain table
+func NewDomainTable() *DomainTable {
+	return &DomainTable{domains: make(map[string]*Domain)}
+}
+
+// Update records the VTP message carried by packet, if any. It reports whether the packet was VTP.
+func (t *DomainTable) Update(packet gopacket.Packet) bool {
+	v := DecodeVTP(packet)
+	if v == nil {
+		return false
+	}
+
+	now := packet.Metadata().Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	d, ok := t.domains[v.Domain]
+	if !ok {
+		d = &Domain{Name: v.Domain}
+		t.domains[v.Domain] = d
+	}
+	d.Version = v.Version
+	d.LastSeen = now
+
+	switch v.Code {
+	case CodeSummary:
+		d.Revision = v.Revision
+		d.Updater = v.Updater
+		if eth, ok := packet.LinkLayer().(*layers.Ethernet); ok {
+			d.SrcMAC = eth.SrcMAC
+		}
+	case CodeSubset:
+		d.Revision = v.Revision
+		if v.Sequence <= 1 {
+			d.VLANs = nil
+		}
+		d.VLANs = mergeVLANs(d.VLANs, v.VLANs)
+	}
+	return true
+}
+
+// Domains returns the known domains sorted by name
+func (t *DomainTable) Domains() []Domain {
+	t.mu.Lock()
+	out := make([]Domain, 0, len(t.domains))
+	for _, d := range t.domains {
+		c := *d
+		c.VLANs = append([]VLAN(nil), d.VLANs...)
+		out = append(out, c)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// mergeVLANs adds vlans to list, replacing entries with the same ID, and keeps it sorted by ID
+func mergeVLANs(list, vlans []VLAN) []VLAN {
+	byID := make(map[uint16]VLAN, len(list)+len(vlans))
+	for _, v := range list {
+		byID[v.ID] = v
+	}
+	for _, v := range vlans {
+		byID[v.ID] = v
+	}
+	out := make([]VLAN, 0, len(byID))
+	for _, v := range byID {
+		out = append(out, v)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// AddVLAN returns vlans with v added or replaced
+func AddVLAN(vlans []VLAN, v VLAN) []VLAN {
+	return mergeVLANs(vlans, []VLAN{v})
+}
+
+// DeleteVLAN returns vlans without the VLAN id
+func DeleteVLAN(vlans []VLAN, id uint16) []VLAN {
+	var out []VLAN
+	for _, v := range vlans {
+		if v.ID != id {
+			out = append(out, v)
+		}
+	}
+	return out
+}
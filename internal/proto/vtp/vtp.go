@@ -0,0 +1,325 @@
+package vtp
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// VTP message codes
+const (
+	CodeSummary       = 0x01
+	CodeSubset        = 0x02
+	CodeAdvertRequest = 0x03
+)
+
+// VLAN types
+const (
+	VLANTypeEthernet = 0x01
+	VLANTypeFDDI     = 0x02
+	VLANTypeTrCRF    = 0x03
+	VLANTypeFDDINet  = 0x04
+	VLANTypeTrBRF    = 0x05
+)
+
+// domainLen is the fixed size of the domain name field
+const domainLen = 32
+
+// snapTypeVTP is the SNAP protocol ID of VTP
+const snapTypeVTP = 0x2003
+
+// ciscoOUI is the SNAP organizational code VTP is sent under
+var ciscoOUI = []byte{0x00, 0x00, 0x0c}
+
+// VLAN is one VLAN information field of a Subset advertisement
+type VLAN struct {
+	ID     uint16
+	Name   string
+	Type   byte
+	Status byte
+	MTU    uint16
+	// SAID is the 802.10 index, by convention 100000 + ID
+	SAID uint32
+}
+
+// DefaultVLANs are the VLANs every Catalyst domain carries
+func DefaultVLANs() []VLAN {
+	return []VLAN{
+		NewVLAN(1, "default"),
+		{ID: 1002, Name: "fddi-default", Type: VLANTypeFDDI, MTU: 1500, SAID: 101002},
+		{ID: 1003, Name: "token-ring-default", Type: VLANTypeTrCRF, MTU: 1500, SAID: 101003},
+		{ID: 1004, Name: "fddinet-default", Type: VLANTypeFDDINet, MTU: 1500, SAID: 101004},
+		{ID: 1005, Name: "trnet-default", Type: VLANTypeTrBRF, MTU: 1500, SAID: 101005},
+	}
+}
+
+// NewVLAN returns an active Ethernet VLAN with the usual MTU and SAID
+func NewVLAN(id uint16, name string) VLAN {
+	return VLAN{ID: id, Name: name, Type: VLANTypeEthernet, MTU: 1500, SAID: 100000 + uint32(id)}
+}
+
+// CustomVTPLayer implements gopacket.SerializableLayer and gopacket.DecodingLayer for
+// the VTP PDU that follows the SNAP header. Which fields are used depends on Code.
+type CustomVTPLayer struct {
+	layers.BaseLayer
+	Version byte
+	Code    byte
+	// Followers is the number of Subset messages after a Summary. Sequence numbers Subsets from 1.
+	Followers byte
+	Sequence  byte
+	Domain    string
+	Revision  uint32
+
+	// Summary fields
+	Updater   net.IP
+	Timestamp string
+	MD5       [16]byte
+
+	// Subset fields
+	VLANs []VLAN
+
+	// Advertisement Request field
+	StartValue uint16
+}
+
+// LayerTypeCustomVTP registers our custom layer
+var LayerTypeCustomVTP = gopacket.RegisterLayerType(2005, gopacket.LayerTypeMetadata{Name: "CustomVTP", Decoder: gopacket.DecodeFunc(decodeVTP)})
+
+func (v *CustomVTPLayer) LayerType() gopacket.LayerType {
+	return LayerTypeCustomVTP
+}
+
+// SerializeTo writes the VTP PDU
+func (v *CustomVTPLayer) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	var body []byte
+	switch v.Code {
+	case CodeSummary:
+		body = v.summaryFields()
+		body = append(body, v.MD5[:]...)
+	case CodeSubset:
+		body = v.header(v.Sequence)
+		body = binary.BigEndian.AppendUint32(body, v.Revision)
+		body = append(body, encodeVLANs(v.VLANs)...)
+	case CodeAdvertRequest:
+		body = v.header(0)
+		body = binary.BigEndian.AppendUint16(body, v.StartValue)
+	default:
+		return errors.New("unsupported VTP code")
+	}
+
+	bytes, err := b.PrependBytes(len(body))
+	if err != nil {
+		return err
+	}
+	copy(bytes, body)
+	return nil
+}
+
+// header returns version, code, the code specific byte and the padded domain
+func (v *CustomVTPLayer) header(third byte) []byte {
+	h := make([]byte, 4+domainLen)
+	h[0] = v.Version
+	h[1] = v.Code
+	h[2] = third
+	h[3] = byte(copy(h[4:], v.Domain))
+	return h
+}
+
+// summaryFields returns a Summary up to and including the timestamp, which is what the MD5 digest covers
+func (v *CustomVTPLayer) summaryFields() []byte {
+	body := v.header(v.Followers)
+	body = binary.BigEndian.AppendUint32(body, v.Revision)
+	updater := make([]byte, 4)
+	copy(updater, v.Updater.To4())
+	body = append(body, updater...)
+	ts := make([]byte, 12)
+	copy(ts, v.Timestamp)
+	return append(body, ts...)
+}
+
+// encodeVLANs builds the VLAN information fields of a Subset
+func encodeVLANs(vlans []VLAN) []byte {
+	var out []byte
+	for _, vlan := range vlans {
+		name := []byte(vlan.Name)
+		info := make([]byte, vlanInfoLen(vlan))
+		info[0] = byte(len(info))
+		info[1] = vlan.Status
+		info[2] = vlan.Type
+		info[3] = byte(len(name))
+		binary.BigEndian.PutUint16(info[4:], vlan.ID)
+		binary.BigEndian.PutUint16(info[6:], vlan.MTU)
+		binary.BigEndian.PutUint32(info[8:], vlan.SAID)
+		copy(info[12:], name)
+		out = append(out, info...)
+	}
+	return out
+}
+
+// Secret derives the 16 byte VTP secret from a password, as "show vtp password" prints it.
+// A 32 digit hex string is taken as the secret itself, like "vtp password <secret> hidden".
+func Secret(password string) []byte {
+	if raw, err := hex.DecodeString(password); err == nil && len(raw) == md5.Size {
+		return raw
+	}
+	if password == "" {
+		return nil
+	}
+
+	block := make([]byte, 64)
+	for i := range block {
+		block[i] = password[i%len(password)]
+	}
+	h := md5.New()
+	for i := 0; i < 1563; i++ {
+		h.Write(block)
+	}
+	return h.Sum(nil)
+}
+
+// Digest computes the MD5 digest of a Summary: the secret, the summary fields, the
+// VLAN information of its Subsets and the secret again.
+func Digest(secret []byte, summary *CustomVTPLayer, vlans []VLAN) [16]byte {
+	h := md5.New()
+	h.Write(secret)
+	h.Write(summary.summaryFields())
+	h.Write(encodeVLANs(vlans))
+	h.Write(secret)
+
+	var sum [16]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// Advertisement is a Summary with the Subset messages that follow it
+type Advertisement struct {
+	Version  byte
+	Domain   string
+	Revision uint32
+	Updater  net.IP
+	VLANs    []VLAN
+	// Password, when set, signs the Summary with an MD5 digest
+	Password string
+}
+
+// maxSubsetVLANBytes is what is left of the 1500 byte Ethernet payload for the VLAN
+// information of a Subset, after LLC/SNAP (8) and the Subset header and revision (40)
+const maxSubsetVLANBytes = 1500 - 8 - 4 - domainLen - 4
+
+// maxVLANNameLen is the longest VLAN name VTP carries
+const maxVLANNameLen = 32
+
+// vlanInfoLen is the encoded length of a VLAN information field
+func vlanInfoLen(vlan VLAN) int {
+	return 12 + (len(vlan.Name)+3)&^3
+}
+
+// CraftAdvertisement returns the Summary frame followed by the Subset frames announcing adv
+func CraftAdvertisement(srcMAC net.HardwareAddr, adv Advertisement) ([][]byte, error) {
+	version := adv.Version
+	if version == 0 {
+		version = 2
+	}
+	if len(adv.Domain) > domainLen {
+		return nil, errors.New("VTP domain longer than 32 characters")
+	}
+
+	var subsets []*CustomVTPLayer
+	for i := 0; i < len(adv.VLANs); {
+		end, size := i, 0
+		for ; end < len(adv.VLANs); end++ {
+			vlan := adv.VLANs[end]
+			if len(vlan.Name) > maxVLANNameLen {
+				return nil, fmt.Errorf("VLAN %d name longer than %d characters", vlan.ID, maxVLANNameLen)
+			}
+			if size+vlanInfoLen(vlan) > maxSubsetVLANBytes {
+				break
+			}
+			size += vlanInfoLen(vlan)
+		}
+		subsets = append(subsets, &CustomVTPLayer{
+			Version:  version,
+			Code:     CodeSubset,
+			Sequence: byte(len(subsets) + 1),
+			Domain:   adv.Domain,
+			Revision: adv.Revision,
+			VLANs:    adv.VLANs[i:end],
+		})
+		i = end
+	}
+
+	updater := adv.Updater
+	if updater == nil {
+		updater = net.IPv4zero
+	}
+	summary := &CustomVTPLayer{
+		Version:   version,
+		Code:      CodeSummary,
+		Followers: byte(len(subsets)),
+		Domain:    adv.Domain,
+		Revision:  adv.Revision,
+		Updater:   updater,
+		Timestamp: time.Now().UTC().Format("060102150405"),
+	}
+	if secret := Secret(adv.Password); secret != nil {
+		summary.MD5 = Digest(secret, summary, adv.VLANs)
+	}
+
+	frames := make([][]byte, 0, 1+len(subsets))
+	for _, msg := range append([]*CustomVTPLayer{summary}, subsets...) {
+		frame, err := CraftVTPFrame(srcMAC, msg)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+// CraftAdvertRequest creates an Advertisement Request asking the domain's servers for their VLANs
+func CraftAdvertRequest(srcMAC net.HardwareAddr, version byte, domain string) ([]byte, error) {
+	if version == 0 {
+		version = 2
+	}
+	return CraftVTPFrame(srcMAC, &CustomVTPLayer{Version: version, Code: CodeAdvertRequest, Domain: domain})
+}
+
+// CraftVTPFrame wraps a VTP PDU in Ethernet/LLC/SNAP headers addressed to the Cisco multicast MAC
+func CraftVTPFrame(srcMAC net.HardwareAddr, vtp *CustomVTPLayer) ([]byte, error) {
+	eth := layers.Ethernet{
+		SrcMAC:       srcMAC,
+		DstMAC:       net.HardwareAddr{0x01, 0x00, 0x0c, 0xcc, 0xcc, 0xcc},
+		EthernetType: layers.EthernetTypeLLC,
+	}
+
+	llc := layers.LLC{
+		DSAP:    0xaa,
+		SSAP:    0xaa,
+		Control: 0x03,
+	}
+
+	snap := layers.SNAP{
+		OrganizationalCode: ciscoOUI,
+		Type:               snapTypeVTP,
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{
+		FixLengths:       true,
+		ComputeChecksums: true,
+	}
+
+	err := gopacket.SerializeLayers(buf, opts, &eth, &llc, &snap, vtp)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
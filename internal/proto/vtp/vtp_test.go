@@ -0,0 +1,151 @@
+package vtp
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func decode(t *testing.T, frame []byte) (gopacket.Packet, *CustomVTPLayer) {
+	t.Helper()
+	packet := gopacket.NewPacket(frame, layers.LinkTypeEthernet, gopacket.Default)
+	v := DecodeVTP(packet)
+	if v == nil {
+		t.Fatal("DecodeVTP returned nil")
+	}
+	return packet, v
+}
+
+func TestCraftAdvertisement(t *testing.T) {
+	mac, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+	vlans := AddVLAN(DefaultVLANs(), NewVLAN(666, "pwned"))
+
+	frames, err := CraftAdvertisement(mac, Advertisement{
+		Version:  2,
+		Domain:   "LAB",
+		Revision: 42,
+		Updater:  net.ParseIP("10.0.0.5"),
+		VLANs:    vlans,
+	})
+	if err != nil {
+		t.Fatalf("Failed to craft VTP advertisement: %v", err)
+	}
+	if len(frames) != 2 {
+		t.Fatalf("Expected a Summary and one Subset, got %d frames", len(frames))
+	}
+
+	_, summary := decode(t, frames[0])
+	if summary.Code != CodeSummary || summary.Domain != "LAB" || summary.Revision != 42 || summary.Followers != 1 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	if !summary.Updater.Equal(net.ParseIP("10.0.0.5")) || len(summary.Timestamp) != 12 {
+		t.Errorf("Unexpected updater/timestamp: %s %q", summary.Updater, summary.Timestamp)
+	}
+	if summary.MD5 != [16]byte{} {
+		t.Errorf("Summary without password should have a zero digest")
+	}
+
+	_, subset := decode(t, frames[1])
+	if subset.Code != CodeSubset || subset.Sequence != 1 || subset.Revision != 42 {
+		t.Errorf("Unexpected subset: %+v", subset)
+	}
+	if len(subset.VLANs) != len(vlans) {
+		t.Fatalf("Got %d VLANs, want %d", len(subset.VLANs), len(vlans))
+	}
+	for i, v := range subset.VLANs {
+		if v != vlans[i] {
+			t.Errorf("VLAN %d = %+v, want %+v", i, v, vlans[i])
+		}
+	}
+}
+
+func TestAdvertisementSubsetsFitTheMTU(t *testing.T) {
+	mac, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+	var vlans []VLAN
+	for id := uint16(100); id < 200; id++ {
+		vlans = append(vlans, NewVLAN(id, fmt.Sprintf("%-32d", id)))
+	}
+
+	frames, err := CraftAdvertisement(mac, Advertisement{Domain: "LAB", Revision: 9, VLANs: vlans})
+	if err != nil {
+		t.Fatalf("Failed to craft VTP advertisement: %v", err)
+	}
+	var got []VLAN
+	for _, frame := range frames[1:] {
+		if len(frame) > 14+1500 {
+			t.Errorf("Subset of %d bytes is over the MTU", len(frame))
+		}
+		_, subset := decode(t, frame)
+		got = append(got, subset.VLANs...)
+	}
+	if len(got) != len(vlans) {
+		t.Fatalf("Subsets carry %d VLANs, want %d", len(got), len(vlans))
+	}
+
+	vlans[0].Name = strings.Repeat("x", 33)
+	if _, err := CraftAdvertisement(mac, Advertisement{Domain: "LAB", VLANs: vlans}); err == nil {
+		t.Error("Accepted a VLAN name longer than 32 characters")
+	}
+}
+
+func TestAdvertisementDigest(t *testing.T) {
+	mac, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+	vlans := DefaultVLANs()
+
+	frames, err := CraftAdvertisement(mac, Advertisement{Domain: "LAB", Revision: 7, VLANs: vlans, Password: "cisco"})
+	if err != nil {
+		t.Fatalf("Failed to craft VTP advertisement: %v", err)
+	}
+	_, summary := decode(t, frames[0])
+	if summary.MD5 == [16]byte{} {
+		t.Fatal("Summary with password has no digest")
+	}
+	if summary.MD5 != Digest(Secret("cisco"), summary, vlans) {
+		t.Error("Decoded digest does not verify")
+	}
+	if summary.MD5 == Digest(Secret("wrong"), summary, vlans) {
+		t.Error("Digest verifies with the wrong password")
+	}
+
+	secret := Secret("cisco")
+	hidden := Secret("0123456789abcdef0123456789abcdef")
+	if len(secret) != 16 || hidden[0] != 0x01 || hidden[15] != 0xef {
+		t.Errorf("Unexpected secrets: %x %x", secret, hidden)
+	}
+}
+
+func TestDomainTable(t *testing.T) {
+	mac, _ := net.ParseMAC("00:1e:13:00:aa:01")
+	table := NewDomainTable()
+
+	frames, _ := CraftAdvertisement(mac, Advertisement{Domain: "LAB", Revision: 3, VLANs: DefaultVLANs()})
+	for _, frame := range frames {
+		packet, _ := decode(t, frame)
+		if !table.Update(packet) {
+			t.Fatal("Update did not recognize VTP")
+		}
+	}
+	req, _ := CraftAdvertRequest(mac, 2, "LAB")
+	packet, v := decode(t, req)
+	if v.Code != CodeAdvertRequest || v.Domain != "LAB" {
+		t.Errorf("Unexpected advert request: %+v", v)
+	}
+	table.Update(packet)
+
+	domains := table.Domains()
+	if len(domains) != 1 {
+		t.Fatalf("Expected one domain, got %d", len(domains))
+	}
+	d := domains[0]
+	if d.Name != "LAB" || d.Revision != 3 || len(d.VLANs) != 5 || d.SrcMAC.String() != mac.String() {
+		t.Errorf("Unexpected domain: %+v", d)
+	}
+
+	if got := DeleteVLAN(d.VLANs, 1); len(got) != 4 || got[0].ID != 1002 {
+		t.Errorf("DeleteVLAN = %+v", got)
+	}
+}
@@ -0,0 +1,900 @@
+package ui
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gnpaone/l2star/internal/proto/arp"
+	"github.com/gnpaone/l2star/internal/proto/cdp"
+	"github.com/gnpaone/l2star/internal/proto/dhcp"
+	"github.com/gnpaone/l2star/internal/proto/dtp"
+	"github.com/gnpaone/l2star/internal/proto/glbp"
+	"github.com/gnpaone/l2star/internal/proto/hsrp"
+	"github.com/gnpaone/l2star/internal/proto/vrrp"
+	"github.com/gnpaone/l2star/internal/proto/vtp"
+)
+
+// parseIPList parses a comma separated list of IP addresses
+func parseIPList(s string) ([]net.IP, error) {
+	var ips []net.IP
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		ip := net.ParseIP(part)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address %q", part)
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
+
+// parseHostList parses a comma separated list of IPv4 addresses and CIDR subnets
+func parseHostList(s string) ([]net.IP, error) {
+	var ips []net.IP
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if strings.Contains(part, "/") {
+			_, subnet, err := net.ParseCIDR(part)
+			if err != nil || subnet.IP.To4() == nil {
+				return nil, fmt.Errorf("invalid IPv4 subnet %q", part)
+			}
+			ips = append(ips, arp.Hosts(subnet)...)
+			continue
+		}
+		ip := net.ParseIP(part).To4()
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IPv4 address %q", part)
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
+
+// parseUint parses a decimal or 0x-prefixed number, treating an empty string as 0
+func parseUint(s string, bits int) (uint64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(s, 0, bits)
+}
+
+// parseOptionalByte parses a number into a byte, returning nil for an empty string
+func parseOptionalByte(s string) (*byte, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	v, err := parseUint(s, 8)
+	if err != nil {
+		return nil, err
+	}
+	b := byte(v)
+	return &b, nil
+}
+
+// parseBool parses yes/no style settings
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "yes", "on", "true", "1":
+		return true, nil
+	case "no", "off", "false", "0", "":
+		return false, nil
+	}
+	return false, fmt.Errorf("expected yes or no, got %q", s)
+}
+
+// checkFields validates the settings of a tab before an attack is started
+func (m Model) checkFields(tab string) error {
+	switch tab {
+	case "CDP":
+		if _, err := m.cdpIdentity(); err != nil {
+			return err
+		}
+		_, err := m.cdpFloodConfig()
+		return err
+	case "DTP":
+		_, err := m.dtpConfig()
+		return err
+	case "ARP":
+		if m.selectedAttack == 1 {
+			if _, err := parseUint(m.fieldValue("ARP", "Scan Rate"), 32); err != nil {
+				return fmt.Errorf("Scan Rate: %v", err)
+			}
+			_, err := m.arpScanTargets()
+			return err
+		}
+		if m.selectedAttack == 2 {
+			_, err := m.arpPoisonConfig()
+			return err
+		}
+		if m.selectedAttack == 3 {
+			_, err := m.arpResponderConfig()
+			return err
+		}
+	case "DHCP":
+		if _, err := parseIPList(m.fieldValue("DHCP", "Trusted Servers")); err != nil {
+			return fmt.Errorf("Trusted Servers: %v", err)
+		}
+		if m.selectedAttack == 0 || m.selectedAttack == 3 {
+			if _, err := parseUint(m.fieldValue("DHCP", "Starve Rate"), 32); err != nil {
+				return fmt.Errorf("Starve Rate: %v", err)
+			}
+		}
+		if m.selectedAttack == 0 {
+			if _, err := parseBool(m.fieldValue("DHCP", "Release on Stop")); err != nil {
+				return fmt.Errorf("Release on Stop: %v", err)
+			}
+			if _, err := m.dhcpRelay(); err != nil {
+				return err
+			}
+		}
+		if m.selectedAttack == 1 {
+			_, err := m.dhcpServerConfig()
+			return err
+		}
+		if m.selectedAttack == 2 {
+			_, _, err := m.dhcpReleaseTargets()
+			return err
+		}
+	case "HSRP":
+		if _, _, err := m.gatewayUpstream("HSRP"); err != nil {
+			return err
+		}
+		_, err := m.hsrpConfig()
+		return err
+	case "GLBP":
+		if _, _, err := m.gatewayUpstream("GLBP"); err != nil {
+			return err
+		}
+		_, err := m.glbpConfig()
+		return err
+	case "VRRP":
+		if _, _, err := m.gatewayUpstream("VRRP"); err != nil {
+			return err
+		}
+		_, err := m.vrrpConfig()
+		return err
+	case "VTP":
+		if _, err := parseUint(m.fieldValue("VTP", "Revision"), 32); err != nil {
+			return fmt.Errorf("Revision: %v", err)
+		}
+		if _, err := m.vtpVLAN(); err != nil {
+			return err
+		}
+		switch m.fieldValue("VTP", "Version") {
+		case "1", "2":
+		default:
+			return fmt.Errorf("Version: must be 1 or 2")
+		}
+	}
+	return nil
+}
+
+// arpPoisonConfig builds the ARP MitM settings. The cache is filled in by the caller.
+func (m Model) arpPoisonConfig() (arp.PoisonConfig, error) {
+	cfg := arp.PoisonConfig{OurMAC: m.senderMAC, OurIP: m.senderIP()}
+
+	var err error
+	if cfg.Targets, err = parseHostList(m.fieldValue("ARP", "Targets")); err != nil {
+		return cfg, fmt.Errorf("Targets: %v", err)
+	}
+	if cfg.Gateways, err = parseHostList(m.fieldValue("ARP", "Gateway")); err != nil {
+		return cfg, fmt.Errorf("Gateway: %v", err)
+	}
+	if len(cfg.Targets) == 0 || len(cfg.Gateways) == 0 {
+		return cfg, fmt.Errorf("set both Targets and Gateway")
+	}
+
+	switch strings.ToLower(m.fieldValue("ARP", "Method")) {
+	case "", "reply":
+		cfg.Method = arp.MethodReply
+	case "request":
+		cfg.Method = arp.MethodRequest
+	default:
+		return cfg, fmt.Errorf("Method: use reply or request")
+	}
+
+	if _, err := m.arpInterval(); err != nil {
+		return cfg, err
+	}
+	if _, err := parseUint(m.fieldValue("ARP", "Restore Rounds"), 8); err != nil {
+		return cfg, fmt.Errorf("Restore Rounds: %v", err)
+	}
+	return cfg, nil
+}
+
+// arpResponderConfig builds the reactive ARP responder settings
+func (m Model) arpResponderConfig() (arp.ResponderConfig, error) {
+	cfg := arp.ResponderConfig{OurMAC: m.senderMAC}
+
+	var err error
+	if cfg.IPs, err = parseHostList(m.fieldValue("ARP", "Answer IPs")); err != nil {
+		return cfg, fmt.Errorf("Answer IPs: %v", err)
+	}
+	followUps, err := parseUint(m.fieldValue("ARP", "Follow-ups"), 8)
+	if err != nil {
+		return cfg, fmt.Errorf("Follow-ups: %v", err)
+	}
+	cfg.FollowUps = int(followUps)
+
+	if r := strings.TrimSpace(m.fieldValue("ARP", "Claim Range")); r != "" {
+		_, subnet, err := net.ParseCIDR(r)
+		if err != nil || subnet.IP.To4() == nil {
+			return cfg, fmt.Errorf("Claim Range: invalid IPv4 subnet %q", r)
+		}
+		cfg.Claim = subnet
+		if cfg.ClaimTimeout, err = time.ParseDuration(m.fieldValue("ARP", "Claim Timeout")); err != nil || cfg.ClaimTimeout <= 0 {
+			return cfg, fmt.Errorf("Claim Timeout: use a duration such as 1s")
+		}
+	}
+
+	if len(cfg.IPs) == 0 && cfg.Claim == nil {
+		return cfg, fmt.Errorf("set Answer IPs or a Claim Range")
+	}
+	return cfg, nil
+}
+
+// arpInterval returns the time between ARP poisoning rounds
+func (m Model) arpInterval() (time.Duration, error) {
+	d, err := time.ParseDuration(m.fieldValue("ARP", "Interval"))
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("Interval: use a duration such as 2s or 500ms")
+	}
+	return d, nil
+}
+
+// arpScanTargets returns the addresses to sweep: the Scan Range, or the subnet of the active interface
+func (m Model) arpScanTargets() ([]net.IP, error) {
+	if r := m.fieldValue("ARP", "Scan Range"); strings.TrimSpace(r) != "" {
+		targets, err := parseHostList(r)
+		if err != nil {
+			return nil, fmt.Errorf("Scan Range: %v", err)
+		}
+		return targets, nil
+	}
+
+	subnet := m.senderSubnet()
+	if subnet == nil {
+		return nil, fmt.Errorf("Scan Range: %s has no IPv4 subnet, set one", m.activeInterface)
+	}
+	return arp.Hosts(subnet), nil
+}
+
+// senderSubnet returns the first IPv4 subnet configured on the active interface, or nil
+func (m Model) senderSubnet() *net.IPNet {
+	iface, err := net.InterfaceByName(m.activeInterface)
+	if err != nil {
+		return nil
+	}
+	addrs, _ := iface.Addrs()
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() != nil {
+			return ipnet
+		}
+	}
+	return nil
+}
+
+// senderIP returns the first IPv4 address of the active interface, or nil
+func (m Model) senderIP() net.IP {
+	for _, iface := range m.interfaces {
+		if iface.Name != m.activeInterface {
+			continue
+		}
+		for _, s := range iface.IPs {
+			if ip := net.ParseIP(s).To4(); ip != nil {
+				return ip
+			}
+		}
+	}
+	return nil
+}
+
+// dhcpServerConfig builds the rogue DHCP server settings. Blank fields default to
+// our own address as server, router and DNS, and the upper half of our subnet as pool.
+func (m Model) dhcpServerConfig() (dhcp.ServerConfig, error) {
+	cfg := dhcp.ServerConfig{ServerMAC: m.senderMAC, ServerIP: m.senderIP()}
+	subnet := m.senderSubnet()
+
+	if v := strings.TrimSpace(m.fieldValue("DHCP", "Server IP")); v != "" {
+		if cfg.ServerIP = net.ParseIP(v).To4(); cfg.ServerIP == nil {
+			return cfg, fmt.Errorf("Server IP: invalid IPv4 address %q", v)
+		}
+	}
+	if cfg.ServerIP == nil {
+		return cfg, fmt.Errorf("Server IP: interface has no IPv4 address, set one")
+	}
+
+	var err error
+	if v := strings.TrimSpace(m.fieldValue("DHCP", "Pool")); v != "" {
+		if cfg.Pool, err = dhcp.ParsePool(v); err != nil {
+			return cfg, fmt.Errorf("Pool: %v", err)
+		}
+	} else if subnet != nil {
+		if ones, bits := subnet.Mask.Size(); bits == 32 && ones < 30 {
+			half := make(net.IP, 4)
+			binary.BigEndian.PutUint32(half, binary.BigEndian.Uint32(subnet.IP.Mask(subnet.Mask).To4())|1<<uint(bits-ones-1))
+			cfg.Pool, _ = dhcp.ParsePool(fmt.Sprintf("%s/%d", half, ones+1))
+		}
+	}
+	if cfg.Pool.Size() == 0 {
+		return cfg, fmt.Errorf("Pool: set a range such as 192.168.1.100-192.168.1.200")
+	}
+
+	if v := strings.TrimSpace(m.fieldValue("DHCP", "Subnet Mask")); v != "" {
+		mask := net.ParseIP(v).To4()
+		if mask == nil {
+			return cfg, fmt.Errorf("Subnet Mask: invalid mask %q", v)
+		}
+		cfg.Options.Mask = net.IPMask(mask)
+	} else if subnet != nil {
+		cfg.Options.Mask = subnet.Mask
+	}
+
+	if cfg.Options.Routers, err = parseIPList(m.fieldValue("DHCP", "Routers")); err != nil {
+		return cfg, fmt.Errorf("Routers: %v", err)
+	}
+	if len(cfg.Options.Routers) == 0 {
+		cfg.Options.Routers = []net.IP{cfg.ServerIP}
+	}
+	if cfg.Options.DNS, err = parseIPList(m.fieldValue("DHCP", "DNS")); err != nil {
+		return cfg, fmt.Errorf("DNS: %v", err)
+	}
+	if len(cfg.Options.DNS) == 0 {
+		cfg.Options.DNS = []net.IP{cfg.ServerIP}
+	}
+
+	o := &cfg.Options
+	o.Domain = strings.TrimSpace(m.fieldValue("DHCP", "Domain"))
+	for _, domain := range strings.Split(m.fieldValue("DHCP", "Search"), ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			o.Search = append(o.Search, domain)
+		}
+	}
+
+	if o.LeaseTime, err = time.ParseDuration(m.fieldValue("DHCP", "Lease Time")); err != nil || o.LeaseTime < time.Second {
+		return cfg, fmt.Errorf("Lease Time: use a duration such as 1h")
+	}
+	for _, timer := range []struct {
+		label string
+		d     *time.Duration
+	}{{"Renew Time", &o.RenewTime}, {"Rebind Time", &o.RebindTime}} {
+		v := strings.TrimSpace(m.fieldValue("DHCP", timer.label))
+		if v == "" {
+			continue
+		}
+		if *timer.d, err = time.ParseDuration(v); err != nil || *timer.d < time.Second || *timer.d > o.LeaseTime {
+			return cfg, fmt.Errorf("%s: use a duration shorter than the lease time", timer.label)
+		}
+	}
+
+	if o.NTP, err = parseIPList(m.fieldValue("DHCP", "NTP")); err != nil {
+		return cfg, fmt.Errorf("NTP: %v", err)
+	}
+	o.TFTPServer = strings.TrimSpace(m.fieldValue("DHCP", "TFTP Server"))
+	o.BootFile = strings.TrimSpace(m.fieldValue("DHCP", "Boot File"))
+	if o.Routes, err = dhcp.ParseRoutes(m.fieldValue("DHCP", "Routes")); err != nil {
+		return cfg, fmt.Errorf("Routes: %v", err)
+	}
+	o.WPAD = strings.TrimSpace(m.fieldValue("DHCP", "WPAD"))
+	if o.Raw, err = dhcp.ParseRawOptions(m.fieldValue("DHCP", "Raw Options")); err != nil {
+		return cfg, fmt.Errorf("Raw Options: %v", err)
+	}
+
+	for _, name := range strings.Split(m.fieldValue("DHCP", "Presets"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		preset, ok := dhcp.Presets[name]
+		if !ok {
+			return cfg, fmt.Errorf("Presets: unknown preset %q, use %s", name, strings.Join(dhcp.PresetNames(), ", "))
+		}
+		preset(o, cfg.ServerIP)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(m.fieldValue("DHCP", "Respond"))) {
+	case "always":
+		cfg.Respond = dhcp.RespondAlways
+	case "fallback":
+		cfg.Respond = dhcp.RespondFallback
+	default:
+		return cfg, fmt.Errorf("Respond: must be always or fallback")
+	}
+	if cfg.Wait, err = time.ParseDuration(m.fieldValue("DHCP", "Fallback Wait")); err != nil || cfg.Wait <= 0 {
+		return cfg, fmt.Errorf("Fallback Wait: use a duration such as 500ms")
+	}
+	return cfg, nil
+}
+
+// dhcpRelay returns the relay agent starvation goes through, nil when no relay server is set.
+// The agent IP defaults to our own address, the next hop MAC is resolved by the caller.
+func (m Model) dhcpRelay() (*dhcp.Relay, error) {
+	v := strings.TrimSpace(m.fieldValue("DHCP", "Relay Server"))
+	if v == "" {
+		return nil, nil
+	}
+	relay := &dhcp.Relay{AgentMAC: m.senderMAC, AgentIP: m.senderIP()}
+	if relay.ServerIP = net.ParseIP(v).To4(); relay.ServerIP == nil {
+		return nil, fmt.Errorf("Relay Server: invalid IPv4 address %q", v)
+	}
+	if v := strings.TrimSpace(m.fieldValue("DHCP", "Relay Agent IP")); v != "" {
+		if relay.AgentIP = net.ParseIP(v).To4(); relay.AgentIP == nil {
+			return nil, fmt.Errorf("Relay Agent IP: invalid IPv4 address %q", v)
+		}
+	}
+	if relay.AgentIP == nil {
+		return nil, fmt.Errorf("Relay Agent IP: interface has no IPv4 address, set one")
+	}
+
+	var err error
+	if relay.CircuitID, err = dhcp.ParseAgentID(m.fieldValue("DHCP", "Circuit ID")); err != nil {
+		return nil, fmt.Errorf("Circuit ID: %v", err)
+	}
+	if relay.RemoteID, err = dhcp.ParseAgentID(m.fieldValue("DHCP", "Remote ID")); err != nil {
+		return nil, fmt.Errorf("Remote ID: %v", err)
+	}
+	return relay, nil
+}
+
+// dhcpReleaseTargets returns the clients to release and their server. No clients means every binding the monitor learned.
+func (m Model) dhcpReleaseTargets() ([]dhcp.Client, net.IP, error) {
+	clients, err := dhcp.ParseClients(m.fieldValue("DHCP", "Release Clients"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("Release Clients: %v", err)
+	}
+	server := net.ParseIP(strings.TrimSpace(m.fieldValue("DHCP", "Release Server"))).To4()
+	if server == nil {
+		return nil, nil, fmt.Errorf("Release Server: set the DHCP server IPv4 address")
+	}
+	return clients, server, nil
+}
+
+// hsrpConfig builds the HSRP group settings the takeover speaks in. A blank
+// Source IP is our interface address, or our link-local address for an IPv6 group.
+func (m Model) hsrpConfig() (hsrp.Config, error) {
+	get := func(label string) string { return m.fieldValue("HSRP", label) }
+	cfg := hsrp.Config{Auth: get("Auth"), MD5Key: get("MD5 Key")}
+
+	switch get("Version") {
+	case "1":
+		cfg.Version = 1
+	case "2":
+		cfg.Version = 2
+	default:
+		return cfg, fmt.Errorf("Version: must be 1 or 2")
+	}
+	group, err := parseUint(get("Group"), 12)
+	if err != nil || (cfg.Version == 1 && group > 255) {
+		return cfg, fmt.Errorf("Group: must be 0-255 (0-4095 with version 2)")
+	}
+	cfg.Group = uint16(group)
+	if cfg.VIP = net.ParseIP(strings.TrimSpace(get("VIP"))); cfg.VIP == nil {
+		return cfg, fmt.Errorf("VIP: set the virtual IP, or press 'g' to use a learned group")
+	}
+	if vip4 := cfg.VIP.To4(); vip4 != nil {
+		cfg.VIP = vip4
+	} else if cfg.Version == 1 {
+		return cfg, fmt.Errorf("VIP: IPv6 groups need version 2")
+	}
+	if src := strings.TrimSpace(get("Source IP")); src != "" {
+		if cfg.SourceIP = net.ParseIP(src); cfg.SourceIP == nil {
+			return cfg, fmt.Errorf("Source IP: invalid address")
+		}
+	} else if cfg.VIP.To4() != nil {
+		if cfg.SourceIP = m.senderIP(); cfg.SourceIP == nil {
+			return cfg, fmt.Errorf("Source IP: the interface has no IPv4 address, set one")
+		}
+	}
+	if (cfg.SourceIP.To4() == nil) != (cfg.VIP.To4() == nil) && cfg.SourceIP != nil {
+		return cfg, fmt.Errorf("Source IP: must be of the same family as the VIP")
+	}
+	if cfg.MD5Key != "" && cfg.Version == 1 {
+		return cfg, fmt.Errorf("MD5 Key: MD5 authentication is only sent with version 2")
+	}
+	keyID, err := parseUint(get("Key ID"), 32)
+	if err != nil {
+		return cfg, fmt.Errorf("Key ID: must be a number")
+	}
+	cfg.KeyID = uint32(keyID)
+	priority, err := parseUint(get("Priority"), 8)
+	if err != nil {
+		return cfg, fmt.Errorf("Priority: must be 0-255")
+	}
+	cfg.Priority = uint8(priority)
+	hello, err := parseUint(get("Hellotime"), 8)
+	if err != nil || hello == 0 {
+		return cfg, fmt.Errorf("Hellotime: must be 1-255 seconds")
+	}
+	cfg.Hellotime = uint8(hello)
+	hold, err := parseUint(get("Holdtime"), 8)
+	if err != nil || hold <= hello {
+		return cfg, fmt.Errorf("Holdtime: must be longer than the hellotime")
+	}
+	cfg.Holdtime = uint8(hold)
+	if len(cfg.Auth) > 8 {
+		return cfg, fmt.Errorf("Auth: at most 8 characters")
+	}
+	return cfg, nil
+}
+
+// glbpConfig builds the GLBP group settings the AVG takeover speaks in. A blank
+// Forwarder is the first one free in the learned group, 0 announces none.
+func (m Model) glbpConfig() (glbp.Config, error) {
+	get := func(label string) string { return m.fieldValue("GLBP", label) }
+	cfg := glbp.Config{Auth: get("Auth"), Redirect: 10 * time.Minute, Timeout: 4 * time.Hour}
+
+	group, err := parseUint(get("Group"), 16)
+	if err != nil || group > 1023 {
+		return cfg, fmt.Errorf("Group: must be 0-1023")
+	}
+	cfg.Group = uint16(group)
+	if cfg.VIP = net.ParseIP(strings.TrimSpace(get("VIP"))).To4(); cfg.VIP == nil {
+		return cfg, fmt.Errorf("VIP: set the virtual IP, or press 'g' to use a learned group")
+	}
+	priority, err := parseUint(get("Priority"), 8)
+	if err != nil {
+		return cfg, fmt.Errorf("Priority: must be 0-255")
+	}
+	cfg.Priority = uint8(priority)
+	if fwd := strings.TrimSpace(get("Forwarder")); fwd == "" {
+		cfg.Forwarder = 1
+		if m.monitor != nil {
+			for _, g := range m.monitor.glbp.Groups() {
+				if g.Number == cfg.Group {
+					cfg.Forwarder = g.FreeForwarder()
+				}
+			}
+		}
+	} else {
+		n, err := parseUint(fwd, 8)
+		if err != nil || n > 4 {
+			return cfg, fmt.Errorf("Forwarder: must be 1-4, or 0 for none")
+		}
+		cfg.Forwarder = uint8(n)
+	}
+	weight, err := parseUint(get("Weight"), 8)
+	if err != nil || weight == 0 || weight > 100 {
+		return cfg, fmt.Errorf("Weight: must be 1-100")
+	}
+	cfg.Weight = uint8(weight)
+	if cfg.Hellotime, err = time.ParseDuration(strings.TrimSpace(get("Hellotime"))); err != nil || cfg.Hellotime <= 0 {
+		return cfg, fmt.Errorf("Hellotime: must be a duration like 3s")
+	}
+	if cfg.Holdtime, err = time.ParseDuration(strings.TrimSpace(get("Holdtime"))); err != nil || cfg.Holdtime <= cfg.Hellotime {
+		return cfg, fmt.Errorf("Holdtime: must be longer than the hellotime")
+	}
+	if len(cfg.Auth) > 255 {
+		return cfg, fmt.Errorf("Auth: too long")
+	}
+	if src := strings.TrimSpace(get("Source IP")); src != "" {
+		if cfg.SourceIP = net.ParseIP(src).To4(); cfg.SourceIP == nil {
+			return cfg, fmt.Errorf("Source IP: must be an IPv4 address")
+		}
+	} else if cfg.SourceIP = m.senderIP(); cfg.SourceIP == nil {
+		return cfg, fmt.Errorf("Source IP: the interface has no IPv4 address, set one")
+	}
+	return cfg, nil
+}
+
+// vrrpConfig builds the virtual router settings the takeover advertises. A blank
+// Source IP is our interface address, or our link-local address for IPv6.
+func (m Model) vrrpConfig() (vrrp.Config, error) {
+	get := func(label string) string { return m.fieldValue("VRRP", label) }
+	cfg := vrrp.Config{Auth: get("Auth")}
+
+	switch get("Version") {
+	case "2":
+		cfg.Version = 2
+	case "3":
+		cfg.Version = 3
+	default:
+		return cfg, fmt.Errorf("Version: must be 2 or 3")
+	}
+	vrid, err := parseUint(get("VRID"), 8)
+	if err != nil || vrid == 0 {
+		return cfg, fmt.Errorf("VRID: must be 1-255")
+	}
+	cfg.VRID = uint8(vrid)
+	for _, s := range strings.Split(get("Addresses"), ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return cfg, fmt.Errorf("Addresses: invalid address %q", s)
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		cfg.Addresses = append(cfg.Addresses, ip)
+	}
+	if len(cfg.Addresses) == 0 {
+		return cfg, fmt.Errorf("Addresses: set the virtual addresses, or press 'g' to use a learned router")
+	}
+	for _, ip := range cfg.Addresses {
+		if (ip.To4() == nil) != cfg.IPv6() {
+			return cfg, fmt.Errorf("Addresses: do not mix IPv4 and IPv6")
+		}
+	}
+	if cfg.IPv6() && cfg.Version == 2 {
+		return cfg, fmt.Errorf("Addresses: IPv6 virtual routers need version 3")
+	}
+	priority, err := parseUint(get("Priority"), 8)
+	if err != nil || priority == 0 {
+		return cfg, fmt.Errorf("Priority: must be 1-255")
+	}
+	cfg.Priority = uint8(priority)
+	if cfg.Interval, err = time.ParseDuration(strings.TrimSpace(get("Interval"))); err != nil || cfg.Interval <= 0 {
+		return cfg, fmt.Errorf("Interval: must be a duration like 1s")
+	}
+	if cfg.Version == 2 && (cfg.Interval%time.Second != 0 || cfg.Interval > 255*time.Second) {
+		return cfg, fmt.Errorf("Interval: version 2 uses whole seconds, up to 255s")
+	}
+	if cfg.Version == 3 && (cfg.Interval%(10*time.Millisecond) != 0 || cfg.Interval > 40950*time.Millisecond) {
+		return cfg, fmt.Errorf("Interval: version 3 uses centiseconds, up to 40.95s")
+	}
+	if len(cfg.Auth) > 8 {
+		return cfg, fmt.Errorf("Auth: at most 8 characters")
+	}
+	if cfg.Auth != "" && cfg.Version == 3 {
+		return cfg, fmt.Errorf("Auth: authentication was removed in version 3")
+	}
+	if src := strings.TrimSpace(get("Source IP")); src != "" {
+		if cfg.SourceIP = net.ParseIP(src); cfg.SourceIP == nil || (cfg.SourceIP.To4() == nil) != cfg.IPv6() {
+			return cfg, fmt.Errorf("Source IP: must be an address of the same family as the virtual addresses")
+		}
+	} else if cfg.IPv6() {
+		cfg.SourceIP = vrrp.LinkLocal(m.senderMAC)
+	} else if cfg.SourceIP = m.senderIP(); cfg.SourceIP == nil {
+		return cfg, fmt.Errorf("Source IP: the interface has no IPv4 address, set one")
+	}
+	return cfg, nil
+}
+
+// gatewayUpstream reads the Upstream field of an FHRP tab: the real router the
+// victims' traffic is relayed to, nil to use the one learned from the group, or
+// off when the takeover should not impersonate the gateway
+func (m Model) gatewayUpstream(tab string) (upstream net.IP, off bool, err error) {
+	value := strings.TrimSpace(m.fieldValue(tab, "Upstream"))
+	switch value {
+	case "":
+		return nil, false, nil
+	case "off":
+		return nil, true, nil
+	}
+	if upstream = net.ParseIP(value).To4(); upstream == nil {
+		return nil, false, fmt.Errorf("Upstream: must be an IPv4 address, blank or off")
+	}
+	return upstream, false, nil
+}
+
+// vtpVLAN returns the VLAN the VTP attacks add or delete
+func (m Model) vtpVLAN() (vtp.VLAN, error) {
+	id, err := parseUint(m.fieldValue("VTP", "VLAN ID"), 12)
+	if err != nil || id == 0 || id > 4094 {
+		return vtp.VLAN{}, fmt.Errorf("VLAN ID: must be 1-4094")
+	}
+	name := m.fieldValue("VTP", "VLAN Name")
+	if name == "" {
+		name = fmt.Sprintf("VLAN%04d", id)
+	}
+	if len(name) > 32 {
+		return vtp.VLAN{}, fmt.Errorf("VLAN Name: at most 32 characters")
+	}
+	return vtp.NewVLAN(uint16(id), name), nil
+}
+
+// vtpAdvertisement builds the frames of the selected VTP attack. The domain, VLAN
+// list and revision start from what the monitor heard; the revision is bumped by one
+// unless configured.
+func (m Model) vtpAdvertisement() ([][]byte, error) {
+	vlan, err := m.vtpVLAN()
+	if err != nil {
+		return nil, err
+	}
+	version, _ := parseUint(m.fieldValue("VTP", "Version"), 8)
+
+	adv := vtp.Advertisement{
+		Version:  byte(version),
+		Domain:   m.fieldValue("VTP", "Domain"),
+		Password: m.fieldValue("VTP", "Password"),
+		VLANs:    vtp.DefaultVLANs(),
+	}
+	if adv.Domain == "auto" {
+		adv.Domain = ""
+		if m.monitor != nil {
+			adv.Domain = m.monitor.vtpDomain()
+		}
+		if adv.Domain == "" {
+			return nil, fmt.Errorf("no VTP domain learned yet, set Domain")
+		}
+	}
+
+	if m.monitor != nil {
+		for _, d := range m.monitor.vtp.Domains() {
+			if d.Name == adv.Domain {
+				adv.Revision = d.Revision + 1
+				adv.Updater = d.Updater
+				if len(d.VLANs) > 0 {
+					adv.VLANs = d.VLANs
+				}
+			}
+		}
+	}
+	if rev := m.fieldValue("VTP", "Revision"); rev != "" {
+		r, _ := parseUint(rev, 32)
+		adv.Revision = uint32(r)
+	}
+
+	if m.selectedAttack == 0 {
+		adv.VLANs = vtp.AddVLAN(adv.VLANs, vlan)
+	} else {
+		adv.VLANs = vtp.DeleteVLAN(adv.VLANs, vlan.ID)
+	}
+	return vtp.CraftAdvertisement(m.senderMAC, adv)
+}
+
+// cdpIdentity builds the CDP announcement from the CDP tab settings
+func (m Model) cdpIdentity() (*cdp.CustomCDPLayer, error) {
+	get := func(label string) string { return m.fieldValue("CDP", label) }
+
+	id := &cdp.CustomCDPLayer{
+		DeviceID:   get("Device ID"),
+		PortID:     get("Port ID"),
+		Platform:   get("Platform"),
+		Software:   get("Software"),
+		SystemName: get("System Name"),
+		VTPDomain:  get("VTP Domain"),
+	}
+
+	version, err := parseUint(get("Version"), 8)
+	if err != nil || version > 2 {
+		return nil, fmt.Errorf("Version: expected 1 or 2")
+	}
+	id.Version = byte(version)
+	holdtime, err := parseUint(get("Holdtime"), 8)
+	if err != nil {
+		return nil, fmt.Errorf("Holdtime: %v", err)
+	}
+	id.TTL = byte(holdtime)
+	if id.Addresses, err = parseIPList(get("Addresses")); err != nil {
+		return nil, fmt.Errorf("Addresses: %v", err)
+	}
+	caps, err := parseUint(get("Capabilities"), 32)
+	if err != nil {
+		return nil, fmt.Errorf("Capabilities: %v", err)
+	}
+	id.Capabilities = uint32(caps)
+	vlan, err := parseUint(get("Native VLAN"), 12)
+	if err != nil {
+		return nil, fmt.Errorf("Native VLAN: %v", err)
+	}
+	id.NativeVLAN = uint16(vlan)
+	voice, err := parseUint(get("VoIP VLAN"), 12)
+	if err != nil {
+		return nil, fmt.Errorf("VoIP VLAN: %v", err)
+	}
+	id.VoIPVLAN = uint16(voice)
+	power, err := parseUint(get("Power Available"), 32)
+	if err != nil {
+		return nil, fmt.Errorf("Power Available: %v", err)
+	}
+	id.PowerAvailable = uint32(power)
+
+	switch strings.ToLower(strings.TrimSpace(get("Duplex"))) {
+	case "":
+	case "full":
+		full := byte(1)
+		id.Duplex = &full
+	case "half":
+		half := byte(0)
+		id.Duplex = &half
+	default:
+		return nil, fmt.Errorf("Duplex: expected full or half")
+	}
+	if id.TrustBitmap, err = parseOptionalByte(get("Trust Bitmap")); err != nil {
+		return nil, fmt.Errorf("Trust Bitmap: %v", err)
+	}
+	if id.UntrustedCoS, err = parseOptionalByte(get("Untrusted CoS")); err != nil {
+		return nil, fmt.Errorf("Untrusted CoS: %v", err)
+	}
+	return id, nil
+}
+
+// dtpConfig builds the DTP settings. A Domain of "auto" is left empty here and
+// filled in from what the monitor learned when each frame is sent.
+func (m Model) dtpConfig() (dtp.Config, error) {
+	var cfg dtp.Config
+	if domain := m.fieldValue("DTP", "Domain"); domain != "auto" {
+		if len(domain) > 32 {
+			return cfg, fmt.Errorf("Domain: longer than 32 characters")
+		}
+		cfg.Domain = domain
+	}
+
+	switch strings.ToLower(strings.TrimSpace(m.fieldValue("DTP", "Encapsulation"))) {
+	case "802.1q", "dot1q":
+		cfg.Encapsulation = dtp.EncapDot1Q
+	case "isl":
+		cfg.Encapsulation = dtp.EncapISL
+	case "", "negotiate":
+		cfg.Encapsulation = dtp.EncapNegotiate
+	default:
+		return cfg, fmt.Errorf("Encapsulation: use 802.1Q, ISL or negotiate")
+	}
+	return cfg, nil
+}
+
+// cdpFloodConfig builds the CDP flood pools from the CDP tab settings. Blank pools keep the personas' own.
+func (m Model) cdpFloodConfig() (cdp.FloodConfig, error) {
+	var cfg cdp.FloodConfig
+	for _, name := range strings.Split(m.fieldValue("CDP", "Flood Personas"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		persona, ok := cdp.DefaultPersonas[name]
+		if !ok {
+			return cfg, fmt.Errorf("Flood Personas: unknown persona %q", name)
+		}
+		cfg.Personas = append(cfg.Personas, persona)
+	}
+
+	if subnet := strings.TrimSpace(m.fieldValue("CDP", "Flood Subnet")); subnet != "" {
+		_, ipnet, err := net.ParseCIDR(subnet)
+		if err != nil {
+			return cfg, fmt.Errorf("Flood Subnet: %v", err)
+		}
+		cfg.Subnet = ipnet
+	}
+
+	// Software strings contain commas, so the pools are separated by semicolons
+	pool := func(label string) []string {
+		var out []string
+		for _, s := range strings.Split(m.fieldValue("CDP", label), ";") {
+			if s = strings.TrimSpace(s); s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	cfg.DeviceIDs = pool("Flood Device IDs")
+	cfg.Platforms = pool("Flood Platforms")
+	cfg.Software = pool("Flood Software")
+
+	holdtime, _ := parseUint(m.fieldValue("CDP", "Holdtime"), 8)
+	cfg.TTL = byte(holdtime)
+
+	if _, err := parseUint(m.fieldValue("CDP", "Flood Rate"), 32); err != nil {
+		return cfg, fmt.Errorf("Flood Rate: %v", err)
+	}
+	return cfg, nil
+}
+
+// cdpFloodRate returns the configured CDP flood rate in packets per second
+func (m Model) cdpFloodRate() int {
+	rate, _ := parseUint(m.fieldValue("CDP", "Flood Rate"), 32)
+	return int(rate)
+}
+
+// rateToTicks converts a packet rate into a ticker frequency and burst size for core.AttackConfig
+func rateToTicks(pps int) (time.Duration, int) {
+	if pps <= 0 {
+		return 100 * time.Millisecond, 1
+	}
+	if pps <= 1000 {
+		return time.Second / time.Duration(pps), 1
+	}
+	return time.Millisecond, pps / 1000
+}
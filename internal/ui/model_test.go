@@ -1,11 +1,22 @@
 package ui
 
 import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gnpaone/l2star/internal/core"
+	l2net "github.com/gnpaone/l2star/internal/net"
+	"github.com/gnpaone/l2star/internal/proto/dtp"
+	"github.com/gnpaone/l2star/internal/proto/hsrp"
+	"github.com/gnpaone/l2star/internal/proto/vlan"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
 )
 
 // Mock core interface for testing
@@ -25,6 +36,7 @@ func TestNavigationToMain(t *testing.T) {
 	m := InitialModel()
 	m.interfaces = mockInterfaces
 	msg := tea.KeyMsg{Type: tea.KeyEnter}
+	newM, _ := m.Update(msg)
 	updatedModel := newM.(Model)
 
 	if updatedModel.state != StateMain {
@@ -65,3 +77,160 @@ func TestTabNavigation(t *testing.T) {
 		t.Errorf("Expected active tab 1 (CDP) after Tab, got %d", updatedModel.activeTab)
 	}
 }
+
+func TestEditField(t *testing.T) {
+	m := InitialModel()
+	m.state = StateMain
+	m.activeTab = 1 // CDP
+
+	keys := []tea.KeyMsg{{Type: tea.KeyRunes, Runes: []rune("e")}}
+	for _, f := range m.fields["CDP"] {
+		if f.Label == "Device ID" {
+			break
+		}
+		keys = append(keys, tea.KeyMsg{Type: tea.KeyDown})
+	}
+	keys = append(keys, []tea.KeyMsg{
+		{Type: tea.KeyEnter},
+		{Type: tea.KeyBackspace},
+		{Type: tea.KeyRunes, Runes: []rune("2")},
+		{Type: tea.KeyEnter},
+		{Type: tea.KeyRunes, Runes: []rune("e")},
+	}...)
+	var newM tea.Model = m
+	for _, k := range keys {
+		newM, _ = newM.Update(k)
+	}
+	updatedModel := newM.(Model)
+
+	if updatedModel.editing {
+		t.Errorf("Expected editing to be finished")
+	}
+	if got := updatedModel.fieldValue("CDP", "Device ID"); got != "Core-Switch-02" {
+		t.Errorf("Expected Device ID 'Core-Switch-02', got '%s'", got)
+	}
+
+	id, err := updatedModel.cdpIdentity()
+	if err != nil {
+		t.Fatalf("Failed to build CDP identity: %v", err)
+	}
+	if id.DeviceID != "Core-Switch-02" || id.Duplex == nil || *id.Duplex != 1 {
+		t.Errorf("Unexpected CDP identity %+v", id)
+	}
+}
+
+func TestQuitSendsRestorePackets(t *testing.T) {
+	var mu sync.Mutex
+	var restored [][]byte
+	injectAttack = func(cfg core.AttackConfig) error {
+		<-cfg.StopChan
+		// Restoring takes a while, quitting must wait for it
+		time.Sleep(100 * time.Millisecond)
+		packets, err := cfg.Restore()
+		mu.Lock()
+		restored = packets
+		mu.Unlock()
+		return err
+	}
+	defer func() { injectAttack = l2net.StartAttack }()
+
+	m := InitialModel()
+	m.state = StateMain
+	m.activeInterface = "eth0"
+	m.senderMAC, _ = net.ParseMAC("00:11:22:33:44:55")
+	for i, tab := range m.tabs {
+		if tab == "HSRP" {
+			m.activeTab = i
+		}
+	}
+	m.setFieldValue("HSRP", "VIP", "10.0.0.1")
+	m.setFieldValue("HSRP", "Source IP", "10.0.0.5")
+	m.setFieldValue("HSRP", "Upstream", "off")
+	m.startAttack()
+	if !m.attack.Active {
+		t.Fatalf("Attack did not start: %v", m.logs)
+	}
+
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	if cmd == nil {
+		t.Fatal("Expected a command to quit")
+	}
+	msgs := make(chan tea.Msg, 1)
+	go func() { msgs <- cmd() }()
+	select {
+	case msg := <-msgs:
+		if _, ok := msg.(tea.QuitMsg); !ok {
+			t.Fatalf("Expected to quit, got %T", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Did not quit after the restore")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(restored) == 0 {
+		t.Fatal("Quit before the restore packets were sent")
+	}
+	msg := hsrp.DecodeHSRP(gopacket.NewPacket(restored[0], layers.LayerTypeEthernet, gopacket.Default))
+	if msg == nil || msg.OpCode != hsrp.OpResign {
+		t.Errorf("Expected a Resign on quit, got %+v", msg)
+	}
+}
+
+func TestDTPPortWithoutDTP(t *testing.T) {
+	out := renderDTPPort(dtp.PortState{}, nil, nil)
+	if !strings.Contains(out, "access (nonegotiate)") {
+		t.Errorf("Expected an access port without DTP or tags, got %q", out)
+	}
+
+	out = renderDTPPort(dtp.PortState{}, nil, []vlan.VLAN{{ID: 20, Hints: []string{vlan.HintCDPNative}}})
+	if !strings.Contains(out, "access (nonegotiate)") {
+		t.Errorf("A hinted VLAN is not a tagged frame, got %q", out)
+	}
+
+	out = renderDTPPort(dtp.PortState{}, nil, []vlan.VLAN{{ID: 20, Frames: 3}})
+	if !strings.Contains(out, "trunk (nonegotiate)") {
+		t.Errorf("Expected a nonegotiate trunk with tagged frames, got %q", out)
+	}
+}
+
+func TestRunnerErrorStopsAttack(t *testing.T) {
+	injectAttack = func(cfg core.AttackConfig) error {
+		return errors.New("permission denied")
+	}
+	defer func() { injectAttack = l2net.StartAttack }()
+
+	m := InitialModel()
+	m.state = StateMain
+	m.activeInterface = "eth0"
+	m.senderMAC, _ = net.ParseMAC("00:11:22:33:44:55")
+	m.startAttack()
+	if !m.attack.Active || m.attack.Protocol != "STP" {
+		t.Fatalf("Attack did not start: %v", m.logs)
+	}
+
+	msgs := make(chan tea.Msg, 1)
+	go func() { msgs <- waitForRunnerErr(m.runnerErrs)() }()
+	var msg tea.Msg
+	select {
+	case msg = <-msgs:
+	case <-time.After(2 * time.Second):
+		t.Fatal("The attack's error never reached the model")
+	}
+
+	updated, cmd := m.Update(msg)
+	m = updated.(Model)
+	if m.attack.Active {
+		t.Error("Attack still active after its runner failed")
+	}
+	if cmd == nil {
+		t.Error("Expected to keep waiting for runner errors")
+	}
+	found := false
+	for _, l := range m.logs {
+		found = found || strings.Contains(l, "STP attack stopped: permission denied")
+	}
+	if !found {
+		t.Errorf("Error not logged: %v", m.logs)
+	}
+}
@@ -0,0 +1,139 @@
+package ui
+
+import (
+	"net"
+	"sync"
+
+	"github.com/gnpaone/l2star/internal/core"
+	"github.com/gnpaone/l2star/internal/proto/arp"
+	"github.com/gnpaone/l2star/internal/proto/cdp"
+	"github.com/gnpaone/l2star/internal/proto/dhcp"
+	"github.com/gnpaone/l2star/internal/proto/dtp"
+	"github.com/gnpaone/l2star/internal/proto/glbp"
+	"github.com/gnpaone/l2star/internal/proto/hsrp"
+	"github.com/gnpaone/l2star/internal/proto/vlan"
+	"github.com/gnpaone/l2star/internal/proto/vrrp"
+	"github.com/gnpaone/l2star/internal/proto/vtp"
+
+	l2net "github.com/gnpaone/l2star/internal/net"
+
+	"github.com/google/gopacket"
+)
+
+// monitor passively listens on the active interface and keeps the tables shown in the tabs up to date
+type monitor struct {
+	arp   *arp.Cache
+	cdp   *cdp.NeighborTable
+	dhcp  *dhcp.Inventory
+	dtp   *dtp.PortMonitor
+	hsrp  *hsrp.GroupTable
+	vrrp  *vrrp.GroupTable
+	glbp  *glbp.GroupTable
+	vtp   *vtp.DomainTable
+	vlans *vlan.Discovery
+	stop  chan struct{}
+
+	// handlers are extra consumers attached by running attacks, keyed by name
+	mu       sync.Mutex
+	handlers map[string]core.PacketHandler
+}
+
+// fingerprintFile is the local DHCP fingerprint database, loaded on top of the built-in one when present
+const fingerprintFile = "dhcp-fingerprints.txt"
+
+func startMonitor(iface string, ourMAC net.HardwareAddr) *monitor {
+	db := dhcp.NewFingerprintDB()
+	db.LoadFile(fingerprintFile)
+
+	mon := &monitor{
+		arp:   arp.NewCache(ourMAC),
+		cdp:   cdp.NewNeighborTable(),
+		dhcp:  dhcp.NewInventory(db, ourMAC),
+		hsrp:  hsrp.NewGroupTable(ourMAC),
+		vrrp:  vrrp.NewGroupTable(),
+		glbp:  glbp.NewGroupTable(ourMAC),
+		dtp:   dtp.NewPortMonitor(),
+		vtp:   vtp.NewDomainTable(),
+		vlans: vlan.NewDiscovery(),
+		stop:  make(chan struct{}),
+
+		handlers: make(map[string]core.PacketHandler),
+	}
+
+	go func() {
+		l2net.StartCapture(core.CaptureConfig{
+			InterfaceName: iface,
+			Handler:       mon.handle,
+			StopChan:      mon.stop,
+		})
+	}()
+	return mon
+}
+
+// attach feeds every captured packet to h as well, replacing the handler attached under the same name
+func (mon *monitor) attach(name string, h core.PacketHandler) {
+	mon.mu.Lock()
+	defer mon.mu.Unlock()
+	mon.handlers[name] = h
+}
+
+// detach removes the handler attached under name
+func (mon *monitor) detach(name string) {
+	mon.mu.Lock()
+	defer mon.mu.Unlock()
+	delete(mon.handlers, name)
+}
+
+func (mon *monitor) handle(packet gopacket.Packet) {
+	mon.mu.Lock()
+	for _, h := range mon.handlers {
+		h(packet)
+	}
+	mon.mu.Unlock()
+
+	mon.vlans.Update(packet)
+	if mon.arp.Update(packet) {
+		return
+	}
+	if mon.cdp.Update(packet) {
+		return
+	}
+	if mon.dtp.Update(packet) {
+		return
+	}
+	if mon.dhcp.Update(packet) {
+		return
+	}
+	if mon.hsrp.Update(packet) {
+		return
+	}
+	if mon.vrrp.Update(packet) {
+		return
+	}
+	if mon.glbp.Update(packet) {
+		return
+	}
+	mon.vtp.Update(packet)
+}
+
+func (mon *monitor) Stop() {
+	close(mon.stop)
+}
+
+// vtpDomain returns the VTP domain learned from DTP, VTP or CDP, in that order
+func (mon *monitor) vtpDomain() string {
+	if port := mon.dtp.State(); port.Domain != "" {
+		return port.Domain
+	}
+	for _, d := range mon.vtp.Domains() {
+		if d.Name != "" {
+			return d.Name
+		}
+	}
+	for _, n := range mon.cdp.Neighbors() {
+		if n.Info.VTPDomain != "" {
+			return n.Info.VTPDomain
+		}
+	}
+	return ""
+}
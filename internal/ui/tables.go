@@ -0,0 +1,386 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gnpaone/l2star/internal/forward"
+	"github.com/gnpaone/l2star/internal/gateway"
+	"github.com/gnpaone/l2star/internal/proto/arp"
+	"github.com/gnpaone/l2star/internal/proto/cdp"
+	"github.com/gnpaone/l2star/internal/proto/dhcp"
+	"github.com/gnpaone/l2star/internal/proto/dtp"
+	"github.com/gnpaone/l2star/internal/proto/glbp"
+	"github.com/gnpaone/l2star/internal/proto/hsrp"
+	"github.com/gnpaone/l2star/internal/proto/vlan"
+	"github.com/gnpaone/l2star/internal/proto/vrrp"
+	"github.com/gnpaone/l2star/internal/proto/vtp"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var tableHeaderStyle = lipgloss.NewStyle().Foreground(ColorSecondary).Bold(true)
+
+func renderCDPNeighbors(neighbors []cdp.Neighbor) string {
+	s := tableHeaderStyle.Render(fmt.Sprintf("%-24s %-20s %-20s %-16s %5s %5s", "Device ID", "Port", "Platform", "Address", "Age", "TTL")) + "\n"
+	if len(neighbors) == 0 {
+		return s + lipgloss.NewStyle().Foreground(ColorSubText).Render("No CDP neighbors seen yet.") + "\n"
+	}
+
+	now := time.Now()
+	for _, n := range neighbors {
+		addr := ""
+		if len(n.Info.Addresses) > 0 {
+			addr = n.Info.Addresses[0].String()
+		} else if len(n.Info.MgmtAddresses) > 0 {
+			addr = n.Info.MgmtAddresses[0].String()
+		}
+		s += fmt.Sprintf("%-24s %-20s %-20s %-16s %4ds %4ds\n",
+			truncate(n.Info.DeviceID, 24), truncate(n.Info.PortID, 20), truncate(n.Info.Platform, 20), addr,
+			int(n.Age(now).Seconds()), n.Info.TTL)
+	}
+	return s
+}
+
+// renderDTPPort summarizes what the switch port is, from its DTP frames and the VTP domain heard over CDP.
+// Without DTP, the tagged frames seen on the link tell a nonegotiate trunk from an access port.
+func renderDTPPort(port dtp.PortState, neighbors []cdp.Neighbor, vlans []vlan.VLAN) string {
+	s := tableHeaderStyle.Render("Switch Port") + "\n"
+	if !port.Seen() {
+		tagged := false
+		for _, v := range vlans {
+			tagged = tagged || v.Active()
+		}
+		s += fmt.Sprintf("%-14s %s\n", "Mode:", dtp.SilentMode(tagged))
+		s += lipgloss.NewStyle().Foreground(ColorSubText).Render("No DTP heard yet, the switch sends it every 30s unless nonegotiate is set.") + "\n"
+	} else {
+		trunk := "no"
+		if port.Trunking() {
+			trunk = fmt.Sprintf("yes, for %ds", int(time.Since(port.TrunkSince).Seconds()))
+		}
+		s += fmt.Sprintf("%-14s %s\n", "Mode:", port.Mode())
+		s += fmt.Sprintf("%-14s %s\n", "Encapsulation:", dtp.Encapsulation(port.Type))
+		s += fmt.Sprintf("%-14s %q\n", "DTP Domain:", port.Domain)
+		s += fmt.Sprintf("%-14s %s\n", "Switch MAC:", port.SwitchMAC)
+		s += fmt.Sprintf("%-14s %s\n", "Trunking:", trunk)
+	}
+
+	for _, n := range neighbors {
+		if n.Info.VTPDomain != "" {
+			s += fmt.Sprintf("%-14s %q (CDP from %s)\n", "VTP Domain:", n.Info.VTPDomain, n.Info.DeviceID)
+			break
+		}
+	}
+
+	if port.Negotiated {
+		s += lipgloss.NewStyle().Foreground(ColorSuccess).Bold(true).Render("Negotiation succeeded: port moved to trunking.") + "\n"
+	}
+	return s
+}
+
+func renderVTPDomains(domains []vtp.Domain) string {
+	s := tableHeaderStyle.Render(fmt.Sprintf("%-20s %3s %10s %-16s %s", "Domain", "Ver", "Revision", "Updater", "VLANs")) + "\n"
+	if len(domains) == 0 {
+		return s + lipgloss.NewStyle().Foreground(ColorSubText).Render("No VTP advertisements seen yet.") + "\n"
+	}
+
+	for _, d := range domains {
+		ids := make([]string, 0, len(d.VLANs))
+		for _, v := range d.VLANs {
+			ids = append(ids, fmt.Sprint(v.ID))
+		}
+		updater := ""
+		if d.Updater != nil {
+			updater = d.Updater.String()
+		}
+		s += fmt.Sprintf("%-20s %3d %10d %-16s %s\n",
+			truncate(d.Name, 20), d.Version, d.Revision, updater, truncate(strings.Join(ids, ","), 40))
+	}
+	return s
+}
+
+func renderVLANs(vlans []vlan.VLAN) string {
+	s := tableHeaderStyle.Render(fmt.Sprintf("%5s %-16s %7s %-24s %s", "VLAN", "Name", "Frames", "Hints", "IPs")) + "\n"
+	if len(vlans) == 0 {
+		return s + lipgloss.NewStyle().Foreground(ColorSubText).Render("No VLANs seen yet.") + "\n"
+	}
+
+	for _, v := range vlans {
+		ips := make([]string, 0, len(v.IPs))
+		for _, ip := range v.IPs {
+			ips = append(ips, ip.String())
+		}
+		s += fmt.Sprintf("%5d %-16s %7d %-24s %s\n",
+			v.ID, truncate(v.Name, 16), v.Frames, truncate(strings.Join(v.Hints, ","), 24), truncate(strings.Join(ips, ","), 40))
+	}
+	return s
+}
+
+func renderPoisonHosts(hosts []arp.Host) string {
+	s := tableHeaderStyle.Render(fmt.Sprintf("%-16s %-8s %s", "IP", "Side", "Real MAC")) + "\n"
+	const maxRows = 12
+	for i, h := range hosts {
+		if i == maxRows {
+			s += fmt.Sprintf("... and %d more\n", len(hosts)-maxRows)
+			break
+		}
+		side := "target"
+		if h.Gateway {
+			side = "gateway"
+		}
+		mac := "resolving..."
+		if h.MAC != nil {
+			mac = h.MAC.String()
+		}
+		s += fmt.Sprintf("%-16s %-8s %s\n", h.IP, side, mac)
+	}
+	return s
+}
+
+func renderScanHosts(hosts []arp.ScanHost) string {
+	s := tableHeaderStyle.Render(fmt.Sprintf("%-16s %-18s %-20s %-9s %-9s %8s", "IP", "MAC", "Vendor", "First", "Last", "Latency")) + "\n"
+	if len(hosts) == 0 {
+		return s + lipgloss.NewStyle().Foreground(ColorSubText).Render("No replies yet.") + "\n"
+	}
+
+	for _, h := range hosts {
+		s += fmt.Sprintf("%-16s %-18s %-20s %-9s %-9s %6.1fms\n",
+			h.IP, h.MAC, truncate(h.Vendor, 20), h.FirstSeen.Format("15:04:05"), h.LastSeen.Format("15:04:05"),
+			float64(h.Latency)/float64(time.Millisecond))
+	}
+	return s
+}
+
+func renderStarvation(stats dhcp.StarveStats, stalled bool, leases []dhcp.StarvedLease) string {
+	s := fmt.Sprintf("DISCOVER %d -> OFFER %d, REQUEST %d -> ACK %d, NAK %d\n",
+		stats.Discovers, stats.Offers, stats.Requests, stats.Acks, stats.Naks)
+	if stats.SubnetSize > 0 {
+		s += fmt.Sprintf("Leased %d of %d subnet addresses (%.0f%%)\n", stats.Leases, stats.SubnetSize, 100*float64(stats.Leases)/float64(stats.SubnetSize))
+	} else {
+		s += fmt.Sprintf("Leased %d addresses\n", stats.Leases)
+	}
+	if stalled {
+		s += lipgloss.NewStyle().Foreground(ColorSuccess).Bold(true).Render("Server stopped offering: pool exhausted") + "\n"
+	}
+
+	s += tableHeaderStyle.Render(fmt.Sprintf("%-16s %-18s %-16s %-8s %s", "IP", "MAC", "Server", "Lease", "Acquired")) + "\n"
+	const maxRows = 12
+	for i, l := range leases {
+		if i == maxRows {
+			s += fmt.Sprintf("... and %d more\n", len(leases)-maxRows)
+			break
+		}
+		s += fmt.Sprintf("%-16s %-18s %-16s %-8s %s\n", l.IP, l.MAC, l.Server, l.LeaseTime, l.Acquired.Format("15:04:05"))
+	}
+	return s
+}
+
+func renderHSRPGroups(groups []hsrp.Group) string {
+	s := tableHeaderStyle.Render(fmt.Sprintf("%-3s %-6s %-16s %-9s %-8s %-16s %-5s %s", "Ver", "Group", "VIP", "Auth", "Timers", "Router", "Prio", "State")) + "\n"
+	if len(groups) == 0 {
+		return s + lipgloss.NewStyle().Foreground(ColorSubText).Render("No HSRP hellos seen yet.") + "\n"
+	}
+	for _, g := range groups {
+		auth := g.Auth
+		if g.MD5 {
+			auth = fmt.Sprintf("md5#%d", g.KeyID)
+		}
+		prefix := fmt.Sprintf("v%-2d %-6d %-16s %-9s %-8s", g.Version, g.Number, truncate(g.VIP.String(), 16), truncate(auth, 9), fmt.Sprintf("%d/%d", g.Hellotime, g.Holdtime))
+		for i, r := range g.Routers {
+			if i > 0 {
+				prefix = fmt.Sprintf("%-46s", "")
+			}
+			state := hsrp.StateName(r.State)
+			if r.Previous != r.State {
+				state += fmt.Sprintf(" (was %s)", hsrp.StateName(r.Previous))
+			}
+			s += fmt.Sprintf("%s %-16s %-5d %s\n", prefix, r.IP, r.Priority, state)
+		}
+	}
+	return s + lipgloss.NewStyle().Foreground(ColorSubText).Render("Press 'g' to take over the next learned group.") + "\n"
+}
+
+func renderVRRPGroups(groups []vrrp.Group) string {
+	s := tableHeaderStyle.Render(fmt.Sprintf("%-3s %-5s %-24s %-9s %-8s %-16s %-5s %s", "Ver", "VRID", "Addresses", "Auth", "Interval", "Router", "Prio", "Role")) + "\n"
+	if len(groups) == 0 {
+		return s + lipgloss.NewStyle().Foreground(ColorSubText).Render("No VRRP advertisements seen yet.") + "\n"
+	}
+	for _, g := range groups {
+		addrs := make([]string, len(g.Addresses))
+		for i, a := range g.Addresses {
+			addrs[i] = a.String()
+		}
+		auth := g.Auth
+		if g.AuthType == vrrp.AuthAH {
+			auth = "ah"
+		}
+		prefix := fmt.Sprintf("v%-2d %-5d %-24s %-9s %-8s", g.Version, g.VRID, truncate(strings.Join(addrs, ","), 24), truncate(auth, 9), g.Interval)
+		for i, r := range g.Routers {
+			if i > 0 {
+				prefix = fmt.Sprintf("%-53s", "")
+			}
+			role := "was master"
+			if r.IP.Equal(g.Master) {
+				role = "master"
+			}
+			s += fmt.Sprintf("%s %-16s %-5d %s\n", prefix, truncate(r.IP.String(), 16), r.Priority, role)
+		}
+	}
+	return s + lipgloss.NewStyle().Foreground(ColorSubText).Render("Press 'g' to take over the next learned virtual router.") + "\n"
+}
+
+func renderGLBPGroups(groups []glbp.Group) string {
+	s := tableHeaderStyle.Render(fmt.Sprintf("%-6s %-16s %-9s %-8s %-16s %-5s %s", "Group", "VIP", "Auth", "Timers", "Router", "Prio", "Gateway")) + "\n"
+	if len(groups) == 0 {
+		return s + lipgloss.NewStyle().Foreground(ColorSubText).Render("No GLBP hellos seen yet.") + "\n"
+	}
+	for _, g := range groups {
+		auth := g.Auth
+		if g.AuthType == glbp.AuthMD5 || g.AuthType == glbp.AuthMD5Chain {
+			auth = "md5"
+		}
+		prefix := fmt.Sprintf("%-6d %-16s %-9s %-8s", g.Number, g.VIP, truncate(auth, 9), fmt.Sprintf("%v/%v", g.Hellotime, g.Holdtime))
+		for i, r := range g.Routers {
+			if i > 0 {
+				prefix = fmt.Sprintf("%-42s", "")
+			}
+			state := glbp.StateName(r.State)
+			if r.Previous != r.State {
+				state += fmt.Sprintf(" (was %s)", glbp.StateName(r.Previous))
+			}
+			s += fmt.Sprintf("%s %-16s %-5d %s\n", prefix, r.IP, r.Priority, state)
+		}
+		for _, f := range g.Forwarders {
+			owner := "-"
+			if f.Owner != nil {
+				owner = f.Owner.String()
+			}
+			s += fmt.Sprintf("%-6s forwarder %d  %-18s weight %-4d %-16s %s\n", "", f.Number, f.VirtualMAC, f.Weight, owner, glbp.StateName(f.State))
+		}
+	}
+	return s + lipgloss.NewStyle().Foreground(ColorSubText).Render("Press 'g' to take over the AVG of the next learned group.") + "\n"
+}
+
+func renderGatewayVictims(stats gateway.Stats, victims []gateway.Victim) string {
+	s := fmt.Sprintf("Gateway: %d ARP answered, %d frames relayed, %d unresolved\n", stats.ARPAnswered, stats.Relayed, stats.Unresolved)
+	s += tableHeaderStyle.Render(fmt.Sprintf("%-16s %-18s %8s %10s %-9s", "Victim", "MAC", "Packets", "Bytes", "Since")) + "\n"
+	if len(victims) == 0 {
+		return s + lipgloss.NewStyle().Foreground(ColorSubText).Render("No host has switched to us yet.") + "\n"
+	}
+	const maxRows = 12
+	for i, v := range victims {
+		if i == maxRows {
+			s += fmt.Sprintf("... and %d more\n", len(victims)-maxRows)
+			break
+		}
+		ip := "-"
+		if v.IP != nil {
+			ip = v.IP.String()
+		}
+		s += fmt.Sprintf("%-16s %-18s %8d %10d %-9s\n", truncate(ip, 16), v.MAC, v.Packets, v.Bytes, v.FirstSeen.Format("15:04:05"))
+	}
+	return s
+}
+
+func renderDHCPInventory(clients []dhcp.ClientInfo, servers []dhcp.ServerInfo) string {
+	s := tableHeaderStyle.Render(fmt.Sprintf("%-16s %-18s %-8s %s", "Server", "MAC", "Replies", "Status")) + "\n"
+	for _, srv := range servers {
+		status := lipgloss.NewStyle().Foreground(ColorSuccess).Render("expected")
+		switch {
+		case srv.Ours:
+			status = "ours"
+		case srv.Rogue:
+			status = lipgloss.NewStyle().Foreground(ColorDanger).Bold(true).Render("UNEXPECTED")
+		}
+		s += fmt.Sprintf("%-16s %-18s %-8d %s\n", srv.IP, srv.MAC, srv.Offers+srv.Acks+srv.Naks, status)
+	}
+
+	s += "\n" + tableHeaderStyle.Render(fmt.Sprintf("%-18s %-16s %-16s %-14s %-20s %s", "Client", "IP", "Hostname", "Vendor", "OS", "Fingerprint")) + "\n"
+	if len(clients) == 0 {
+		return s + lipgloss.NewStyle().Foreground(ColorSubText).Render("No DHCP clients seen yet. Press 'r' to reload "+fingerprintFile+".") + "\n"
+	}
+	const maxRows = 12
+	for i, c := range clients {
+		if i == maxRows {
+			s += fmt.Sprintf("... and %d more\n", len(clients)-maxRows)
+			break
+		}
+		ip := ""
+		if c.IP != nil {
+			ip = c.IP.String()
+		}
+		os := c.OS
+		if os == "" {
+			os = "unknown"
+		}
+		s += fmt.Sprintf("%-18s %-16s %-16s %-14s %-20s %s\n",
+			c.MAC, ip, truncate(c.Hostname, 16), truncate(c.VendorClass, 14), truncate(os, 20), truncate(c.Fingerprint, 40))
+	}
+	return s
+}
+
+func renderDeclined(offers uint64, declined []dhcp.Declined) string {
+	s := fmt.Sprintf("Declined %d addresses from %d offers\n", len(declined), offers)
+	s += tableHeaderStyle.Render(fmt.Sprintf("%-16s %-18s %-16s %s", "IP", "Client", "Server", "Declined")) + "\n"
+	const maxRows = 12
+	for i, d := range declined {
+		if i == maxRows {
+			s += fmt.Sprintf("... and %d more\n", len(declined)-maxRows)
+			break
+		}
+		s += fmt.Sprintf("%-16s %-18s %-16s %s\n", d.IP, d.Client, d.Server, d.At.Format("15:04:05"))
+	}
+	return s
+}
+
+func renderDHCPServer(stats dhcp.ServerStats, leases []dhcp.Lease) string {
+	s := fmt.Sprintf("DISCOVER %d, REQUEST %d -> OFFER %d, ACK %d, NAK %d; pool %d/%d used",
+		stats.Discovers, stats.Requests, stats.Offers, stats.Acks, stats.Naks, stats.LeasesUsed, stats.PoolSize)
+	if stats.Exhausted > 0 {
+		s += fmt.Sprintf(", %d dropped (pool full)", stats.Exhausted)
+	}
+	s += "\n"
+	if stats.LegitSeen > 0 {
+		s += fmt.Sprintf("Other server %s answered %d times, %d clients left to it\n", stats.LegitLast, stats.LegitSeen, stats.Yielded)
+	}
+
+	s += tableHeaderStyle.Render(fmt.Sprintf("%-16s %-18s %-20s %-9s %s", "IP", "MAC", "Hostname", "State", "Expires")) + "\n"
+	if len(leases) == 0 {
+		return s + lipgloss.NewStyle().Foreground(ColorSubText).Render("No leases yet.") + "\n"
+	}
+	const maxRows = 12
+	for i, l := range leases {
+		if i == maxRows {
+			s += fmt.Sprintf("... and %d more\n", len(leases)-maxRows)
+			break
+		}
+		mac := ""
+		if l.MAC != nil {
+			mac = l.MAC.String()
+		}
+		s += fmt.Sprintf("%-16s %-18s %-20s %-9s %s\n", l.IP, mac, truncate(l.Hostname, 20), l.State, l.Expires.Format("15:04:05"))
+	}
+	return s
+}
+
+func renderForwarding(mode forward.Mode, stats forward.Stats, hosts []forward.HostStats) string {
+	s := tableHeaderStyle.Render(fmt.Sprintf("Forwarding (%s): %d intercepted, %d relayed, %d unresolved",
+		mode, stats.Intercepted, stats.Forwarded, stats.Unresolved)) + "\n"
+	const maxRows = 8
+	for i, h := range hosts {
+		if i == maxRows {
+			s += fmt.Sprintf("... and %d more hosts\n", len(hosts)-maxRows)
+			break
+		}
+		s += fmt.Sprintf("%-16s %6d flows %8d pkts %10d bytes\n", h.IP, h.Flows, h.Packets, h.Bytes)
+	}
+	return s
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-1] + "~"
+}
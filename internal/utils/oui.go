@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ouiVendors maps common OUIs to their vendor. It is deliberately small: enough to
+// tell network gear, hypervisors and the usual endpoints apart on a LAN.
+var ouiVendors = map[string]string{
+	"00:00:0c": "Cisco",
+	"00:01:42": "Cisco",
+	"00:1b:54": "Cisco",
+	"00:1e:13": "Cisco",
+	"00:1e:7a": "Cisco",
+	"00:24:14": "Cisco",
+	"00:24:f7": "Cisco",
+	"00:26:0b": "Cisco",
+	"00:3a:99": "Cisco",
+	"58:ac:78": "Cisco",
+	"64:16:8d": "Cisco",
+	"70:10:5c": "Cisco",
+	"70:db:98": "Cisco",
+	"f4:db:e6": "Cisco",
+	"00:00:5e": "IANA (VRRP virtual)",
+	"00:07:b4": "Cisco (GLBP virtual)",
+	"00:05:85": "Juniper",
+	"00:1f:12": "Juniper",
+	"28:8a:1c": "Juniper",
+	"00:0b:86": "Aruba",
+	"00:1a:1e": "Aruba",
+	"24:a4:3c": "Ubiquiti",
+	"80:2a:a8": "Ubiquiti",
+	"f0:9f:c2": "Ubiquiti",
+	"00:0c:42": "MikroTik",
+	"4c:5e:0c": "MikroTik",
+	"00:09:0f": "Fortinet",
+	"00:1b:17": "Palo Alto Networks",
+	"00:14:22": "Dell",
+	"18:03:73": "Dell",
+	"f8:bc:12": "Dell",
+	"00:17:a4": "HP",
+	"3c:d9:2b": "HP",
+	"9c:8e:99": "HP",
+	"00:1b:21": "Intel",
+	"3c:97:0e": "Intel",
+	"a4:bf:01": "Intel",
+	"00:03:93": "Apple",
+	"3c:07:54": "Apple",
+	"ac:bc:32": "Apple",
+	"f0:18:98": "Apple",
+	"00:15:5d": "Microsoft Hyper-V",
+	"00:50:56": "VMware",
+	"00:0c:29": "VMware",
+	"00:05:69": "VMware",
+	"08:00:27": "VirtualBox",
+	"52:54:00": "QEMU/KVM",
+	"00:16:3e": "Xen",
+	"02:42:ac": "Docker",
+	"b8:27:eb": "Raspberry Pi",
+	"dc:a6:32": "Raspberry Pi",
+	"e4:5f:01": "Raspberry Pi",
+	"00:1d:d8": "Microsoft",
+	"00:11:32": "Synology",
+	"00:08:9b": "QNAP",
+	"00:80:77": "Brother",
+	"00:00:48": "Epson",
+	"00:26:ab": "Epson",
+}
+
+// virtualPrefixes are the HSRP virtual MAC ranges, which sit inside Cisco's own OUI
+var virtualPrefixes = []struct{ prefix, name string }{
+	{"00:00:0c:07:ac:", "Cisco (HSRPv1 virtual)"},
+	{"00:00:0c:9f:f", "Cisco (HSRPv2 virtual)"},
+}
+
+// Vendor returns the vendor of a MAC address from its OUI, or "" if unknown.
+// Locally administered addresses are reported as such since they have no vendor.
+func Vendor(mac net.HardwareAddr) string {
+	if len(mac) < 3 {
+		return ""
+	}
+	for _, v := range virtualPrefixes {
+		if strings.HasPrefix(mac.String(), v.prefix) {
+			return v.name
+		}
+	}
+	if v, ok := ouiVendors[fmt.Sprintf("%02x:%02x:%02x", mac[0], mac[1], mac[2])]; ok {
+		return v
+	}
+	if mac[0]&0x02 != 0 {
+		return "(locally administered)"
+	}
+	return ""
+}